@@ -11,14 +11,43 @@ import (
 
 	"auth_service/internal/auth"
 	"auth_service/internal/config"
+	"auth_service/internal/http_server/handlers/jwks"
 	"auth_service/internal/http_server/handlers/login"
 	"auth_service/internal/http_server/handlers/logout"
+	"auth_service/internal/http_server/handlers/logoutall"
+	deleteMe "auth_service/internal/http_server/handlers/me/delete"
+	"auth_service/internal/http_server/handlers/me/email/change"
+	emailConfirm "auth_service/internal/http_server/handlers/me/email/confirm"
+	"auth_service/internal/http_server/handlers/me/export"
+	mePassword "auth_service/internal/http_server/handlers/me/password"
+	"auth_service/internal/http_server/handlers/me/profile"
+	"auth_service/internal/http_server/handlers/me/update"
+	"auth_service/internal/http_server/handlers/password/forgot"
+	"auth_service/internal/http_server/handlers/password/reset"
 	"auth_service/internal/http_server/handlers/refresh"
 	register "auth_service/internal/http_server/handlers/register"
+	"auth_service/internal/http_server/handlers/resend"
+	"auth_service/internal/http_server/handlers/sessions/current"
+	"auth_service/internal/http_server/handlers/sessions/list"
+	"auth_service/internal/http_server/handlers/sessions/revoke"
+	"auth_service/internal/http_server/handlers/twofactor/confirm"
+	"auth_service/internal/http_server/handlers/twofactor/disable"
+	"auth_service/internal/http_server/handlers/twofactor/enroll"
+	twoFactorLogin "auth_service/internal/http_server/handlers/twofactor/login"
+	"auth_service/internal/http_server/handlers/twofactor/recovery"
 	"auth_service/internal/http_server/handlers/verify"
+	authMiddleware "auth_service/internal/http_server/middleware/auth"
+	"auth_service/internal/http_server/middleware/correlation"
+	"auth_service/internal/lib/jwt"
+	"auth_service/internal/lib/ratelimit"
+	"auth_service/internal/lib/secretbox"
 	"auth_service/internal/rabbitmq"
 	"auth_service/internal/storage/postgres"
 
+	"messaging/broker"
+	"messaging/envelope"
+	"messaging/outbox"
+
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-playground/validator/v10"
@@ -54,24 +83,97 @@ func main() {
 		slog.String("database", cfg.Postgres.DBName),
 	)
 
-	rabbitMQClient, err := rabbitmq.New(cfg.RabbitMQ.URL, cfg.RabbitMQ.QueueName)
+	brokerConn, err := broker.Connect(cfg.RabbitMQ.URL, log)
 	if err != nil {
 		log.Error("failed to connect rabbitmq", slog.String("err", err.Error()))
 		os.Exit(1)
 	}
-	defer rabbitMQClient.Close()
+	defer brokerConn.Close()
+
+	// * очереди получателей объявляются и здесь, чтобы письма и события,
+	// * отправленные до их первого запуска, не потерялись
+	topology := []broker.Topology{
+		broker.Queue(cfg.RabbitMQ.Exchange, cfg.RabbitMQ.QueueName, envelope.TypeEmailRequested),
+		broker.Queue(cfg.RabbitMQ.Exchange, cfg.RabbitMQ.EventsQueueName, envelope.TypeUserDeleted, envelope.TypeUserUpdated),
+	}
+
+	for _, t := range topology {
+		if err := brokerConn.Declare(t); err != nil {
+			log.Error("failed to declare rabbitmq topology", slog.String("err", err.Error()))
+			os.Exit(1)
+		}
+	}
+
+	publisher := broker.NewPublisher(brokerConn, cfg.RabbitMQ.Exchange)
+	defer publisher.Close()
+
+	rabbitMQClient := rabbitmq.New(publisher)
 
 	log.Info("rabbitmq connected successfully")
 
+	keys, err := jwt.LoadKeySet(cfg.Tokens.SigningKeys.Dir, cfg.Tokens.SigningKeys.ActiveKeyID)
+	if err != nil {
+		log.Error("failed to load signing keys", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	log.Info("signing keys loaded", slog.String("active_key_id", keys.Active().ID))
+
+	totpBox, err := secretbox.New(cfg.TwoFactor.EncryptionKey)
+	if err != nil {
+		log.Error("failed to init two-factor encryption", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
 	authMiddleware := auth.New(
 		log,
 		storage,
 		storage,
 		storage,
+		storage,
+		storage,
+		keys,
 		cfg.Tokens.AccessTokenTTL,
 		cfg.Tokens.RefreshTokenTTL,
+		auth.LockoutPolicy{
+			Threshold:  cfg.LoginLockout.Threshold,
+			BaseDelay:  cfg.LoginLockout.BaseDelay,
+			MaxDelay:   cfg.LoginLockout.MaxDelay,
+			ResetAfter: cfg.LoginLockout.ResetAfter,
+		},
+		auth.TwoFactorConfig{
+			Box:           totpBox,
+			Issuer:        cfg.TwoFactor.Issuer,
+			ChallengeTTL:  cfg.TwoFactor.ChallengeTTL,
+			MaxAttempts:   cfg.TwoFactor.MaxAttempts,
+			RecoveryCodes: cfg.TwoFactor.RecoveryCodes,
+		},
+		time.Now,
 	)
 
+	// * контекст фоновых задач живёт до сигнала остановки
+	runCtx, stopRun := context.WithCancel(context.Background())
+	defer stopRun()
+
+	eventRelay := outbox.NewRelay(
+		log,
+		storage,
+		publisher,
+		cfg.Outbox.PollInterval,
+		cfg.Outbox.ClaimTimeout,
+		cfg.Outbox.Retention,
+		cfg.Outbox.BatchSize,
+		cfg.Outbox.MaxAttempts,
+	)
+
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+
+		log.Info("starting user event relay")
+		eventRelay.Run(runCtx)
+	}()
+
 	requestValidator := validator.New()
 
 	router := setupRouter(
@@ -79,9 +181,9 @@ func main() {
 		requestValidator,
 		authMiddleware,
 		rabbitMQClient,
-		cfg.Tokens.VerificationTokenTTL,
-		cfg.Tokens.VerificationTokenSecret,
-		cfg.HTTPServer.Address,
+		eventRelay,
+		keys,
+		cfg,
 	)
 
 	srv := &http.Server{
@@ -111,11 +213,18 @@ func main() {
 		log.Info("shutdown signal received", slog.String("signal", sig.String()))
 
 		cancel()
+		stopRun()
 
 		// * Graceful shutdown context
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer shutdownCancel()
 
+		select {
+		case <-relayDone:
+		case <-shutdownCtx.Done():
+			log.Error("event relay did not stop in time")
+		}
+
 		log.Info("shutting down http server")
 
 		if err := srv.Shutdown(shutdownCtx); err != nil {
@@ -135,21 +244,33 @@ func setupRouter(
 	validate *validator.Validate,
 	authService *auth.Auth,
 	msgBroker *rabbitmq.RabbitMQClient,
-	verificationTokenTTL time.Duration,
-	verificationTokenSecret string,
-	address string,
+	eventRelay *outbox.Relay,
+	keys *jwt.KeySet,
+	cfg *config.Config,
 ) *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
+	r.Use(correlation.New())
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
 	r.Post("/register",
-		register.New(log, validate, authService, msgBroker, verificationTokenTTL, verificationTokenSecret, address),
+		register.New(
+			log,
+			validate,
+			authService,
+			msgBroker,
+			cfg.Tokens.VerificationTokenTTL,
+			cfg.Tokens.VerificationTokenSecret,
+			cfg.HTTPServer.PublicURL,
+		),
 	)
 	r.Post("/login",
 		login.New(log, validate, authService),
 	)
+	r.Post("/login/2fa",
+		twoFactorLogin.New(log, validate, authService),
+	)
 	r.Post("/refresh",
 		refresh.New(log, validate, authService),
 	)
@@ -157,8 +278,72 @@ func setupRouter(
 		logout.New(log, validate, authService),
 	)
 	r.Get("/verify",
-		verify.New(log, authService, verificationTokenSecret),
+		verify.New(log, authService, cfg.Tokens.VerificationTokenSecret),
+	)
+	r.Post("/verify/resend",
+		resend.New(
+			log,
+			validate,
+			authService,
+			msgBroker,
+			ratelimit.New(cfg.ResendVerification.Limit, cfg.ResendVerification.Window),
+			cfg.Tokens.VerificationTokenTTL,
+			cfg.Tokens.VerificationTokenSecret,
+			cfg.HTTPServer.PublicURL,
+		),
+	)
+	r.Post("/password/forgot",
+		forgot.New(
+			log,
+			validate,
+			authService,
+			msgBroker,
+			cfg.Tokens.PasswordResetTokenTTL,
+			cfg.Tokens.VerificationTokenSecret,
+			cfg.HTTPServer.ResetURL,
+		),
+	)
+	r.Post("/password/reset",
+		reset.New(log, validate, authService, cfg.Tokens.VerificationTokenSecret),
 	)
+	r.Get("/me/email/confirm",
+		emailConfirm.New(log, authService, eventRelay, cfg.Tokens.VerificationTokenSecret),
+	)
+	r.Get("/.well-known/jwks.json",
+		jwks.New(keys),
+	)
+
+	// * эндпоинты, требующие access токена
+	r.Group(func(r chi.Router) {
+		r.Use(authMiddleware.New(log, keys, authService))
+
+		r.Get("/sessions", list.New(log, authService))
+		r.Get("/sessions/current", current.New(log))
+		r.Delete("/sessions/{id}", revoke.New(log, validate, authService))
+		r.Post("/logout/all", logoutall.New(log, authService))
+
+		r.Post("/2fa/enroll", enroll.New(log, authService))
+		r.Post("/2fa/confirm", confirm.New(log, validate, authService))
+		r.Post("/2fa/disable", disable.New(log, validate, authService))
+		r.Post("/2fa/recovery-codes", recovery.New(log, validate, authService))
+
+		r.Get("/me", profile.New(log, authService))
+		r.Patch("/me", update.New(log, validate, authService, eventRelay))
+		r.Post("/me/password", mePassword.New(log, validate, authService))
+		r.Post("/me/email",
+			change.New(
+				log,
+				validate,
+				authService,
+				msgBroker,
+				cfg.Tokens.EmailChangeTokenTTL,
+				cfg.Tokens.VerificationTokenSecret,
+				cfg.HTTPServer.PublicURL+"/me/email/confirm",
+			),
+		)
+		r.Get("/me/export", export.New(log, authService))
+		r.Delete("/me", deleteMe.New(log, validate, authService, eventRelay))
+	})
 
 	return r
 }
@@ -21,6 +21,12 @@ var (
 	ErrInvalidAppID       = errors.New("invalid app id")
 	ErrUserExists         = errors.New("user already exists")
 	ErrEmailNotVerified   = errors.New("email not verified")
+	ErrInvalidResetToken  = errors.New("invalid or expired reset token")
+	ErrAlreadyVerified    = errors.New("email already verified")
+
+	ErrVerificationTokenExpired = errors.New("verification token expired")
+	ErrVerificationTokenUsed    = errors.New("verification token already used")
+	ErrVerificationTokenInvalid = errors.New("invalid verification token")
 )
 
 type Auth struct {
@@ -28,107 +34,246 @@ type Auth struct {
 	usrSaver    UserSaver
 	usrProvider UserProvider
 	appProvider AppProvider
+	attempts    LoginAttempts
+	twoFactor   TwoFactorStore
+	keys        *jwt.KeySet
+	lockout     LockoutPolicy
+	now         func() time.Time
 	tokenTTL    time.Duration
 	refreshTTL  time.Duration
+
+	twoFactorCfg TwoFactorConfig
 }
 
 type UserSaver interface {
 	SaveUser(ctx context.Context, email string, username string, passHash []byte) (uid int64, err error)
 
-	SaveRefreshToken(ctx context.Context, userID int64, appID int32, tokenHash []byte, expiresAt time.Time) error
-	UpdateRefreshToken(ctx context.Context, userID int64, oldTokenHash, newTokenHash []byte, expiresAt time.Time) error
-	DeleteRefreshToken(ctx context.Context, tokenHash []byte) error
+	SaveRefreshToken(
+		ctx context.Context,
+		userID int64,
+		appID int32,
+		tokenHash []byte,
+		expiresAt time.Time,
+		client models.ClientInfo,
+	) (sessionID string, err error)
+	RotateRefreshToken(
+		ctx context.Context,
+		oldTokenHash, newTokenHash []byte,
+		expiresAt time.Time,
+		client models.ClientInfo,
+	) error
+	RevokeRefreshTokenFamily(ctx context.Context, userID int64, familyID string, eventType string) error
+	DeleteRefreshTokenFamily(ctx context.Context, familyID string) error
+	DeleteSession(ctx context.Context, userID int64, sessionID string) error
+	DeleteSessions(ctx context.Context, userID int64) error
+	DeleteUser(ctx context.Context, userID int64, occurredAt time.Time) error
+
+	SaveOneTimeToken(ctx context.Context, jti string, userID int64, purpose string, expiresAt time.Time) error
+	ResetPassword(ctx context.Context, userID int64, jti, purpose string, passHash []byte) error
+	VerifyEmail(ctx context.Context, userID int64, jti, purpose string) error
+
+	UpdateProfile(ctx context.Context, userID int64, upd models.ProfileUpdate, occurredAt time.Time) (models.User, error)
+	ChangePassword(ctx context.Context, userID int64, passHash []byte, keepSessionID string) error
+	SetPendingEmail(ctx context.Context, userID int64, email, purpose string) error
+	ConfirmEmailChange(ctx context.Context, userID int64, jti, purpose string, occurredAt time.Time) (models.User, error)
 }
 
 type UserProvider interface {
 	User(ctx context.Context, email string) (models.User, error)
 	UserByID(ctx context.Context, id int64) (models.User, error)
-	GetRefreshToken(ctx context.Context, rawToken string) (models.RefreshToken, error)
-	SetEmailVerified(ctx context.Context, uid int64) error
+	Sessions(ctx context.Context, userID int64) ([]models.Session, error)
+	SessionActive(ctx context.Context, userID int64, sessionID string) (bool, error)
+	GetRefreshToken(ctx context.Context, tokenHash []byte) (models.RefreshToken, error)
 }
 
 type AppProvider interface {
 	App(ctx context.Context, appID int32) (models.App, error)
 }
 
+// * LoginAttempts хранит счётчики неудачных входов и блокировки
+type LoginAttempts interface {
+	LoginLockedUntil(ctx context.Context, keys []string) (time.Time, error)
+	RegisterLoginFailure(ctx context.Context, key string, now, resetBefore time.Time) (failures int, err error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	ResetLoginFailures(ctx context.Context, key string) error
+}
+
 func New(
 	log *slog.Logger,
 	userSaver UserSaver,
 	userProvider UserProvider,
 	appProvider AppProvider,
+	attempts LoginAttempts,
+	twoFactor TwoFactorStore,
+	keys *jwt.KeySet,
 	tokenTTL, refreshTTL time.Duration,
+	lockout LockoutPolicy,
+	twoFactorCfg TwoFactorConfig,
+	now func() time.Time,
 ) *Auth {
 	return &Auth{
 		usrSaver:    userSaver,
 		usrProvider: userProvider,
 		appProvider: appProvider,
+		attempts:    attempts,
+		twoFactor:   twoFactor,
+		keys:        keys,
+		lockout:     lockout,
+		now:         now,
 		log:         log,
 		tokenTTL:    tokenTTL,
 		refreshTTL:  refreshTTL,
+
+		twoFactorCfg: twoFactorCfg,
 	}
 }
 
-// * Login проверяет учетные данные и возвращает JWT и refresh token
+// * Login проверяет учетные данные и возвращает JWT и refresh token.
+// * Неизвестный email и неверный пароль неразличимы ни по ошибке, ни по времени
+// * ответа; после серии неудач IP и аккаунт блокируются (ErrTooManyAttempts).
+// * Если у пользователя включена 2FA, вместо токенов возвращается ChallengeToken
 func (a *Auth) Login(
 	ctx context.Context,
 	email, password string,
 	appID int32,
-) (accessToken string, refreshToken string, err error) {
+	client models.ClientInfo,
+) (LoginResult, error) {
 	const op = "Auth.Login"
 
 	log := a.log.With(slog.String("op", op))
 
+	ipKey, accountKey := attemptKeys(email, client)
+
+	lockedUntil, err := a.attempts.LoginLockedUntil(ctx, []string{ipKey, accountKey})
+	if err != nil {
+		log.Error("failed to check login lockout", sl.Err(err))
+		return LoginResult{}, err
+	}
+
+	if now := a.now(); now.Before(lockedUntil) {
+		log.Warn("login locked", slog.String("ip", client.IP))
+		return LoginResult{}, &LockoutError{RetryAfter: lockedUntil.Sub(now)}
+	}
+
 	user, err := a.usrProvider.User(ctx, email)
 	if err != nil {
-		if errors.Is(err, storage.ErrUserNotFound) {
-			log.Warn("user not found")
-			return "", "", storage.ErrUserNotFound
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			log.Error("failed to get user", sl.Err(err))
+			return LoginResult{}, err
 		}
 
-		log.Error("failed to get user", sl.Err(err))
-		return "", "", err
-	}
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
 
-	if !user.IsVerified {
-		return "", "", ErrEmailNotVerified
+		log.Info("invalid credentials: unknown email")
+		return LoginResult{}, a.loginFailed(ctx, log, ipKey, accountKey)
 	}
 
 	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
-		log.Info("invalid credentials", sl.Err(err))
-		return "", "", ErrInvalidCredentials
+		log.Info("invalid credentials", slog.Int64("uid", user.ID))
+		return LoginResult{}, a.loginFailed(ctx, log, ipKey, accountKey)
+	}
+
+	if err := a.attempts.ResetLoginFailures(ctx, accountKey); err != nil {
+		log.Error("failed to reset login failures", sl.Err(err))
+	}
+
+	if !user.IsVerified {
+		return LoginResult{}, ErrEmailNotVerified
 	}
 
 	app, err := a.appProvider.App(ctx, appID)
 	if err != nil {
-		return "", "", ErrInvalidAppID
+		return LoginResult{}, ErrInvalidAppID
 	}
 
-	accessToken, err = jwt.NewToken(user, app, a.tokenTTL)
+	enabled, err := a.twoFactorEnabled(ctx, user.ID)
 	if err != nil {
-		log.Error("failed to generate access token", sl.Err(err))
-		return "", "", err
+		log.Error("failed to check two-factor status", sl.Err(err))
+		return LoginResult{}, err
+	}
+
+	if enabled {
+		challenge, err := a.issueChallenge(ctx, user.ID, app.ID)
+		if err != nil {
+			log.Error("failed to issue login challenge", sl.Err(err))
+			return LoginResult{}, err
+		}
+
+		log.Info("second factor required", slog.Int64("uid", user.ID))
+		return LoginResult{ChallengeToken: challenge}, nil
 	}
 
-	refreshTokenValue, err := jwt.NewRefreshToken()
+	result, err := a.issueTokens(ctx, user, app, client)
 	if err != nil {
-		log.Error("failed to generate refresh token", sl.Err(err))
-		return "", "", err
+		log.Error("failed to issue tokens", sl.Err(err))
+		return LoginResult{}, err
 	}
 
-	refreshHash, err := bcrypt.GenerateFromPassword([]byte(refreshTokenValue), bcrypt.DefaultCost)
+	log.Info("user logged in successfully", slog.Int64("uid", user.ID))
+	return result, nil
+}
+
+// * issueTokens открывает новую сессию и выпускает access и refresh токены
+func (a *Auth) issueTokens(
+	ctx context.Context,
+	user models.User,
+	app models.App,
+	client models.ClientInfo,
+) (LoginResult, error) {
+	refreshToken, err := jwt.NewRefreshToken()
 	if err != nil {
-		log.Error("failed to hash refresh token", sl.Err(err))
-		return "", "", err
+		return LoginResult{}, err
 	}
 
-	err = a.usrSaver.SaveRefreshToken(ctx, user.ID, appID, refreshHash, time.Now().Add(a.refreshTTL))
+	refreshHash := jwt.HashRefreshToken(refreshToken)
+
+	sessionID, err := a.usrSaver.SaveRefreshToken(ctx, user.ID, app.ID, refreshHash, a.now().Add(a.refreshTTL), client)
 	if err != nil {
-		log.Error("failed to save refresh token", sl.Err(err))
-		return "", "", err
+		return LoginResult{}, err
 	}
 
-	log.Info("user logged in successfully", slog.Int64("uid", user.ID))
-	return accessToken, refreshTokenValue, nil
+	accessToken, err := jwt.NewToken(user, app, sessionID, a.keys, a.tokenTTL)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	return LoginResult{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// * loginFailed учитывает неудачу по обоим ключам и всегда возвращает ErrInvalidCredentials
+func (a *Auth) loginFailed(ctx context.Context, log *slog.Logger, keys ...string) error {
+	a.registerFailures(ctx, log, keys...)
+
+	return ErrInvalidCredentials
+}
+
+// * registerFailures увеличивает счётчики неудач ключей и при достижении
+// * порога LockoutPolicy ставит блокировку
+func (a *Auth) registerFailures(ctx context.Context, log *slog.Logger, keys ...string) {
+	now := a.now()
+
+	for _, key := range keys {
+		failures, err := a.attempts.RegisterLoginFailure(ctx, key, now, now.Add(-a.lockout.ResetAfter))
+		if err != nil {
+			log.Error("failed to register login failure", sl.Err(err))
+			continue
+		}
+
+		if delay := a.lockout.Delay(failures); delay > 0 {
+			if err := a.attempts.LockLogin(ctx, key, now.Add(delay)); err != nil {
+				log.Error("failed to lock login", sl.Err(err))
+				continue
+			}
+
+			log.Warn("login locked after repeated failures",
+				slog.Int("failures", failures),
+				slog.Duration("delay", delay),
+			)
+		}
+	}
 }
 
 func (a *Auth) RegisterNewUser(
@@ -170,6 +315,7 @@ func (a *Auth) RegisterNewUser(
 func (a *Auth) Refresh(
 	ctx context.Context,
 	refreshToken string,
+	client models.ClientInfo,
 ) (string, string, error) {
 	const op = "auth.refresh"
 
@@ -177,13 +323,23 @@ func (a *Auth) Refresh(
 		slog.String("op", op),
 	)
 
-	rt, err := a.usrProvider.GetRefreshToken(ctx, refreshToken)
+	rt, err := a.usrProvider.GetRefreshToken(ctx, jwt.HashRefreshToken(refreshToken))
 	if err != nil {
 		log.Warn("refresh token not found", sl.Err(err))
 		return "", "", ErrInvalidCredentials
 	}
 
-	if time.Now().After(rt.ExpiresAt) {
+	if rt.RevokedAt != nil {
+		log.Warn("refresh token revoked", slog.Int64("uid", rt.UserID))
+		return "", "", ErrInvalidCredentials
+	}
+
+	if rt.RotatedAt != nil {
+		a.revokeFamily(ctx, log, rt)
+		return "", "", ErrInvalidCredentials
+	}
+
+	if a.now().After(rt.ExpiresAt) {
 		log.Warn("refresh token expired")
 
 		return "", "", ErrInvalidCredentials
@@ -200,7 +356,7 @@ func (a *Auth) Refresh(
 		return "", "", ErrInvalidAppID
 	}
 
-	accessToken, err := jwt.NewToken(user, app, a.tokenTTL)
+	accessToken, err := jwt.NewToken(user, app, rt.FamilyID, a.keys, a.tokenTTL)
 	if err != nil {
 		log.Error("failed to generate access token", sl.Err(err))
 		return "", "", err
@@ -212,20 +368,20 @@ func (a *Auth) Refresh(
 		return "", "", err
 	}
 
-	newHash, err := bcrypt.GenerateFromPassword([]byte(newRefresh), bcrypt.DefaultCost)
-	if err != nil {
-		log.Error("failed to hash new refresh token", sl.Err(err))
-		return "", "", err
-	}
-
-	err = a.usrSaver.UpdateRefreshToken(
+	err = a.usrSaver.RotateRefreshToken(
 		ctx,
-		rt.UserID,
 		rt.TokenHash,
-		newHash,
-		time.Now().Add(a.refreshTTL),
+		jwt.HashRefreshToken(newRefresh),
+		a.now().Add(a.refreshTTL),
+		client,
 	)
 	if err != nil {
+		if errors.Is(err, storage.ErrRefreshTokenReused) {
+			// * токен ротирован параллельным запросом: одна из сторон использует украденную копию
+			a.revokeFamily(ctx, log, rt)
+			return "", "", ErrInvalidCredentials
+		}
+
 		log.Error("failed to update refresh token", sl.Err(err))
 		return "", "", err
 	}
@@ -235,6 +391,21 @@ func (a *Auth) Refresh(
 	return accessToken, newRefresh, nil
 }
 
+// * revokeFamily реагирует на повторное предъявление ротированного токена:
+// * отзывает всю сессию, так что и легитимный клиент, и атакующий должны войти заново
+func (a *Auth) revokeFamily(ctx context.Context, log *slog.Logger, rt models.RefreshToken) {
+	log.Warn("refresh token reuse detected, revoking session",
+		slog.Int64("uid", rt.UserID),
+		slog.String("family_id", rt.FamilyID),
+	)
+
+	err := a.usrSaver.RevokeRefreshTokenFamily(ctx, rt.UserID, rt.FamilyID, models.SecurityEventRefreshTokenReuse)
+	if err != nil {
+		log.Error("failed to revoke refresh token family", sl.Err(err))
+	}
+}
+
+// * VerifyUser подтверждает email по одноразовому токену
 func (a *Auth) VerifyUser(
 	ctx context.Context,
 	verificationToken string,
@@ -246,19 +417,325 @@ func (a *Auth) VerifyUser(
 		slog.String("op", op),
 	)
 
-	user_id, err := verification.ParseVerificationToken(verificationToken, verificationTokenSecret)
+	userID, jti, err := verification.ParseOneTimeToken(
+		verificationToken,
+		verificationTokenSecret,
+		verification.PurposeEmailVerification,
+	)
 	if err != nil {
-		log.Error("failed to update parse verification token", sl.Err(err))
+		log.Warn("failed to parse verification token", sl.Err(err))
 
-		return err
+		if errors.Is(err, verification.ErrTokenExpired) {
+			return ErrVerificationTokenExpired
+		}
+		return ErrVerificationTokenInvalid
 	}
 
-	if err = a.usrProvider.SetEmailVerified(ctx, user_id); err != nil {
-		log.Error("failed to update update status in database", sl.Err(err))
+	err = a.usrSaver.VerifyEmail(ctx, userID, jti, verification.PurposeEmailVerification)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrOneTimeTokenUsed):
+			log.Warn("verification token already used", slog.Int64("uid", userID))
+			return ErrVerificationTokenUsed
+		case errors.Is(err, storage.ErrOneTimeTokenNotFound), errors.Is(err, storage.ErrUserNotFound):
+			log.Warn("verification token not issued", slog.Int64("uid", userID))
+			return ErrVerificationTokenInvalid
+		}
 
-		return err
+		log.Error("failed to update status in database", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	log.Info("email verified", slog.Int64("uid", userID))
+
+	return nil
+}
+
+// * IssueVerificationToken выпускает одноразовый токен подтверждения email
+func (a *Auth) IssueVerificationToken(
+	ctx context.Context,
+	userID int64,
+	tokenTTL time.Duration,
+	tokenSecret string,
+) (string, error) {
+	return a.issueOneTimeToken(ctx, userID, verification.PurposeEmailVerification, tokenTTL, tokenSecret)
+}
+
+// * ResendVerification выпускает новый токен подтверждения для ещё не подтверждённого email
+func (a *Auth) ResendVerification(
+	ctx context.Context,
+	email string,
+	tokenTTL time.Duration,
+	tokenSecret string,
+) (models.User, string, error) {
+	const op = "auth.ResendVerification"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	user, err := a.usrProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, "", storage.ErrUserNotFound
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+		return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if user.IsVerified {
+		return models.User{}, "", ErrAlreadyVerified
+	}
+
+	token, err := a.IssueVerificationToken(ctx, user.ID, tokenTTL, tokenSecret)
+	if err != nil {
+		log.Error("failed to issue verification token", sl.Err(err))
+		return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, token, nil
+}
+
+// * issueOneTimeToken подписывает токен и сохраняет его jti для последующего гашения
+func (a *Auth) issueOneTimeToken(
+	ctx context.Context,
+	userID int64,
+	purpose string,
+	tokenTTL time.Duration,
+	tokenSecret string,
+) (string, error) {
+	const op = "auth.issueOneTimeToken"
+
+	token, jti, err := verification.GenerateOneTimeToken(userID, purpose, tokenTTL, tokenSecret)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.usrSaver.SaveOneTimeToken(ctx, jti, userID, purpose, a.now().Add(tokenTTL)); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// * RequestPasswordReset выпускает одноразовый токен сброса пароля для пользователя с данным email
+func (a *Auth) RequestPasswordReset(
+	ctx context.Context,
+	email string,
+	tokenTTL time.Duration,
+	tokenSecret string,
+) (models.User, string, error) {
+	const op = "auth.RequestPasswordReset"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	user, err := a.usrProvider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, "", storage.ErrUserNotFound
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+		return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := a.issueOneTimeToken(ctx, user.ID, verification.PurposePasswordReset, tokenTTL, tokenSecret)
+	if err != nil {
+		log.Error("failed to issue reset token", sl.Err(err))
+		return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, token, nil
+}
+
+// * ResetPassword меняет пароль по токену сброса и завершает все сессии пользователя
+func (a *Auth) ResetPassword(
+	ctx context.Context,
+	resetToken string,
+	newPassword string,
+	tokenSecret string,
+) error {
+	const op = "auth.ResetPassword"
+
+	log := a.log.With(
+		slog.String("op", op),
+	)
+
+	userID, jti, err := verification.ParseOneTimeToken(resetToken, tokenSecret, verification.PurposePasswordReset)
+	if err != nil {
+		log.Warn("invalid reset token", sl.Err(err))
+		return ErrInvalidResetToken
+	}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = a.usrSaver.ResetPassword(ctx, userID, jti, verification.PurposePasswordReset, passHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrOneTimeTokenNotFound) ||
+			errors.Is(err, storage.ErrOneTimeTokenUsed) ||
+			errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("reset token already used or user removed", slog.Int64("uid", userID))
+			return ErrInvalidResetToken
+		}
+
+		log.Error("failed to reset password", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password reset successfully", slog.Int64("uid", userID))
+
+	return nil
+}
+
+// * Sessions возвращает активные сессии пользователя, отмечая текущую
+func (a *Auth) Sessions(ctx context.Context, userID int64, currentSessionID string) ([]models.Session, error) {
+	const op = "auth.Sessions"
+
+	sessions, err := a.usrProvider.Sessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// * SessionActive проверяет, что сессия access токена не завершена
+func (a *Auth) SessionActive(ctx context.Context, userID int64, sessionID string) (bool, error) {
+	const op = "auth.SessionActive"
+
+	active, err := a.usrProvider.SessionActive(ctx, userID, sessionID)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return active, nil
+}
+
+// * RevokeSession завершает одну сессию. auth_service сразу перестаёт принимать
+// * её access токены, main_service отклоняет их, проверив сессию через
+// * /sessions/current, не позже чем через session_cache_ttl своего кеша проверок
+func (a *Auth) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	const op = "auth.RevokeSession"
+
+	if err := a.usrSaver.DeleteSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return storage.ErrSessionNotFound
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.log.Info("session revoked", slog.String("op", op), slog.Int64("uid", userID))
+
+	return nil
+}
+
+// * LogoutAll завершает все сессии пользователя на всех устройствах.
+// * Как и в RevokeSession, main_service отклоняет уже выданные access токены
+// * не позже чем через session_cache_ttl своего кеша проверок
+func (a *Auth) LogoutAll(ctx context.Context, userID int64) error {
+	const op = "auth.LogoutAll"
+
+	if err := a.usrSaver.DeleteSessions(ctx, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.log.Info("all sessions revoked", slog.String("op", op), slog.Int64("uid", userID))
+
+	return nil
+}
+
+// * ExportAccount собирает данные аккаунта для выгрузки персональных данных
+func (a *Auth) ExportAccount(ctx context.Context, userID int64, currentSessionID string) (models.AccountExport, error) {
+	const op = "auth.ExportAccount"
+
+	user, err := a.usrProvider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.AccountExport{}, storage.ErrUserNotFound
+		}
+
+		return models.AccountExport{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	sessions, err := a.Sessions(ctx, userID, currentSessionID)
+	if err != nil {
+		return models.AccountExport{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	twoFactor, err := a.twoFactorEnabled(ctx, userID)
+	if err != nil {
+		return models.AccountExport{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if sessions == nil {
+		sessions = []models.Session{}
+	}
+
+	return models.AccountExport{
+		Profile:          user.Profile(),
+		TwoFactorEnabled: twoFactor,
+		Sessions:         sessions,
+	}, nil
+}
+
+// * DeleteAccount удаляет аккаунт после повторной проверки пароля и, если
+// * включена 2FA, кода. Событие user.deleted публикуется relay из outbox
+func (a *Auth) DeleteAccount(ctx context.Context, userID int64, password, code string) error {
+	const op = "auth.DeleteAccount"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("uid", userID))
+
+	user, err := a.usrProvider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return storage.ErrUserNotFound
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
+		log.Warn("invalid password on account deletion")
+		return ErrInvalidCredentials
+	}
+
+	enabled, err := a.twoFactorEnabled(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if enabled {
+		if err := a.verifySecondFactor(ctx, userID, code); err != nil {
+			if errors.Is(err, ErrInvalidTwoFactorCode) {
+				return ErrInvalidTwoFactorCode
+			}
+
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := a.usrSaver.DeleteUser(ctx, userID, a.now()); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return storage.ErrUserNotFound
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("account deleted")
+
 	return nil
 }
 
@@ -272,13 +749,13 @@ func (a *Auth) Logout(
 		slog.String("op", op),
 	)
 
-	rt, err := a.usrProvider.GetRefreshToken(ctx, rawRefreshToken)
+	rt, err := a.usrProvider.GetRefreshToken(ctx, jwt.HashRefreshToken(rawRefreshToken))
 	if err != nil {
 		log.Warn("refresh token not found", slog.Any("err", err))
 		return ErrInvalidCredentials
 	}
 
-	err = a.usrSaver.DeleteRefreshToken(ctx, rt.TokenHash)
+	err = a.usrSaver.DeleteRefreshTokenFamily(ctx, rt.FamilyID)
 	if err != nil {
 		log.Error("failed to delete refresh token", slog.Any("err", err))
 		return err
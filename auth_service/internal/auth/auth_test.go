@@ -0,0 +1,534 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"auth_service/internal/lib/jwt"
+	"auth_service/internal/models"
+	"auth_service/internal/storage"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// * fakeStore повторяет семантику RotateRefreshToken: токен ротируется
+// * только один раз, повторная ротация возвращает ErrRefreshTokenReused.
+// * Остальные методы UserSaver и UserProvider тестам не нужны
+type fakeStore struct {
+	UserSaver
+	UserProvider
+
+	mu     sync.Mutex
+	users  []models.User
+	tokens []*models.RefreshToken
+	events []string
+}
+
+func (s *fakeStore) find(tokenHash []byte) *models.RefreshToken {
+	for _, rt := range s.tokens {
+		if bytes.Equal(rt.TokenHash, tokenHash) {
+			return rt
+		}
+	}
+
+	return nil
+}
+
+func (s *fakeStore) addRefreshToken(token string, userID int64, familyID string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens = append(s.tokens, &models.RefreshToken{
+		TokenHash: jwt.HashRefreshToken(token),
+		UserID:    userID,
+		AppID:     1,
+		FamilyID:  familyID,
+		ExpiresAt: expiresAt,
+	})
+}
+
+func (s *fakeStore) GetRefreshToken(_ context.Context, tokenHash []byte) (models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rt := s.find(tokenHash)
+	if rt == nil {
+		return models.RefreshToken{}, storage.ErrRefreshTokenNotFound
+	}
+
+	return *rt, nil
+}
+
+func (s *fakeStore) RotateRefreshToken(
+	_ context.Context,
+	oldTokenHash, newTokenHash []byte,
+	expiresAt time.Time,
+	_ models.ClientInfo,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rt := s.find(oldTokenHash)
+	if rt == nil || rt.RotatedAt != nil || rt.RevokedAt != nil {
+		return storage.ErrRefreshTokenReused
+	}
+
+	now := time.Now()
+	rt.RotatedAt = &now
+
+	s.tokens = append(s.tokens, &models.RefreshToken{
+		TokenHash: newTokenHash,
+		UserID:    rt.UserID,
+		AppID:     rt.AppID,
+		FamilyID:  rt.FamilyID,
+		ExpiresAt: expiresAt,
+	})
+
+	return nil
+}
+
+func (s *fakeStore) RevokeRefreshTokenFamily(_ context.Context, userID int64, familyID string, eventType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, rt := range s.tokens {
+		if rt.UserID == userID && rt.FamilyID == familyID && rt.RevokedAt == nil {
+			rt.RevokedAt = &now
+		}
+	}
+
+	s.events = append(s.events, eventType)
+
+	return nil
+}
+
+// * addUser добавляет подтверждённого пользователя с паролем password
+func (s *fakeStore) addUser(t *testing.T, email, password string) models.User {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := models.User{
+		ID:         int64(len(s.users) + 1),
+		Email:      email,
+		Username:   email,
+		PassHash:   hash,
+		IsVerified: true,
+	}
+	s.users = append(s.users, user)
+
+	return user
+}
+
+func (s *fakeStore) User(_ context.Context, email string) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+
+	return models.User{}, storage.ErrUserNotFound
+}
+
+func (s *fakeStore) UserByID(_ context.Context, id int64) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+
+	return models.User{ID: id, Email: "user@example.com", Username: "user"}, nil
+}
+
+func (s *fakeStore) SaveRefreshToken(
+	_ context.Context,
+	userID int64,
+	appID int32,
+	tokenHash []byte,
+	expiresAt time.Time,
+	_ models.ClientInfo,
+) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	familyID := fmt.Sprintf("family-%d", len(s.tokens)+1)
+
+	s.tokens = append(s.tokens, &models.RefreshToken{
+		TokenHash: tokenHash,
+		UserID:    userID,
+		AppID:     appID,
+		FamilyID:  familyID,
+		ExpiresAt: expiresAt,
+	})
+
+	return familyID, nil
+}
+
+func (s *fakeStore) DeleteUser(_ context.Context, userID int64, _ time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, u := range s.users {
+		if u.ID == userID {
+			s.users = append(s.users[:i], s.users[i+1:]...)
+			return nil
+		}
+	}
+
+	return storage.ErrUserNotFound
+}
+
+func (s *fakeStore) App(_ context.Context, appID int32) (models.App, error) {
+	return models.App{ID: appID, Name: "test"}, nil
+}
+
+func (s *fakeStore) familyRevoked(familyID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rt := range s.tokens {
+		if rt.FamilyID == familyID && rt.RevokedAt == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// * fakeAttempts повторяет семантику login_attempts: счётчик начинается
+// * заново, если прошлая неудача старше resetBefore, блокировка только растёт
+type fakeAttempts struct {
+	mu   sync.Mutex
+	keys map[string]*attemptState
+}
+
+type attemptState struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func newFakeAttempts() *fakeAttempts {
+	return &fakeAttempts{keys: make(map[string]*attemptState)}
+}
+
+func (f *fakeAttempts) LoginLockedUntil(_ context.Context, keys []string) (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var until time.Time
+	for _, key := range keys {
+		if st, ok := f.keys[key]; ok && st.lockedUntil.After(until) {
+			until = st.lockedUntil
+		}
+	}
+
+	return until, nil
+}
+
+func (f *fakeAttempts) RegisterLoginFailure(_ context.Context, key string, now, resetBefore time.Time) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	st, ok := f.keys[key]
+	switch {
+	case !ok:
+		st = &attemptState{failures: 1}
+		f.keys[key] = st
+	case st.lastFailure.Before(resetBefore):
+		st.failures = 1
+	default:
+		st.failures++
+	}
+	st.lastFailure = now
+
+	return st.failures, nil
+}
+
+func (f *fakeAttempts) LockLogin(_ context.Context, key string, until time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if st, ok := f.keys[key]; ok && until.After(st.lockedUntil) {
+		st.lockedUntil = until
+	}
+
+	return nil
+}
+
+func (f *fakeAttempts) ResetLoginFailures(_ context.Context, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.keys, key)
+
+	return nil
+}
+
+// * fakeTwoFactor хранит настройки 2FA и challenge токены; у пользователя без
+// * записи 2FA выключена. ClaimChallengeAttempt атомарен, как UPDATE в базе.
+// * Кодов восстановления нет: любой такой код неверен
+type fakeTwoFactor struct {
+	TwoFactorStore
+
+	mu         sync.Mutex
+	configs    map[int64]models.TwoFactor
+	challenges map[string]*models.LoginChallenge
+}
+
+func newFakeTwoFactor() *fakeTwoFactor {
+	return &fakeTwoFactor{
+		configs:    make(map[int64]models.TwoFactor),
+		challenges: make(map[string]*models.LoginChallenge),
+	}
+}
+
+func (f *fakeTwoFactor) TwoFactor(_ context.Context, userID int64) (models.TwoFactor, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tf, ok := f.configs[userID]
+	if !ok {
+		return models.TwoFactor{}, storage.ErrTwoFactorNotFound
+	}
+
+	return tf, nil
+}
+
+func (f *fakeTwoFactor) ConfirmTwoFactor(_ context.Context, userID int64, _ [][]byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tf, ok := f.configs[userID]
+	if !ok || tf.ConfirmedAt != nil {
+		return storage.ErrTwoFactorNotFound
+	}
+
+	now := time.Now()
+	tf.ConfirmedAt = &now
+	f.configs[userID] = tf
+
+	return nil
+}
+
+func (f *fakeTwoFactor) DeleteTwoFactor(_ context.Context, userID int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.configs, userID)
+
+	return nil
+}
+
+func (f *fakeTwoFactor) UseTOTPStep(_ context.Context, userID int64, step int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tf := f.configs[userID]
+	if step <= tf.LastUsedStep {
+		return storage.ErrTOTPStepUsed
+	}
+
+	tf.LastUsedStep = step
+	f.configs[userID] = tf
+
+	return nil
+}
+
+func (f *fakeTwoFactor) ReplaceRecoveryCodes(context.Context, int64, [][]byte) error {
+	return nil
+}
+
+func (f *fakeTwoFactor) UseRecoveryCode(context.Context, int64, []byte) error {
+	return storage.ErrRecoveryCodeNotFound
+}
+
+func (f *fakeTwoFactor) SaveLoginChallenge(_ context.Context, tokenHash []byte, userID int64, appID int32, expiresAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.challenges[string(tokenHash)] = &models.LoginChallenge{
+		ID:        int64(len(f.challenges) + 1),
+		UserID:    userID,
+		AppID:     appID,
+		ExpiresAt: expiresAt,
+	}
+
+	return nil
+}
+
+func (f *fakeTwoFactor) ClaimChallengeAttempt(
+	_ context.Context,
+	tokenHash []byte,
+	now time.Time,
+	maxAttempts int,
+) (models.LoginChallenge, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.challenges[string(tokenHash)]
+	if !ok || c.UsedAt != nil || !c.ExpiresAt.After(now) || c.Attempts >= maxAttempts {
+		return models.LoginChallenge{}, storage.ErrChallengeNotFound
+	}
+
+	c.Attempts++
+
+	return *c, nil
+}
+
+func (f *fakeTwoFactor) ConsumeLoginChallenge(_ context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, c := range f.challenges {
+		if c.ID == id && c.UsedAt == nil {
+			now := time.Now()
+			c.UsedAt = &now
+			return nil
+		}
+	}
+
+	return storage.ErrChallengeNotFound
+}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func newTestAuth(t *testing.T, store *fakeStore) *Auth {
+	t.Helper()
+
+	keys, err := jwt.LoadKeySet(t.TempDir(), "test")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return New(
+		log, store, store, store, newFakeAttempts(), newFakeTwoFactor(), keys,
+		time.Hour, 24*time.Hour, LockoutPolicy{}, TwoFactorConfig{}, time.Now,
+	)
+}
+
+// * Легитимный клиент и атакующий с украденной копией одновременно предъявляют
+// * один refresh токен. Успеть может только один, после чего вся сессия отзывается
+func TestRefreshConcurrentReuse(t *testing.T) {
+	for i := range 50 {
+		store := &fakeStore{}
+		a := newTestAuth(t, store)
+
+		store.addRefreshToken("stolen", 1, "family", time.Now().Add(time.Hour))
+
+		var (
+			wg       sync.WaitGroup
+			start    = make(chan struct{})
+			refresh  [2]string
+			failures [2]error
+		)
+
+		for j := range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				_, refresh[j], failures[j] = a.Refresh(context.Background(), "stolen", models.ClientInfo{})
+			}()
+		}
+
+		close(start)
+		wg.Wait()
+
+		succeeded := 0
+		for j := range 2 {
+			switch {
+			case failures[j] == nil:
+				succeeded++
+			case !errors.Is(failures[j], ErrInvalidCredentials):
+				t.Fatalf("run %d: Refresh: %v, want %v", i, failures[j], ErrInvalidCredentials)
+			}
+		}
+
+		if succeeded != 1 {
+			t.Fatalf("run %d: %d refreshes succeeded, want exactly 1", i, succeeded)
+		}
+
+		if !store.familyRevoked("family") {
+			t.Fatalf("run %d: session not revoked after concurrent reuse", i)
+		}
+
+		if len(store.events) == 0 || store.events[0] != models.SecurityEventRefreshTokenReuse {
+			t.Fatalf("run %d: security events = %v, want %q", i, store.events, models.SecurityEventRefreshTokenReuse)
+		}
+
+		// * токен, выданный победителю гонки, тоже отозван
+		for j := range 2 {
+			if failures[j] != nil {
+				continue
+			}
+
+			if _, _, err := a.Refresh(context.Background(), refresh[j], models.ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("run %d: refresh with winner's token: %v, want %v", i, err, ErrInvalidCredentials)
+			}
+		}
+	}
+}
+
+func TestRefreshRotatedTokenRevokesSession(t *testing.T) {
+	store := &fakeStore{}
+	a := newTestAuth(t, store)
+
+	store.addRefreshToken("token", 1, "family", time.Now().Add(time.Hour))
+
+	_, next, err := a.Refresh(context.Background(), "token", models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	if _, _, err := a.Refresh(context.Background(), "token", models.ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("reuse of rotated token: %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	if _, _, err := a.Refresh(context.Background(), next, models.ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("refresh after reuse: %v, want %v", err, ErrInvalidCredentials)
+	}
+}
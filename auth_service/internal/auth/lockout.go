@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"auth_service/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// * LockoutError сообщает, через сколько можно повторить вход
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter)
+}
+
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// * LockoutPolicy — после Threshold неудач подряд ключ блокируется на BaseDelay,
+// * каждая следующая неудача удваивает блокировку до MaxDelay. Счётчик
+// * сбрасывается, если неудач не было дольше ResetAfter
+type LockoutPolicy struct {
+	Threshold  int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	ResetAfter time.Duration
+}
+
+// * Delay возвращает длительность блокировки после failures неудач подряд
+func (p LockoutPolicy) Delay(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.Threshold; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+
+	return min(delay, p.MaxDelay)
+}
+
+// * attemptKeys — счётчики по IP и по аккаунту. Аккаунт считается по email
+// * даже если пользователя нет, чтобы блокировка не выдавала существование
+func attemptKeys(email string, client models.ClientInfo) (ipKey, accountKey string) {
+	return "ip:" + client.IP, "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// * twoFactorAttemptKey — счётчик неверных кодов 2FA пользователя
+func twoFactorAttemptKey(userID int64) string {
+	return "2fa:" + strconv.FormatInt(userID, 10)
+}
+
+// * dummyHash сравнивается с паролем при входе в несуществующий аккаунт,
+// * чтобы время ответа не отличалось от неверного пароля
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password for timing"), bcrypt.DefaultCost)
+	return hash
+})
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"auth_service/internal/models"
+)
+
+var testLockout = LockoutPolicy{
+	Threshold:  3,
+	BaseDelay:  time.Minute,
+	MaxDelay:   10 * time.Minute,
+	ResetAfter: 15 * time.Minute,
+}
+
+func TestLockoutDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: 0},
+		{failures: 2, want: 0},
+		{failures: 3, want: time.Minute},
+		{failures: 4, want: 2 * time.Minute},
+		{failures: 5, want: 4 * time.Minute},
+		{failures: 6, want: 8 * time.Minute},
+		{failures: 7, want: 10 * time.Minute},
+		{failures: 100, want: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("failures=%d", tt.failures), func(t *testing.T) {
+			if got := testLockout.Delay(tt.failures); got != tt.want {
+				t.Errorf("Delay(%d) = %s, want %s", tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+// * newLockoutAuth возвращает сервис с пользователем user@example.com / secret,
+// * политикой testLockout и часами, которые двигает только тест
+func newLockoutAuth(t *testing.T) (*Auth, *fakeClock) {
+	t.Helper()
+
+	store := &fakeStore{}
+	store.addUser(t, "user@example.com", "secret")
+
+	clock := newFakeClock()
+
+	a := newTestAuth(t, store)
+	a.lockout = testLockout
+	a.now = clock.Now
+
+	return a, clock
+}
+
+func login(a *Auth, email, password, ip string) error {
+	_, err := a.Login(context.Background(), email, password, 1, models.ClientInfo{IP: ip})
+	return err
+}
+
+// * retryAfter проверяет, что вход заблокирован, и возвращает оставшееся время
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+
+	var lockout *LockoutError
+	if !errors.As(err, &lockout) {
+		t.Fatalf("Login: %v, want %v", err, ErrTooManyAttempts)
+	}
+
+	return lockout.RetryAfter
+}
+
+func TestLoginLockout(t *testing.T) {
+	t.Run("locks after threshold until delay passes", func(t *testing.T) {
+		a, clock := newLockoutAuth(t)
+
+		for range testLockout.Threshold {
+			if err := login(a, "user@example.com", "wrong", "10.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("Login with wrong password: %v, want %v", err, ErrInvalidCredentials)
+			}
+		}
+
+		// * верный пароль во время блокировки не проверяется
+		if got := retryAfter(t, login(a, "user@example.com", "secret", "10.0.0.1")); got != time.Minute {
+			t.Errorf("RetryAfter = %s, want 1m", got)
+		}
+
+		clock.Advance(59 * time.Second)
+
+		if got := retryAfter(t, login(a, "user@example.com", "secret", "10.0.0.1")); got != time.Second {
+			t.Errorf("RetryAfter = %s, want 1s", got)
+		}
+
+		clock.Advance(time.Second)
+
+		if err := login(a, "user@example.com", "secret", "10.0.0.1"); err != nil {
+			t.Fatalf("Login after lockout expired: %v", err)
+		}
+	})
+
+	t.Run("delay doubles up to max", func(t *testing.T) {
+		a, clock := newLockoutAuth(t)
+
+		for range testLockout.Threshold - 1 {
+			_ = login(a, "user@example.com", "wrong", "10.0.0.1")
+		}
+
+		for _, want := range []time.Duration{
+			time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute,
+		} {
+			if err := login(a, "user@example.com", "wrong", "10.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("Login with wrong password: %v, want %v", err, ErrInvalidCredentials)
+			}
+
+			if got := retryAfter(t, login(a, "user@example.com", "wrong", "10.0.0.1")); got != want {
+				t.Errorf("RetryAfter = %s, want %s", got, want)
+			}
+
+			clock.Advance(want)
+		}
+	})
+
+	t.Run("counter resets after quiet period", func(t *testing.T) {
+		a, clock := newLockoutAuth(t)
+
+		for range testLockout.Threshold - 1 {
+			_ = login(a, "user@example.com", "wrong", "10.0.0.1")
+		}
+
+		clock.Advance(testLockout.ResetAfter + time.Second)
+
+		for range testLockout.Threshold - 1 {
+			_ = login(a, "user@example.com", "wrong", "10.0.0.1")
+		}
+
+		if err := login(a, "user@example.com", "wrong", "10.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Login after counter reset: %v, want %v", err, ErrInvalidCredentials)
+		}
+	})
+
+	t.Run("account locks across IPs", func(t *testing.T) {
+		a, _ := newLockoutAuth(t)
+
+		for i := range testLockout.Threshold {
+			_ = login(a, "user@example.com", "wrong", fmt.Sprintf("10.0.0.%d", i+1))
+		}
+
+		retryAfter(t, login(a, "User@Example.com ", "secret", "10.0.1.1"))
+	})
+
+	t.Run("unknown email locks like existing one", func(t *testing.T) {
+		a, _ := newLockoutAuth(t)
+
+		for i := range testLockout.Threshold {
+			err := login(a, "nobody@example.com", "wrong", fmt.Sprintf("10.0.0.%d", i+1))
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("Login with unknown email: %v, want %v", err, ErrInvalidCredentials)
+			}
+		}
+
+		if got := retryAfter(t, login(a, "nobody@example.com", "wrong", "10.0.1.1")); got != time.Minute {
+			t.Errorf("RetryAfter = %s, want 1m", got)
+		}
+	})
+
+	t.Run("successful login resets account counter", func(t *testing.T) {
+		a, _ := newLockoutAuth(t)
+
+		for i := range testLockout.Threshold - 1 {
+			_ = login(a, "user@example.com", "wrong", fmt.Sprintf("10.0.0.%d", i+1))
+		}
+
+		if err := login(a, "user@example.com", "secret", "10.0.1.1"); err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+
+		if err := login(a, "user@example.com", "wrong", "10.0.2.1"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Login after reset: %v, want %v", err, ErrInvalidCredentials)
+		}
+
+		if err := login(a, "user@example.com", "secret", "10.0.3.1"); err != nil {
+			t.Fatalf("Login after reset: %v", err)
+		}
+	})
+}
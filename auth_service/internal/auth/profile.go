@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	sl "auth_service/internal/lib/logger"
+	"auth_service/internal/lib/verification"
+	"auth_service/internal/models"
+	"auth_service/internal/storage"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUsernameTaken     = errors.New("username already taken")
+	ErrEmailTaken        = errors.New("email already taken")
+	ErrSameEmail         = errors.New("new email matches the current one")
+	ErrInvalidEmailToken = errors.New("invalid or expired email change token")
+)
+
+// * Profile возвращает профиль текущего пользователя
+func (a *Auth) Profile(ctx context.Context, userID int64) (models.Profile, error) {
+	const op = "auth.Profile"
+
+	user, err := a.usrProvider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.Profile{}, storage.ErrUserNotFound
+		}
+
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user.Profile(), nil
+}
+
+// * UpdateProfile меняет переданные поля профиля. Остальные сервисы узнают
+// * об изменении из события user.updated
+func (a *Auth) UpdateProfile(ctx context.Context, userID int64, upd models.ProfileUpdate) (models.Profile, error) {
+	const op = "auth.UpdateProfile"
+
+	user, err := a.usrSaver.UpdateProfile(ctx, userID, upd, a.now())
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrUsernameTaken):
+			return models.Profile{}, ErrUsernameTaken
+		case errors.Is(err, storage.ErrUserNotFound):
+			return models.Profile{}, storage.ErrUserNotFound
+		}
+
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.log.Info("profile updated", slog.String("op", op), slog.Int64("uid", userID))
+
+	return user.Profile(), nil
+}
+
+// * ChangePassword меняет пароль после проверки текущего. Все сессии, кроме
+// * текущей, завершаются
+func (a *Auth) ChangePassword(
+	ctx context.Context,
+	userID int64,
+	currentSessionID string,
+	oldPassword, newPassword string,
+) error {
+	const op = "auth.ChangePassword"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("uid", userID))
+
+	user, err := a.usrProvider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return storage.ErrUserNotFound
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(oldPassword)); err != nil {
+		log.Warn("invalid current password on password change")
+		return ErrInvalidCredentials
+	}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("failed to generate password hash", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.usrSaver.ChangePassword(ctx, userID, passHash, currentSessionID); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return storage.ErrUserNotFound
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("password changed")
+
+	return nil
+}
+
+// * RequestEmailChange запоминает новый адрес и выпускает токен его подтверждения.
+// * Email меняется только после перехода по ссылке, отправленной на новый адрес
+func (a *Auth) RequestEmailChange(
+	ctx context.Context,
+	userID int64,
+	password, newEmail string,
+	tokenTTL time.Duration,
+	tokenSecret string,
+) (models.User, string, error) {
+	const op = "auth.RequestEmailChange"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("uid", userID))
+
+	user, err := a.usrProvider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, "", storage.ErrUserNotFound
+		}
+
+		return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
+		log.Warn("invalid password on email change")
+		return models.User{}, "", ErrInvalidCredentials
+	}
+
+	if strings.EqualFold(user.Email, newEmail) {
+		return models.User{}, "", ErrSameEmail
+	}
+
+	if _, err := a.usrProvider.User(ctx, newEmail); err == nil {
+		return models.User{}, "", ErrEmailTaken
+	} else if !errors.Is(err, storage.ErrUserNotFound) {
+		return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.usrSaver.SetPendingEmail(ctx, userID, newEmail, verification.PurposeEmailChange); err != nil {
+		return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := a.issueOneTimeToken(ctx, userID, verification.PurposeEmailChange, tokenTTL, tokenSecret)
+	if err != nil {
+		return models.User{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	user.PendingEmail = newEmail
+
+	log.Info("email change requested")
+
+	return user, token, nil
+}
+
+// * ConfirmEmailChange заменяет email адресом, подтверждённым по ссылке из письма
+func (a *Auth) ConfirmEmailChange(ctx context.Context, token, tokenSecret string) (models.Profile, error) {
+	const op = "auth.ConfirmEmailChange"
+
+	log := a.log.With(slog.String("op", op))
+
+	userID, jti, err := verification.ParseOneTimeToken(token, tokenSecret, verification.PurposeEmailChange)
+	if err != nil {
+		log.Warn("invalid email change token", sl.Err(err))
+		return models.Profile{}, ErrInvalidEmailToken
+	}
+
+	user, err := a.usrSaver.ConfirmEmailChange(ctx, userID, jti, verification.PurposeEmailChange, a.now())
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrEmailTaken):
+			log.Warn("email taken before confirmation", slog.Int64("uid", userID))
+			return models.Profile{}, ErrEmailTaken
+		case errors.Is(err, storage.ErrOneTimeTokenNotFound),
+			errors.Is(err, storage.ErrOneTimeTokenUsed),
+			errors.Is(err, storage.ErrNoPendingEmail):
+			log.Warn("email change token already used or superseded", slog.Int64("uid", userID))
+			return models.Profile{}, ErrInvalidEmailToken
+		}
+
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("email changed", slog.Int64("uid", userID))
+
+	return user.Profile(), nil
+}
@@ -0,0 +1,399 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"auth_service/internal/lib/jwt"
+	sl "auth_service/internal/lib/logger"
+	"auth_service/internal/lib/secretbox"
+	"auth_service/internal/lib/totp"
+	"auth_service/internal/models"
+	"auth_service/internal/storage"
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication not enabled")
+	ErrTwoFactorNotEnrolled = errors.New("two-factor enrollment not started")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	ErrInvalidChallenge     = errors.New("invalid or expired login challenge")
+)
+
+const recoveryCodeCount = 10
+
+// * TwoFactorStore хранит TOTP секреты, коды восстановления и незавершённые входы
+type TwoFactorStore interface {
+	TwoFactor(ctx context.Context, userID int64) (models.TwoFactor, error)
+	SavePendingTwoFactor(ctx context.Context, userID int64, secret []byte) (bool, error)
+	ConfirmTwoFactor(ctx context.Context, userID int64, recoveryCodeHashes [][]byte) error
+	DeleteTwoFactor(ctx context.Context, userID int64) error
+	UseTOTPStep(ctx context.Context, userID int64, step int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, recoveryCodeHashes [][]byte) error
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash []byte) error
+
+	SaveLoginChallenge(ctx context.Context, tokenHash []byte, userID int64, appID int32, expiresAt time.Time) error
+	ClaimChallengeAttempt(ctx context.Context, tokenHash []byte, now time.Time, maxAttempts int) (models.LoginChallenge, error)
+	ConsumeLoginChallenge(ctx context.Context, id int64) error
+}
+
+// * TwoFactorConfig — шифрование секретов, подпись в приложении-аутентификаторе
+// * и ограничения на challenge токен второго шага входа
+type TwoFactorConfig struct {
+	Box           *secretbox.Box
+	Issuer        string
+	ChallengeTTL  time.Duration
+	MaxAttempts   int
+	RecoveryCodes int
+}
+
+// * LoginResult — либо пара токенов, либо ChallengeToken, если нужен второй фактор
+type LoginResult struct {
+	AccessToken    string
+	RefreshToken   string
+	ChallengeToken string
+}
+
+// * EnrollTwoFactor генерирует новый TOTP секрет. 2FA включается только
+// * после ConfirmTwoFactor с кодом из приложения
+func (a *Auth) EnrollTwoFactor(ctx context.Context, userID int64) (secret, uri string, err error) {
+	const op = "auth.EnrollTwoFactor"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("uid", userID))
+
+	user, err := a.usrProvider.UserByID(ctx, userID)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	secret, err = totp.GenerateSecret()
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	sealed, err := a.twoFactorCfg.Box.Seal([]byte(secret))
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved, err := a.twoFactor.SavePendingTwoFactor(ctx, userID, sealed)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !saved {
+		return "", "", ErrTwoFactorEnabled
+	}
+
+	log.Info("two-factor enrollment started")
+
+	return secret, totp.URI(a.twoFactorCfg.Issuer, user.Email, secret), nil
+}
+
+// * ConfirmTwoFactor включает 2FA по первому коду и возвращает коды восстановления
+func (a *Auth) ConfirmTwoFactor(ctx context.Context, userID int64, code string) ([]string, error) {
+	const op = "auth.ConfirmTwoFactor"
+
+	log := a.log.With(slog.String("op", op), slog.Int64("uid", userID))
+
+	tf, err := a.twoFactor.TwoFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTwoFactorNotFound) {
+			return nil, ErrTwoFactorNotEnrolled
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tf.ConfirmedAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	err = a.limitCodeAttempts(ctx, userID, func() error {
+		return a.checkTOTP(ctx, tf, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes(a.twoFactorCfg.RecoveryCodes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.twoFactor.ConfirmTwoFactor(ctx, userID, hashes); err != nil {
+		if errors.Is(err, storage.ErrTwoFactorNotFound) {
+			return nil, ErrTwoFactorEnabled
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("two-factor authentication enabled")
+
+	return codes, nil
+}
+
+// * RegenerateRecoveryCodes выдаёт новый набор кодов восстановления, старые перестают действовать
+func (a *Auth) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	const op = "auth.RegenerateRecoveryCodes"
+
+	if err := a.verifySecondFactor(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes(a.twoFactorCfg.RecoveryCodes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.twoFactor.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.log.Info("recovery codes regenerated", slog.String("op", op), slog.Int64("uid", userID))
+
+	return codes, nil
+}
+
+// * DisableTwoFactor выключает 2FA; требуется действующий код или код восстановления
+func (a *Auth) DisableTwoFactor(ctx context.Context, userID int64, code string) error {
+	const op = "auth.DisableTwoFactor"
+
+	if err := a.verifySecondFactor(ctx, userID, code); err != nil {
+		return err
+	}
+
+	if err := a.twoFactor.DeleteTwoFactor(ctx, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	a.log.Info("two-factor authentication disabled", slog.String("op", op), slog.Int64("uid", userID))
+
+	return nil
+}
+
+// * LoginTwoFactor завершает вход: обменивает challenge токен и код на пару токенов.
+// * Каждый ввод кода расходует попытку challenge, после MaxAttempts он перестаёт
+// * действовать; неверные коды также учитываются в блокировке 2FA пользователя
+func (a *Auth) LoginTwoFactor(
+	ctx context.Context,
+	challengeToken string,
+	code string,
+	client models.ClientInfo,
+) (LoginResult, error) {
+	const op = "auth.LoginTwoFactor"
+
+	log := a.log.With(slog.String("op", op))
+
+	challenge, err := a.twoFactor.ClaimChallengeAttempt(
+		ctx,
+		jwt.HashRefreshToken(challengeToken),
+		a.now(),
+		a.twoFactorCfg.MaxAttempts,
+	)
+	if err != nil {
+		if errors.Is(err, storage.ErrChallengeNotFound) {
+			return LoginResult{}, ErrInvalidChallenge
+		}
+		return LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := a.verifySecondFactor(ctx, challenge.UserID, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			log.Info("invalid second factor code",
+				slog.Int64("uid", challenge.UserID),
+				slog.Int("attempts", challenge.Attempts),
+			)
+		}
+		return LoginResult{}, err
+	}
+
+	if err := a.twoFactor.ConsumeLoginChallenge(ctx, challenge.ID); err != nil {
+		if errors.Is(err, storage.ErrChallengeNotFound) {
+			return LoginResult{}, ErrInvalidChallenge
+		}
+		return LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.usrProvider.UserByID(ctx, challenge.UserID)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	app, err := a.appProvider.App(ctx, challenge.AppID)
+	if err != nil {
+		return LoginResult{}, ErrInvalidAppID
+	}
+
+	result, err := a.issueTokens(ctx, user, app, client)
+	if err != nil {
+		return LoginResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user logged in with second factor", slog.Int64("uid", user.ID))
+
+	return result, nil
+}
+
+// * issueChallenge создаёт challenge токен для второго шага входа
+func (a *Auth) issueChallenge(ctx context.Context, userID int64, appID int32) (string, error) {
+	token, err := jwt.NewRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := a.now().Add(a.twoFactorCfg.ChallengeTTL)
+
+	if err := a.twoFactor.SaveLoginChallenge(ctx, jwt.HashRefreshToken(token), userID, appID, expiresAt); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// * twoFactorEnabled сообщает, подтверждена ли у пользователя 2FA
+func (a *Auth) twoFactorEnabled(ctx context.Context, userID int64) (bool, error) {
+	tf, err := a.twoFactor.TwoFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTwoFactorNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return tf.ConfirmedAt != nil, nil
+}
+
+// * verifySecondFactor принимает шестизначный TOTP код или код восстановления.
+// * Проверка идёт через limitCodeAttempts
+func (a *Auth) verifySecondFactor(ctx context.Context, userID int64, code string) error {
+	return a.limitCodeAttempts(ctx, userID, func() error {
+		return a.checkSecondFactor(ctx, userID, code)
+	})
+}
+
+// * limitCodeAttempts защищает проверку кода 2FA от перебора: неверные коды
+// * считаются по ключу пользователя в той же LockoutPolicy, что и пароли,
+// * во время блокировки код не проверяется (ErrTooManyAttempts), верный код
+// * сбрасывает счётчик. Ключ общий для входа, подтверждения и отключения 2FA,
+// * новых кодов восстановления и удаления аккаунта
+func (a *Auth) limitCodeAttempts(ctx context.Context, userID int64, check func() error) error {
+	log := a.log.With(slog.String("op", "auth.limitCodeAttempts"), slog.Int64("uid", userID))
+
+	key := twoFactorAttemptKey(userID)
+
+	lockedUntil, err := a.attempts.LoginLockedUntil(ctx, []string{key})
+	if err != nil {
+		return err
+	}
+
+	if now := a.now(); now.Before(lockedUntil) {
+		log.Warn("two-factor code check locked")
+		return &LockoutError{RetryAfter: lockedUntil.Sub(now)}
+	}
+
+	if err := check(); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			a.registerFailures(ctx, log, key)
+		}
+		return err
+	}
+
+	if err := a.attempts.ResetLoginFailures(ctx, key); err != nil {
+		log.Error("failed to reset two-factor failures", sl.Err(err))
+	}
+
+	return nil
+}
+
+func (a *Auth) checkSecondFactor(ctx context.Context, userID int64, code string) error {
+	tf, err := a.twoFactor.TwoFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTwoFactorNotFound) {
+			return ErrTwoFactorNotEnabled
+		}
+		return err
+	}
+
+	if tf.ConfirmedAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+
+	if len(code) == totp.Digits {
+		return a.checkTOTP(ctx, tf, code)
+	}
+
+	err = a.twoFactor.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if err != nil {
+		if errors.Is(err, storage.ErrRecoveryCodeNotFound) {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+
+	a.log.Info("recovery code used", slog.Int64("uid", userID))
+
+	return nil
+}
+
+func (a *Auth) checkTOTP(ctx context.Context, tf models.TwoFactor, code string) error {
+	secret, err := a.twoFactorCfg.Box.Open(tf.Secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(string(secret), code, a.now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	if err := a.twoFactor.UseTOTPStep(ctx, tf.UserID, step); err != nil {
+		if errors.Is(err, storage.ErrTOTPStepUsed) {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+
+	return nil
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// * newRecoveryCodes возвращает коды вида xxxxx-xxxxx и их SHA-256 хэши для хранения
+func newRecoveryCodes(n int) ([]string, [][]byte, error) {
+	if n <= 0 {
+		n = recoveryCodeCount
+	}
+
+	codes := make([]string, 0, n)
+	hashes := make([][]byte, 0, n)
+
+	for range n {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+		code := raw[:5] + "-" + raw[5:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) []byte {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return sum[:]
+}
@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"auth_service/internal/lib/secretbox"
+	"auth_service/internal/lib/totp"
+	"auth_service/internal/models"
+)
+
+// * twoFactorEnv — пользователь user@example.com / secret с TOTP секретом
+type twoFactorEnv struct {
+	auth      *Auth
+	clock     *fakeClock
+	twoFactor *fakeTwoFactor
+	user      models.User
+	secret    string
+}
+
+func newTwoFactorEnv(t *testing.T, confirmed bool) *twoFactorEnv {
+	t.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("rand: %v", err)
+	}
+
+	box, err := secretbox.New(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		t.Fatalf("secretbox.New: %v", err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+
+	sealed, err := box.Seal([]byte(secret))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	store := &fakeStore{}
+	user := store.addUser(t, "user@example.com", "secret")
+
+	clock := newFakeClock()
+	twoFactor := newFakeTwoFactor()
+
+	tf := models.TwoFactor{UserID: user.ID, Secret: sealed}
+	if confirmed {
+		confirmedAt := clock.Now()
+		tf.ConfirmedAt = &confirmedAt
+	}
+	twoFactor.configs[user.ID] = tf
+
+	a := newTestAuth(t, store)
+	a.twoFactor = twoFactor
+	a.lockout = testLockout
+	a.now = clock.Now
+	a.twoFactorCfg = TwoFactorConfig{Box: box, ChallengeTTL: 5 * time.Minute, MaxAttempts: 5}
+
+	return &twoFactorEnv{auth: a, clock: clock, twoFactor: twoFactor, user: user, secret: secret}
+}
+
+// * codes возвращает действующий код и заведомо неверный код того же шага
+func (e *twoFactorEnv) codes(t *testing.T) (valid, invalid string) {
+	t.Helper()
+
+	valid, err := totp.Code(e.secret, e.clock.Now())
+	if err != nil {
+		t.Fatalf("totp.Code: %v", err)
+	}
+
+	last := (valid[len(valid)-1]-'0'+5)%10 + '0'
+
+	return valid, valid[:len(valid)-1] + string(last)
+}
+
+// * Все проверки кода 2FA проходят через блокировку: после Threshold неверных
+// * кодов даже верный код отклоняется до истечения задержки
+func TestTwoFactorCodeLockout(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		confirmed bool
+		check     func(a *Auth, userID int64, code string) error
+	}{
+		{
+			name:      "confirm",
+			confirmed: false,
+			check: func(a *Auth, userID int64, code string) error {
+				_, err := a.ConfirmTwoFactor(ctx, userID, code)
+				return err
+			},
+		},
+		{
+			name:      "disable",
+			confirmed: true,
+			check: func(a *Auth, userID int64, code string) error {
+				return a.DisableTwoFactor(ctx, userID, code)
+			},
+		},
+		{
+			name:      "regenerate recovery codes",
+			confirmed: true,
+			check: func(a *Auth, userID int64, code string) error {
+				_, err := a.RegenerateRecoveryCodes(ctx, userID, code)
+				return err
+			},
+		},
+		{
+			name:      "delete account",
+			confirmed: true,
+			check: func(a *Auth, userID int64, code string) error {
+				return a.DeleteAccount(ctx, userID, "secret", code)
+			},
+		},
+		{
+			name:      "login",
+			confirmed: true,
+			check: func(a *Auth, _ int64, code string) error {
+				result, err := a.Login(ctx, "user@example.com", "secret", 1, models.ClientInfo{})
+				if err != nil {
+					return err
+				}
+
+				_, err = a.LoginTwoFactor(ctx, result.ChallengeToken, code, models.ClientInfo{})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTwoFactorEnv(t, tt.confirmed)
+
+			for range testLockout.Threshold {
+				_, invalid := env.codes(t)
+
+				if err := tt.check(env.auth, env.user.ID, invalid); !errors.Is(err, ErrInvalidTwoFactorCode) {
+					t.Fatalf("invalid code: %v, want %v", err, ErrInvalidTwoFactorCode)
+				}
+			}
+
+			valid, _ := env.codes(t)
+
+			err := tt.check(env.auth, env.user.ID, valid)
+			if got := retryAfter(t, err); got != testLockout.BaseDelay {
+				t.Errorf("RetryAfter = %s, want %s", got, testLockout.BaseDelay)
+			}
+
+			env.clock.Advance(testLockout.BaseDelay)
+
+			valid, _ = env.codes(t)
+			if err := tt.check(env.auth, env.user.ID, valid); err != nil {
+				t.Fatalf("valid code after lockout expired: %v", err)
+			}
+		})
+	}
+}
+
+// * Счётчик общий: неудачи в разных операциях складываются
+func TestTwoFactorLockoutShared(t *testing.T) {
+	ctx := context.Background()
+	env := newTwoFactorEnv(t, true)
+
+	_, invalid := env.codes(t)
+
+	for range testLockout.Threshold - 1 {
+		if err := env.auth.DisableTwoFactor(ctx, env.user.ID, invalid); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("DisableTwoFactor: %v, want %v", err, ErrInvalidTwoFactorCode)
+		}
+	}
+
+	if _, err := env.auth.RegenerateRecoveryCodes(ctx, env.user.ID, invalid); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("RegenerateRecoveryCodes: %v, want %v", err, ErrInvalidTwoFactorCode)
+	}
+
+	valid, _ := env.codes(t)
+	retryAfter(t, env.auth.DeleteAccount(ctx, env.user.ID, "secret", valid))
+}
+
+// * Параллельные запросы с одним challenge токеном не проверяют больше
+// * MaxAttempts кодов: попытка расходуется до проверки
+func TestLoginTwoFactorConcurrentAttempts(t *testing.T) {
+	ctx := context.Background()
+	env := newTwoFactorEnv(t, true)
+
+	// * блокировка по пользователю не должна вмешиваться в счётчик challenge
+	env.auth.lockout = LockoutPolicy{Threshold: 1000, BaseDelay: time.Minute, MaxDelay: time.Minute}
+
+	result, err := env.auth.Login(ctx, "user@example.com", "secret", 1, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	_, invalid := env.codes(t)
+
+	const requests = 50
+
+	var (
+		wg    sync.WaitGroup
+		start = make(chan struct{})
+		errs  = make([]error, requests)
+	)
+
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[i] = env.auth.LoginTwoFactor(ctx, result.ChallengeToken, invalid, models.ClientInfo{})
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	checked := 0
+	for _, err := range errs {
+		switch {
+		case errors.Is(err, ErrInvalidTwoFactorCode):
+			checked++
+		case !errors.Is(err, ErrInvalidChallenge):
+			t.Fatalf("LoginTwoFactor: %v", err)
+		}
+	}
+
+	if checked != env.auth.twoFactorCfg.MaxAttempts {
+		t.Errorf("codes checked = %d, want %d", checked, env.auth.twoFactorCfg.MaxAttempts)
+	}
+
+	valid, _ := env.codes(t)
+	if _, err := env.auth.LoginTwoFactor(ctx, result.ChallengeToken, valid, models.ClientInfo{}); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("valid code after attempts exhausted: %v, want %v", err, ErrInvalidChallenge)
+	}
+}
@@ -13,10 +13,40 @@ type Config struct {
 	RabbitMQ   `yaml:"rabbitmq"`
 	Postgres   `yaml:"postgres"`
 	HTTPServer `yaml:"http_server"`
+
+	ResendVerification RateLimit `yaml:"resend_verification"`
+	LoginLockout       `yaml:"login_lockout"`
+	TwoFactor          `yaml:"two_factor"`
+	Outbox             `yaml:"outbox"`
+}
+
+// * TwoFactor — TOTP: ключ шифрования секретов (32 байта в base64) и параметры второго шага входа
+type TwoFactor struct {
+	EncryptionKey string        `yaml:"encryption_key" env:"TWO_FACTOR_ENCRYPTION_KEY" env-required:"true"`
+	Issuer        string        `yaml:"issuer" env-default:"Price Monitoring"`
+	ChallengeTTL  time.Duration `yaml:"challenge_ttl" env-default:"5m"`
+	MaxAttempts   int           `yaml:"max_attempts" env-default:"5"`
+	RecoveryCodes int           `yaml:"recovery_codes" env-default:"10"`
+}
+
+// * LoginLockout — блокировка входа после серии неудачных попыток
+type LoginLockout struct {
+	Threshold  int           `yaml:"threshold" env-default:"5"`
+	BaseDelay  time.Duration `yaml:"base_delay" env-default:"1m"`
+	MaxDelay   time.Duration `yaml:"max_delay" env-default:"1h"`
+	ResetAfter time.Duration `yaml:"reset_after" env-default:"24h"`
+}
+
+// * RateLimit — не более Limit запросов на один email за Window
+type RateLimit struct {
+	Limit  int           `yaml:"limit" env-default:"3"`
+	Window time.Duration `yaml:"window" env-default:"1h"`
 }
 
 type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8080"`
+	PublicURL   string        `yaml:"public_url" env-default:"http://localhost:8082"`
+	ResetURL    string        `yaml:"password_reset_url" env-default:"http://localhost:8082/password/reset"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
 }
@@ -35,11 +65,31 @@ type Tokens struct {
 	RefreshTokenTTL         time.Duration `yaml:"refresh_token_ttl" env-required:"true"`
 	VerificationTokenTTL    time.Duration `yaml:"verification_token_ttl" env-required:"true"`
 	VerificationTokenSecret string        `yaml:"verification_token_secret" env-required:"true"`
+	PasswordResetTokenTTL   time.Duration `yaml:"password_reset_token_ttl" env-default:"30m"`
+	EmailChangeTokenTTL     time.Duration `yaml:"email_change_token_ttl" env-default:"24h"`
+	SigningKeys             `yaml:"signing_keys"`
+}
+
+// * SigningKeys — каталог с Ed25519 ключами <kid>.pem и ключ, которым подписываются новые токены
+type SigningKeys struct {
+	Dir         string `yaml:"dir" env-default:"./keys"`
+	ActiveKeyID string `yaml:"active_key_id" env-required:"true"`
 }
 
 type RabbitMQ struct {
-	URL       string `yaml:"url" env-required:"true"`
-	QueueName string `yaml:"queue_name" env-required:"true"`
+	URL             string `yaml:"url" env-required:"true"`
+	Exchange        string `yaml:"exchange" env-default:"price_monitoring"`
+	QueueName       string `yaml:"queue_name" env-required:"true"`
+	EventsQueueName string `yaml:"events_queue_name" env-default:"user_events"`
+}
+
+// * Outbox — публикация событий об аккаунтах, записанных в одной транзакции с изменением
+type Outbox struct {
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1m"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+	ClaimTimeout time.Duration `yaml:"claim_timeout" env-default:"1m"`
+	Retention    time.Duration `yaml:"retention" env-default:"24h"`
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"10"`
 }
 
 func MustLoad(configPath string) *Config {
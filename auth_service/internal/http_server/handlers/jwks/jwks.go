@@ -0,0 +1,18 @@
+package jwks
+
+import (
+	"net/http"
+
+	"auth_service/internal/lib/jwt"
+
+	"github.com/go-chi/render"
+)
+
+// * New отдаёт публичные ключи проверки access токенов
+func New(keys *jwt.KeySet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+
+		render.JSON(w, r, keys.JWKS())
+	}
+}
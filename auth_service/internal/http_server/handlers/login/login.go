@@ -4,13 +4,15 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"auth_service/internal/auth"
+	"auth_service/internal/lib/api/client"
 	resp "auth_service/internal/lib/api/response"
 	sl "auth_service/internal/lib/logger"
-	"auth_service/internal/storage"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
@@ -23,10 +25,14 @@ type Request struct {
 	AppID int32  `json:"app_id" validate:"required"`
 }
 
+// * Response при включённой 2FA содержит только challenge_token,
+// * который обменивается на токены через POST /login/2fa
 type Response struct {
 	resp.Response
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken       string `json:"access_token,omitempty"`
+	RefreshToken      string `json:"refresh_token,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
 func New(
@@ -70,11 +76,15 @@ func New(
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
-		accessToken, refreshToken, err := authMiddleware.Login(ctx, req.Email, req.Pass, req.AppID)
+		result, err := authMiddleware.Login(ctx, req.Email, req.Pass, req.AppID, client.Info(r))
 		if err != nil {
+			var lockoutErr *auth.LockoutError
+
 			switch {
-			case errors.Is(err, storage.ErrUserNotFound):
-				render.JSON(w, r, resp.Error("User not found"))
+			case errors.As(err, &lockoutErr):
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockoutErr.RetryAfter.Seconds()))))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Error("Too many failed attempts, try again later"))
 				return
 			case errors.Is(err, auth.ErrInvalidCredentials):
 				render.JSON(w, r, resp.Error("Invalid credentials"))
@@ -95,16 +105,22 @@ func New(
 			return
 		}
 
-		log.Info("User logged in successfully")
+		if result.ChallengeToken != "" {
+			log.Info("Second factor required")
+		} else {
+			log.Info("User logged in successfully")
+		}
 
-		ResponseOK(w, r, accessToken, refreshToken)
+		ResponseOK(w, r, result)
 	}
 }
 
-func ResponseOK(w http.ResponseWriter, r *http.Request, accessToken, refreshToken string) {
+func ResponseOK(w http.ResponseWriter, r *http.Request, result auth.LoginResult) {
 	render.JSON(w, r, Response{
-		Response:     resp.OK(),
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		Response:          resp.OK(),
+		AccessToken:       result.AccessToken,
+		RefreshToken:      result.RefreshToken,
+		TwoFactorRequired: result.ChallengeToken != "",
+		ChallengeToken:    result.ChallengeToken,
 	})
 }
@@ -0,0 +1,67 @@
+package logoutall
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"auth_service/internal/auth"
+	authMiddleware "auth_service/internal/http_server/middleware/auth"
+	resp "auth_service/internal/lib/api/response"
+	sl "auth_service/internal/lib/logger"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+}
+
+// * New завершает все сессии пользователя, включая текущую
+func New(
+	log *slog.Logger,
+	authService *auth.Auth,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.logoutall.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(authMiddleware.UserIDKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if err := authService.LogoutAll(ctx, userID); err != nil {
+			log.Error("failed to logout everywhere", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		log.Info("user logged out everywhere", slog.Int64("uid", userID))
+
+		ResponseOK(w, r)
+	}
+}
+
+func ResponseOK(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+	})
+}
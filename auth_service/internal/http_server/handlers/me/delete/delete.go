@@ -0,0 +1,125 @@
+package deleteMe
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auth_service/internal/auth"
+	authMiddleware "auth_service/internal/http_server/middleware/auth"
+	resp "auth_service/internal/lib/api/response"
+	sl "auth_service/internal/lib/logger"
+	"auth_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code,omitempty"` // * обязателен, если включена 2FA
+}
+
+type Response struct {
+	resp.Response
+}
+
+// * EventTrigger запускает публикацию события об удалении без ожидания
+type EventTrigger interface {
+	Trigger()
+}
+
+// * New удаляет аккаунт текущего пользователя; данные в остальных сервисах
+// * удаляются по событию user.deleted
+func New(
+	log *slog.Logger,
+	validate *validator.Validate,
+	authService *auth.Auth,
+	events EventTrigger,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.me.delete.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(authMiddleware.UserIDKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("Failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Failed to decode request"))
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("Invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		err = authService.DeleteAccount(ctx, userID, req.Password, req.Code)
+		if err != nil {
+			var lockoutErr *auth.LockoutError
+
+			switch {
+			case errors.Is(err, auth.ErrInvalidCredentials):
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("invalid password"))
+			case errors.As(err, &lockoutErr):
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockoutErr.RetryAfter.Seconds()))))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Error("Too many failed attempts, try again later"))
+			case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("invalid code"))
+			case errors.Is(err, storage.ErrUserNotFound):
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("user not found"))
+			default:
+				log.Error("failed to delete account", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("Internal error"))
+			}
+
+			return
+		}
+
+		events.Trigger()
+
+		log.Info("account deleted", slog.Int64("uid", userID))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package change
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"auth_service/internal/auth"
+	authMiddleware "auth_service/internal/http_server/middleware/auth"
+	resp "auth_service/internal/lib/api/response"
+	sl "auth_service/internal/lib/logger"
+	"auth_service/internal/lib/verification"
+	"auth_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+// * New отправляет ссылку подтверждения на новый адрес. Текущий email
+// * остаётся рабочим, пока пользователь не перейдёт по ссылке
+func New(
+	log *slog.Logger,
+	validate *validator.Validate,
+	authService *auth.Auth,
+	msgSender verification.Publisher,
+	tokenTTL time.Duration,
+	tokenSecret string,
+	confirmURL string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.me.email.change.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(authMiddleware.UserIDKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("Failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Failed to decode request"))
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("Invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		user, token, err := authService.RequestEmailChange(ctx, userID, req.Password, req.Email, tokenTTL, tokenSecret)
+		if err != nil {
+			switch {
+			case errors.Is(err, auth.ErrInvalidCredentials):
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("invalid password"))
+			case errors.Is(err, auth.ErrSameEmail):
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("new email matches the current one"))
+			case errors.Is(err, auth.ErrEmailTaken):
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, resp.Error("email already taken"))
+			case errors.Is(err, storage.ErrUserNotFound):
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("user not found"))
+			default:
+				log.Error("failed to request email change", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("Internal error"))
+			}
+
+			return
+		}
+
+		if err := verification.SendEmailChange(ctx, msgSender, confirmURL, user, token, tokenTTL); err != nil {
+			log.Error("failed to send email change confirmation", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		log.Info("email change confirmation sent", slog.Int64("uid", userID))
+
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package confirm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"auth_service/internal/auth"
+	resp "auth_service/internal/lib/api/response"
+	sl "auth_service/internal/lib/logger"
+	"auth_service/internal/models"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Profile models.Profile `json:"profile"`
+}
+
+// * EventTrigger запускает публикацию события об изменении профиля без ожидания
+type EventTrigger interface {
+	Trigger()
+}
+
+// * New подтверждает смену email по ссылке из письма. Ссылка открывается
+// * из почтового клиента, поэтому эндпоинт не требует access токена
+func New(
+	log *slog.Logger,
+	authService *auth.Auth,
+	events EventTrigger,
+	tokenSecret string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.me.email.confirm.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			log.Warn("missing email change token")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("missing token"))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		profile, err := authService.ConfirmEmailChange(ctx, token, tokenSecret)
+		if err != nil {
+			switch {
+			case errors.Is(err, auth.ErrInvalidEmailToken):
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("invalid or expired token"))
+			case errors.Is(err, auth.ErrEmailTaken):
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, resp.Error("email already taken"))
+			default:
+				log.Error("failed to confirm email change", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("internal error"))
+			}
+
+			return
+		}
+
+		events.Trigger()
+
+		log.Info("email change confirmed", slog.Int64("uid", profile.ID))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Profile:  profile,
+		})
+	}
+}
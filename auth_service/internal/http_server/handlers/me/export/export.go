@@ -0,0 +1,77 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"auth_service/internal/auth"
+	authMiddleware "auth_service/internal/http_server/middleware/auth"
+	resp "auth_service/internal/lib/api/response"
+	sl "auth_service/internal/lib/logger"
+	"auth_service/internal/models"
+	"auth_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Account models.AccountExport `json:"account"`
+}
+
+// * New возвращает профиль, сессии и состояние 2FA текущего пользователя.
+// * main_service включает ответ в архив выгрузки персональных данных
+func New(
+	log *slog.Logger,
+	authService *auth.Auth,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.me.export.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(authMiddleware.UserIDKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		sessionID, _ := r.Context().Value(authMiddleware.SessionIDKey).(string)
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		account, err := authService.ExportAccount(ctx, userID, sessionID)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("user not found"))
+
+				return
+			}
+
+			log.Error("failed to export account", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Account:  account,
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"auth_service/internal/auth"
+	authMiddleware "auth_service/internal/http_server/middleware/auth"
+	resp "auth_service/internal/lib/api/response"
+	sl "auth_service/internal/lib/logger"
+	"auth_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+// * New меняет пароль текущего пользователя и завершает остальные его сессии
+func New(
+	log *slog.Logger,
+	validate *validator.Validate,
+	authService *auth.Auth,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.me.password.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(authMiddleware.UserIDKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		sessionID, _ := r.Context().Value(authMiddleware.SessionIDKey).(string)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("Failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Failed to decode request"))
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("Invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		err = authService.ChangePassword(ctx, userID, sessionID, req.CurrentPassword, req.NewPassword)
+		if err != nil {
+			switch {
+			case errors.Is(err, auth.ErrInvalidCredentials):
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("invalid password"))
+			case errors.Is(err, storage.ErrUserNotFound):
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("user not found"))
+			default:
+				log.Error("failed to change password", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("Internal error"))
+			}
+
+			return
+		}
+
+		log.Info("password changed", slog.Int64("uid", userID))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
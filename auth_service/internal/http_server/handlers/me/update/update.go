@@ -0,0 +1,131 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"auth_service/internal/auth"
+	authMiddleware "auth_service/internal/http_server/middleware/auth"
+	resp "auth_service/internal/lib/api/response"
+	sl "auth_service/internal/lib/logger"
+	"auth_service/internal/models"
+	"auth_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// * Request — поля PATCH /me; отсутствующее поле не меняется.
+// * Пустая строка в currency сбрасывает предпочитаемую валюту
+type Request struct {
+	Username    *string `json:"username,omitempty" validate:"omitempty,min=1,max=64"`
+	DisplayName *string `json:"display_name,omitempty" validate:"omitempty,max=128"`
+	Locale      *string `json:"locale,omitempty" validate:"omitempty,oneof=ru en"`
+	Timezone    *string `json:"timezone,omitempty" validate:"omitempty,timezone"`
+	Currency    *string `json:"currency,omitempty" validate:"omitempty,iso4217"`
+}
+
+type Response struct {
+	resp.Response
+	Profile models.Profile `json:"profile"`
+}
+
+// * EventTrigger запускает публикацию события об изменении профиля без ожидания
+type EventTrigger interface {
+	Trigger()
+}
+
+// * New меняет профиль текущего пользователя
+func New(
+	log *slog.Logger,
+	validate *validator.Validate,
+	authService *auth.Auth,
+	events EventTrigger,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.me.update.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(authMiddleware.UserIDKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("Failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Failed to decode request"))
+
+			return
+		}
+
+		if req.Currency != nil {
+			currency := strings.ToUpper(strings.TrimSpace(*req.Currency))
+			req.Currency = &currency
+		}
+
+		if err := validate.Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("Invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		profile, err := authService.UpdateProfile(ctx, userID, models.ProfileUpdate{
+			Username:    req.Username,
+			DisplayName: req.DisplayName,
+			Locale:      req.Locale,
+			Timezone:    req.Timezone,
+			Currency:    req.Currency,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, auth.ErrUsernameTaken):
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, resp.Error("username already taken"))
+			case errors.Is(err, storage.ErrUserNotFound):
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("user not found"))
+			default:
+				log.Error("failed to update profile", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("Internal error"))
+			}
+
+			return
+		}
+
+		events.Trigger()
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+			Profile:  profile,
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package forgot
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"auth_service/internal/auth"
+	resp "auth_service/internal/lib/api/response"
+	sl "auth_service/internal/lib/logger"
+	"auth_service/internal/lib/verification"
+	"auth_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+// * New отправляет письмо со ссылкой на сброс пароля. Ответ всегда 200,
+// * чтобы по нему нельзя было узнать, зарегистрирован ли email
+func New(
+	log *slog.Logger,
+	validate *validator.Validate,
+	authMiddleware *auth.Auth,
+	msgSender verification.Publisher,
+	resetTokenTTL time.Duration,
+	resetTokenSecret string,
+	resetURL string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.password.forgot.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("Failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Failed to decode request"))
+
+			return
+		}
+
+		log.Info("Request body decoded")
+
+		if err := validate.Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("Invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		user, token, err := authMiddleware.RequestPasswordReset(ctx, req.Email, resetTokenTTL, resetTokenSecret)
+		if err != nil {
+			if errors.Is(err, storage.ErrUserNotFound) {
+				log.Info("password reset requested for unknown email")
+			} else {
+				log.Error("failed to create password reset token", sl.Err(err))
+			}
+
+			ResponseOK(w, r)
+
+			return
+		}
+
+		if err := verification.SendPasswordReset(ctx, msgSender, resetURL, user, token, resetTokenTTL); err != nil {
+			log.Error("failed to send password reset email", sl.Err(err))
+		} else {
+			log.Info("password reset email sent", slog.Int64("uid", user.ID))
+		}
+
+		ResponseOK(w, r)
+	}
+}
+
+func ResponseOK(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+	})
+}
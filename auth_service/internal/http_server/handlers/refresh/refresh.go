@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"auth_service/internal/auth"
+	"auth_service/internal/lib/api/client"
 	resp "auth_service/internal/lib/api/response"
 	sl "auth_service/internal/lib/logger"
 
@@ -67,7 +68,7 @@ func New(
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
-		accessToken, newRefreshToken, err := authMiddleware.Refresh(ctx, req.RefreshToken)
+		accessToken, newRefreshToken, err := authMiddleware.Refresh(ctx, req.RefreshToken, client.Info(r))
 		if err != nil {
 			if errors.Is(err, auth.ErrInvalidCredentials) {
 				render.JSON(w, r, resp.Error("Invalid credentials"))
@@ -34,7 +34,7 @@ func New(
 	msgSender verification.Publisher,
 	verificationTokenTTL time.Duration,
 	verificationTokenSecret string,
-	address string,
+	publicURL string,
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.register.New"
@@ -84,15 +84,24 @@ func New(
 
 		log.Info("User registered", slog.Int64("id", userID))
 
+		token, err := authMiddleware.IssueVerificationToken(ctx, userID, verificationTokenTTL, verificationTokenSecret)
+		if err != nil {
+			log.Error("Failed to issue verification token", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
 		err = verification.VerifyUserEmail(
 			ctx,
 			log,
 			msgSender,
-			verificationTokenTTL,
-			verificationTokenSecret,
-			userID,
-			address,
+			token,
+			publicURL,
 			req.Email,
+			req.Username,
 		)
 		if err != nil {
 			log.Error("Failed to send verification email", sl.Err(err))
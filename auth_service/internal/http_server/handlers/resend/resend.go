@@ -0,0 +1,117 @@
+package resend
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"auth_service/internal/auth"
+	resp "auth_service/internal/lib/api/response"
+	sl "auth_service/internal/lib/logger"
+	"auth_service/internal/lib/ratelimit"
+	"auth_service/internal/lib/verification"
+	"auth_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+// * New повторно отправляет ссылку подтверждения email. Лимит считается по
+// * адресу до поиска пользователя, поэтому ответы для известных и неизвестных
+// * адресов не отличаются
+func New(
+	log *slog.Logger,
+	validate *validator.Validate,
+	authMiddleware *auth.Auth,
+	msgSender verification.Publisher,
+	limiter *ratelimit.Limiter,
+	verificationTokenTTL time.Duration,
+	verificationTokenSecret string,
+	publicURL string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.resend.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("Failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Failed to decode request"))
+
+			return
+		}
+
+		log.Info("Request body decoded")
+
+		if err := validate.Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("Invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		if !limiter.Allow(strings.ToLower(req.Email)) {
+			log.Warn("verification resend rate limited")
+
+			render.Status(r, http.StatusTooManyRequests)
+			render.JSON(w, r, resp.Error("Too many requests, try again later"))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		user, token, err := authMiddleware.ResendVerification(ctx, req.Email, verificationTokenTTL, verificationTokenSecret)
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrUserNotFound):
+				log.Info("verification resend requested for unknown email")
+			case errors.Is(err, auth.ErrAlreadyVerified):
+				log.Info("verification resend requested for verified email")
+			default:
+				log.Error("failed to issue verification token", sl.Err(err))
+			}
+
+			ResponseOK(w, r)
+
+			return
+		}
+
+		_ = verification.VerifyUserEmail(ctx, log, msgSender, token, publicURL, user.Email, user.Username)
+
+		log.Info("verification email resent", slog.Int64("uid", user.ID))
+
+		ResponseOK(w, r)
+	}
+}
+
+func ResponseOK(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+	})
+}
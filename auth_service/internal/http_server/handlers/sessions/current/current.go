@@ -0,0 +1,54 @@
+package current
+
+import (
+	"log/slog"
+	"net/http"
+
+	authMiddleware "auth_service/internal/http_server/middleware/auth"
+	resp "auth_service/internal/lib/api/response"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	UserID    int64  `json:"user_id"`
+	SessionID string `json:"session_id"`
+}
+
+// * New подтверждает, что сессия access токена не завершена. Токен завершённой
+// * сессии отклоняет middleware с 401: так main_service проверяет отзыв
+// * токенов, которые сам считает действительными по подписи
+func New(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.sessions.current.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(authMiddleware.UserIDKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		sessionID, _ := r.Context().Value(authMiddleware.SessionIDKey).(string)
+
+		ResponseOK(w, r, userID, sessionID)
+	}
+}
+
+func ResponseOK(w http.ResponseWriter, r *http.Request, userID int64, sessionID string) {
+	render.JSON(w, r, Response{
+		Response:  resp.OK(),
+		UserID:    userID,
+		SessionID: sessionID,
+	})
+}
@@ -0,0 +1,70 @@
+package list
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"auth_service/internal/auth"
+	authMiddleware "auth_service/internal/http_server/middleware/auth"
+	resp "auth_service/internal/lib/api/response"
+	sl "auth_service/internal/lib/logger"
+	"auth_service/internal/models"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Sessions []models.Session `json:"sessions"`
+}
+
+func New(
+	log *slog.Logger,
+	authService *auth.Auth,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.sessions.list.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(authMiddleware.UserIDKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		sessionID, _ := r.Context().Value(authMiddleware.SessionIDKey).(string)
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		sessions, err := authService.Sessions(ctx, userID, sessionID)
+		if err != nil {
+			log.Error("failed to list sessions", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		ResponseOK(w, r, sessions)
+	}
+}
+
+func ResponseOK(w http.ResponseWriter, r *http.Request, sessions []models.Session) {
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+		Sessions: sessions,
+	})
+}
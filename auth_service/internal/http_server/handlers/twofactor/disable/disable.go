@@ -0,0 +1,111 @@
+package disable
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auth_service/internal/auth"
+	authMiddleware "auth_service/internal/http_server/middleware/auth"
+	resp "auth_service/internal/lib/api/response"
+	sl "auth_service/internal/lib/logger"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+// * New выключает 2FA по действующему коду или коду восстановления
+func New(
+	log *slog.Logger,
+	validate *validator.Validate,
+	authService *auth.Auth,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.twofactor.disable.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(authMiddleware.UserIDKey).(int64)
+		if !ok {
+			log.Error("user_id not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("Failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Failed to decode request"))
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("Invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		err = authService.DisableTwoFactor(ctx, userID, req.Code)
+		if err != nil {
+			var lockoutErr *auth.LockoutError
+
+			switch {
+			case errors.Is(err, auth.ErrTwoFactorNotEnabled):
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, resp.Error("two-factor authentication is not enabled"))
+			case errors.As(err, &lockoutErr):
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockoutErr.RetryAfter.Seconds()))))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Error("Too many failed attempts, try again later"))
+			case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("invalid code"))
+			default:
+				log.Error("failed to disable two-factor", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("Internal error"))
+			}
+
+			return
+		}
+
+		log.Info("two-factor disabled", slog.Int64("uid", userID))
+
+		render.JSON(w, r, Response{
+			Response: resp.OK(),
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package login
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"auth_service/internal/auth"
+	"auth_service/internal/lib/api/client"
+	resp "auth_service/internal/lib/api/response"
+	sl "auth_service/internal/lib/logger"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type Response struct {
+	resp.Response
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// * New — второй шаг входа: challenge токен из /login и код из приложения
+// * (или код восстановления) обмениваются на access и refresh токены
+func New(
+	log *slog.Logger,
+	validate *validator.Validate,
+	authService *auth.Auth,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.twofactor.login.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("Failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Failed to decode request"))
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("Invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		result, err := authService.LoginTwoFactor(ctx, req.ChallengeToken, req.Code, client.Info(r))
+		if err != nil {
+			var lockoutErr *auth.LockoutError
+
+			switch {
+			case errors.Is(err, auth.ErrInvalidChallenge):
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("login challenge expired, sign in again"))
+			case errors.As(err, &lockoutErr):
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockoutErr.RetryAfter.Seconds()))))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Error("Too many failed attempts, try again later"))
+			case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("invalid code"))
+			default:
+				log.Error("failed to complete two-factor login", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("Internal error"))
+			}
+
+			return
+		}
+
+		log.Info("User logged in successfully")
+
+		render.JSON(w, r, Response{
+			Response:     resp.OK(),
+			AccessToken:  result.AccessToken,
+			RefreshToken: result.RefreshToken,
+		})
+	}
+}
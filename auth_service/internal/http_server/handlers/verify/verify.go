@@ -2,6 +2,7 @@ package verify
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
@@ -9,7 +10,6 @@ import (
 	"auth_service/internal/auth"
 	resp "auth_service/internal/lib/api/response"
 	sl "auth_service/internal/lib/logger"
-	"auth_service/internal/lib/verification"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
@@ -42,29 +42,31 @@ func New(
 			return
 		}
 
-		userID, err := verification.ParseVerificationToken(token, tokenSecret)
-		if err != nil {
-			log.Warn("invalid verification token", sl.Err(err))
-
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, resp.Error("invalid or expired token"))
-
-			return
-		}
-
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
 		if err := authMiddleware.VerifyUser(ctx, token, tokenSecret); err != nil {
-			log.Error("failed to mark user as verified", sl.Err(err))
-
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, resp.Error("internal error"))
+			switch {
+			case errors.Is(err, auth.ErrVerificationTokenExpired):
+				render.Status(r, http.StatusGone)
+				render.JSON(w, r, resp.Error("token expired, request a new verification link"))
+			case errors.Is(err, auth.ErrVerificationTokenUsed):
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, resp.Error("token already used"))
+			case errors.Is(err, auth.ErrVerificationTokenInvalid):
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("invalid token"))
+			default:
+				log.Error("failed to mark user as verified", sl.Err(err))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("internal error"))
+			}
 
 			return
 		}
 
-		log.Info("email verified successfully", slog.Int64("uid", userID))
+		log.Info("email verified successfully")
 
 		ResponseOK(w, r)
 	}
@@ -0,0 +1,95 @@
+package authMiddleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	resp "auth_service/internal/lib/api/response"
+	"auth_service/internal/lib/jwt"
+
+	"github.com/go-chi/render"
+)
+
+type contextKey string
+
+const (
+	UserIDKey    contextKey = "user_id"
+	SessionIDKey contextKey = "session_id"
+)
+
+// * SessionChecker сообщает, не завершена ли сессия
+type SessionChecker interface {
+	SessionActive(ctx context.Context, userID int64, sessionID string) (bool, error)
+}
+
+// * New проверяет access токен, выпущенный этим сервисом, и кладёт в контекст
+// * идентификаторы пользователя и сессии. Токен завершённой сессии (выход,
+// * отзыв, смена пароля) отклоняется, не дожидаясь истечения
+func New(log *slog.Logger, keys *jwt.KeySet, sessions SessionChecker) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.auth.New"
+
+			authHeader := r.Header.Get("Authorization")
+
+			parts := strings.SplitN(authHeader, " ", 2)
+			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" || strings.TrimSpace(parts[1]) == "" {
+				log.Warn("Missing authorization header",
+					slog.String("op", op),
+					slog.String("path", r.URL.Path),
+				)
+
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("Missing authorization"))
+
+				return
+			}
+
+			claims, err := jwt.ParseToken(strings.TrimSpace(parts[1]), keys)
+			if err != nil {
+				log.Warn("Invalid token",
+					slog.String("op", op),
+					slog.String("path", r.URL.Path),
+					slog.String("error", err.Error()),
+				)
+
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("Invalid token"))
+
+				return
+			}
+
+			active, err := sessions.SessionActive(r.Context(), claims.UserID, claims.SessionID)
+			if err != nil {
+				log.Error("Failed to check session",
+					slog.String("op", op),
+					slog.String("error", err.Error()),
+				)
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("Internal error"))
+
+				return
+			}
+
+			if !active {
+				log.Warn("Session revoked",
+					slog.String("op", op),
+					slog.Int64("uid", claims.UserID),
+				)
+
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("Session revoked"))
+
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
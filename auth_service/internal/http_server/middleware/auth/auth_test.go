@@ -0,0 +1,74 @@
+package authMiddleware_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	authMiddleware "auth_service/internal/http_server/middleware/auth"
+	"auth_service/internal/lib/jwt"
+	"auth_service/internal/models"
+)
+
+// * fakeSessions хранит идентификаторы незавершённых сессий
+type fakeSessions struct {
+	active map[string]bool
+	err    error
+}
+
+func (s fakeSessions) SessionActive(_ context.Context, _ int64, sessionID string) (bool, error) {
+	return s.active[sessionID], s.err
+}
+
+func TestRevokedSessionRejected(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	keys := jwt.NewKeySet(&jwt.SigningKey{ID: "test", Private: private})
+
+	token, err := jwt.NewToken(models.User{ID: 1}, models.App{ID: 1}, "session", keys, time.Hour)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		sessions fakeSessions
+		want     int
+	}{
+		{name: "active session", sessions: fakeSessions{active: map[string]bool{"session": true}}, want: http.StatusOK},
+		{name: "revoked session", sessions: fakeSessions{}, want: http.StatusUnauthorized},
+		{name: "storage failure", sessions: fakeSessions{err: errors.New("db down")}, want: http.StatusInternalServerError},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sid, _ := r.Context().Value(authMiddleware.SessionIDKey).(string); sid != "session" {
+			t.Errorf("session id in context = %q, want session", sid)
+		}
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := authMiddleware.New(log, keys, tt.sessions)(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"net"
+	"net/http"
+
+	"auth_service/internal/models"
+)
+
+// * maxUserAgentLen обрезает чрезмерно длинные User-Agent перед сохранением
+const maxUserAgentLen = 512
+
+// * Info извлекает User-Agent и IP клиента из запроса
+func Info(r *http.Request) models.ClientInfo {
+	ua := r.UserAgent()
+	if len(ua) > maxUserAgentLen {
+		ua = ua[:maxUserAgentLen]
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return models.ClientInfo{
+		UserAgent: ua,
+		IP:        ip,
+	}
+}
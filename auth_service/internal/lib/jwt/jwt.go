@@ -3,23 +3,32 @@ package jwt
 import (
 	"auth_service/internal/models"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func NewToken(user models.User, app models.App, duration time.Duration) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+// * NewToken подписывает access токен активным ключом набора; kid в заголовке
+// * позволяет проверяющей стороне выбрать публичный ключ из JWKS
+func NewToken(user models.User, app models.App, sessionID string, keys *KeySet, duration time.Duration) (string, error) {
+	key := keys.Active()
+
+	token := jwt.New(jwt.SigningMethodEdDSA)
+	token.Header["kid"] = key.ID
 
 	claims := token.Claims.(jwt.MapClaims)
 	claims["uid"] = user.ID
 	claims["username"] = user.Username
 	claims["email"] = user.Email
+	claims["role"] = string(user.Role)
 	claims["exp"] = time.Now().Add(duration).Unix()
 	claims["app_id"] = app.ID
+	claims["sid"] = sessionID
 
-	tokenString, err := token.SignedString([]byte(app.Secret))
+	tokenString, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", err
 	}
@@ -27,6 +36,48 @@ func NewToken(user models.User, app models.App, duration time.Duration) (string,
 	return tokenString, nil
 }
 
+// * Claims — данные пользователя из проверенного access токена
+type Claims struct {
+	UserID    int64
+	SessionID string
+}
+
+// * ParseToken проверяет подпись access токена ключом из набора по kid
+func ParseToken(tokenString string, keys *KeySet) (Claims, error) {
+	const op = "jwt.ParseToken"
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, ok := keys.PublicKey(kid)
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}))
+	if err != nil {
+		return Claims{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return Claims{}, fmt.Errorf("%s: unexpected claims", op)
+	}
+
+	uid, ok := claims["uid"].(float64)
+	if !ok {
+		return Claims{}, fmt.Errorf("%s: missing uid claim", op)
+	}
+
+	sid, _ := claims["sid"].(string)
+
+	return Claims{
+		UserID:    int64(uid),
+		SessionID: sid,
+	}, nil
+}
+
 func NewRefreshToken() (string, error) {
 	const size = 32
 	b := make([]byte, size)
@@ -38,3 +89,11 @@ func NewRefreshToken() (string, error) {
 
 	return base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(b), nil
 }
+
+// * HashRefreshToken возвращает SHA-256 дайджест refresh токена. Токен содержит
+// * 256 бит случайности, поэтому медленный хэш не нужен, а по дайджесту
+// * строка находится через уникальный индекс
+func HashRefreshToken(rawToken string) []byte {
+	sum := sha256.Sum256([]byte(rawToken))
+	return sum[:]
+}
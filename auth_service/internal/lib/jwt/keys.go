@@ -0,0 +1,185 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("invalid signing key")
+
+// * SigningKey — Ed25519 ключ подписи access токенов с идентификатором kid
+type SigningKey struct {
+	ID      string
+	Private ed25519.PrivateKey
+}
+
+// * KeySet хранит все опубликованные ключи и активный ключ подписи.
+// * Ротация: положить новый ключ в каталог (или указать новый active_key_id —
+// * он будет сгенерирован), перезапустить сервис и удалить старый файл не раньше,
+// * чем истечёт access_token_ttl. До этого токены старого ключа продолжают проверяться
+type KeySet struct {
+	active *SigningKey
+	keys   []*SigningKey
+}
+
+// * LoadKeySet читает ключи <kid>.pem (PKCS#8) из dir. Если файла активного
+// * ключа нет, он генерируется и сохраняется
+func LoadKeySet(dir, activeKeyID string) (*KeySet, error) {
+	const op = "jwt.LoadKeySet"
+
+	if activeKeyID == "" {
+		return nil, fmt.Errorf("%s: active key id is empty", op)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	activePath := filepath.Join(dir, activeKeyID+".pem")
+	if _, err := os.Stat(activePath); errors.Is(err, os.ErrNotExist) {
+		if err := generateKey(activePath); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	sort.Strings(paths)
+
+	var (
+		active   *SigningKey
+		previous []*SigningKey
+	)
+
+	for _, path := range paths {
+		key, err := readKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, path, err)
+		}
+
+		if key.ID == activeKeyID {
+			active = key
+			continue
+		}
+
+		previous = append(previous, key)
+	}
+
+	if active == nil {
+		return nil, fmt.Errorf("%s: active key %q not found", op, activeKeyID)
+	}
+
+	return NewKeySet(active, previous...), nil
+}
+
+// * NewKeySet собирает набор из готовых ключей: active подписывает новые токены,
+// * previous только проверяют ранее выданные
+func NewKeySet(active *SigningKey, previous ...*SigningKey) *KeySet {
+	return &KeySet{
+		active: active,
+		keys:   append([]*SigningKey{active}, previous...),
+	}
+}
+
+func (ks *KeySet) Active() *SigningKey {
+	return ks.active
+}
+
+// * PublicKey возвращает публичный ключ по kid
+func (ks *KeySet) PublicKey(kid string) (ed25519.PublicKey, bool) {
+	for _, key := range ks.keys {
+		if key.ID == kid {
+			return key.Private.Public().(ed25519.PublicKey), true
+		}
+	}
+
+	return nil, false
+}
+
+// * JWK — публичный ключ в формате RFC 8037 (OKP / Ed25519)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// * JWKS возвращает публичные части всех ключей набора
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+
+	for _, key := range ks.keys {
+		pub := key.Private.Public().(ed25519.PublicKey)
+
+		set.Keys = append(set.Keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(pub),
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+		})
+	}
+
+	return set
+}
+
+func readKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidKey
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	private, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	return &SigningKey{
+		ID:      strings.TrimSuffix(filepath.Base(path), ".pem"),
+		Private: private,
+	}, nil
+}
+
+func generateKey(path string) error {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return err
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	return os.WriteFile(path, data, 0o600)
+}
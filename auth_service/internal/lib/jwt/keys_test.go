@@ -0,0 +1,116 @@
+package jwt_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"auth_service/internal/lib/jwt"
+	"auth_service/internal/models"
+)
+
+func newSigningKey(t *testing.T, id string) *jwt.SigningKey {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	return &jwt.SigningKey{ID: id, Private: private}
+}
+
+func newToken(t *testing.T, keys *jwt.KeySet) string {
+	t.Helper()
+
+	token, err := jwt.NewToken(models.User{ID: 42}, models.App{ID: 1}, "session", keys, time.Hour)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	return token
+}
+
+// * После ротации токены старого ключа продолжают проверяться, пока он в наборе,
+// * а новые подписываются новым ключом
+func TestKeySetRotation(t *testing.T) {
+	oldKey := newSigningKey(t, "old")
+	newKey := newSigningKey(t, "new")
+
+	before := jwt.NewKeySet(oldKey)
+	oldToken := newToken(t, before)
+
+	after := jwt.NewKeySet(newKey, oldKey)
+	rotatedToken := newToken(t, after)
+
+	tests := []struct {
+		name  string
+		token string
+		keys  *jwt.KeySet
+	}{
+		{name: "old token before rotation", token: oldToken, keys: before},
+		{name: "old token after rotation", token: oldToken, keys: after},
+		{name: "new token after rotation", token: rotatedToken, keys: after},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := jwt.ParseToken(tt.token, tt.keys)
+			if err != nil {
+				t.Fatalf("ParseToken: %v", err)
+			}
+
+			if claims.UserID != 42 || claims.SessionID != "session" {
+				t.Errorf("claims = %+v, want uid 42, sid session", claims)
+			}
+		})
+	}
+
+	if got := after.Active().ID; got != "new" {
+		t.Errorf("active key = %q, want new", got)
+	}
+
+	// * после удаления старого ключа его токены перестают проверяться
+	if _, err := jwt.ParseToken(oldToken, jwt.NewKeySet(newKey)); !errors.Is(err, jwt.ErrInvalidKey) {
+		t.Errorf("ParseToken with retired key: %v, want %v", err, jwt.ErrInvalidKey)
+	}
+}
+
+func TestLoadKeySetRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	before, err := jwt.LoadKeySet(dir, "2026-01")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	oldToken := newToken(t, before)
+
+	// * новый active_key_id генерирует ключ рядом со старым
+	after, err := jwt.LoadKeySet(dir, "2026-02")
+	if err != nil {
+		t.Fatalf("LoadKeySet after rotation: %v", err)
+	}
+
+	if got := after.Active().ID; got != "2026-02" {
+		t.Errorf("active key = %q, want 2026-02", got)
+	}
+
+	if _, err := jwt.ParseToken(oldToken, after); err != nil {
+		t.Errorf("ParseToken old token after rotation: %v", err)
+	}
+
+	if _, err := jwt.ParseToken(newToken(t, after), after); err != nil {
+		t.Errorf("ParseToken new token: %v", err)
+	}
+
+	kids := make(map[string]bool)
+	for _, k := range after.JWKS().Keys {
+		kids[k.KeyID] = true
+	}
+
+	if !kids["2026-01"] || !kids["2026-02"] || len(kids) != 2 {
+		t.Errorf("JWKS kids = %v, want 2026-01 and 2026-02", kids)
+	}
+}
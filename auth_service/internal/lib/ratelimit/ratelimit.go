@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// * sweepThreshold — число ключей, после которого устаревшие записи вычищаются
+const sweepThreshold = 10000
+
+// * Limiter — ограничитель по ключу со скользящим окном: не более limit
+// * событий за window. Состояние хранится в памяти процесса
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu   sync.Mutex
+	hits map[string][]time.Time
+}
+
+func New(limit int, window time.Duration) *Limiter {
+	return NewWithClock(limit, window, time.Now)
+}
+
+func NewWithClock(limit int, window time.Duration, now func() time.Time) *Limiter {
+	return &Limiter{
+		limit:  limit,
+		window: window,
+		now:    now,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// * Allow учитывает событие для key и сообщает, укладывается ли оно в лимит
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	if len(l.hits) > sweepThreshold {
+		l.sweep(now)
+	}
+
+	hits := recent(l.hits[key], now.Add(-l.window))
+	if len(hits) >= l.limit {
+		l.hits[key] = hits
+		return false
+	}
+
+	l.hits[key] = append(hits, now)
+
+	return true
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	since := now.Add(-l.window)
+
+	for key, hits := range l.hits {
+		if hits = recent(hits, since); len(hits) == 0 {
+			delete(l.hits, key)
+		} else {
+			l.hits[key] = hits
+		}
+	}
+}
+
+func recent(hits []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && !hits[i].After(since) {
+		i++
+	}
+
+	return hits[i:]
+}
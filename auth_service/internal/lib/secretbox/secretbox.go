@@ -0,0 +1,67 @@
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// * Box шифрует небольшие секреты AES-256-GCM; nonce хранится в начале шифртекста
+type Box struct {
+	aead cipher.AEAD
+}
+
+// * New принимает 32-байтный ключ в base64
+func New(encodedKey string) (*Box, error) {
+	const op = "secretbox.New"
+
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: decode key: %w", op, err)
+	}
+
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%s: key must be 32 bytes, got %d", op, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+func (b *Box) Seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return b.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (b *Box) Open(ciphertext []byte) ([]byte, error) {
+	size := b.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := b.aead.Open(nil, ciphertext[:size], ciphertext[size:], nil)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
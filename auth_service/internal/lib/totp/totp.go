@@ -0,0 +1,96 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// * Параметры RFC 6238, которые понимают все распространённые приложения-аутентификаторы
+const (
+	Digits     = 6
+	Period     = 30 * time.Second
+	secretSize = 20
+	// * skew — сколько соседних шагов принимается из-за расхождения часов
+	skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// * GenerateSecret возвращает случайный секрет в base32
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+// * URI формирует otpauth:// ссылку для QR-кода
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(Digits))
+	q.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// * Validate проверяет код для момента now и возвращает номер совпавшего шага.
+// * Вызывающий должен запоминать шаг и отклонять коды с шагом не больше
+// * последнего использованного, иначе код можно предъявить повторно
+func Validate(secret, code string, now time.Time) (step int64, ok bool) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != Digits {
+		return 0, false
+	}
+
+	current := now.Unix() / int64(Period.Seconds())
+
+	for offset := int64(-skew); offset <= skew; offset++ {
+		s := current + offset
+
+		if subtle.ConstantTimeCompare([]byte(generate(key, s)), []byte(code)) == 1 {
+			return s, true
+		}
+	}
+
+	return 0, false
+}
+
+// * Code возвращает код для момента now
+func Code(secret string, now time.Time) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	return generate(key, now.Unix()/int64(Period.Seconds())), nil
+}
+
+func generate(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, value%1000000)
+}
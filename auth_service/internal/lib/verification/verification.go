@@ -3,49 +3,151 @@ package verification
 import (
 	"auth_service/internal/models"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	PurposeEmailVerification = "email_verification"
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailChange       = "email_change"
+)
+
 type Publisher interface {
 	SendMessage(ctx context.Context, msg models.Message) error
 }
 
+var (
+	ErrTokenExpired = errors.New("token expired")
+	ErrTokenInvalid = errors.New("invalid token")
+)
+
+// * VerifyUserEmail отправляет письмо со ссылкой подтверждения email
 func VerifyUserEmail(
 	ctx context.Context,
 	log *slog.Logger,
 	pub Publisher,
-	tokenTTL time.Duration,
-	tokenSecret string,
-	userID int64,
-	url, email string,
+	token string,
+	url, email, username string,
 ) error {
-	token, err := generateVerificationToken(userID, tokenTTL, tokenSecret)
+	verifyLink := fmt.Sprintf("%s/verify?token=%s", url, token)
+
+	msg := models.Message{
+		Type:  models.MessageVerification,
+		Email: email,
+		Data: models.VerificationData{
+			Username: username,
+			Link:     verifyLink,
+		},
+	}
+
+	if err := pub.SendMessage(ctx, msg); err != nil {
+		log.Error("failed to send verification link", slog.Any("err", err))
+	}
+
+	return nil
+}
+
+// * GenerateOneTimeToken выпускает подписанный токен с назначением purpose.
+// * Одноразовость обеспечивает jti: вызывающий сохраняет его и гасит при использовании
+func GenerateOneTimeToken(userID int64, purpose string, tokenTTL time.Duration, secret string) (token, jti string, err error) {
+	const op = "verification.GenerateOneTimeToken"
+
+	jti, err = newJTI()
 	if err != nil {
-		log.Error("failed to generate token", slog.Any("err", err))
+		return "", "", fmt.Errorf("%s: %w", op, err)
+	}
 
-		return err
+	token, err = generateToken(userID, purpose, jti, tokenTTL, secret)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	verifyLink := fmt.Sprintf("%s/verify?token=%s", url, token)
+	return token, jti, nil
+}
+
+// * ParseOneTimeToken проверяет подпись, назначение и срок действия токена.
+// * Возвращает ErrTokenExpired для просроченного токена и ErrTokenInvalid для остальных ошибок
+func ParseOneTimeToken(tokenStr, secret, purpose string) (userID int64, jti string, err error) {
+	userID, jti, err = parseToken(tokenStr, secret, purpose)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if jti == "" {
+		return 0, "", fmt.Errorf("verification.ParseOneTimeToken: missing jti claim: %w", ErrTokenInvalid)
+	}
+
+	return userID, jti, nil
+}
+
+// * SendPasswordReset отправляет письмо со ссылкой на сброс пароля
+func SendPasswordReset(
+	ctx context.Context,
+	pub Publisher,
+	resetURL string,
+	user models.User,
+	token string,
+	tokenTTL time.Duration,
+) error {
+	const op = "verification.SendPasswordReset"
 
 	msg := models.Message{
-		Email: email,
-		Link:  verifyLink,
+		Type:   models.MessagePasswordReset,
+		Email:  user.Email,
+		Locale: user.Locale,
+		Data: models.PasswordResetData{
+			Username:  user.Username,
+			Link:      fmt.Sprintf("%s?token=%s", resetURL, url.QueryEscape(token)),
+			ExpiresIn: int(tokenTTL.Minutes()),
+		},
 	}
 
 	if err := pub.SendMessage(ctx, msg); err != nil {
-		log.Error("failed to send verification link", slog.Any("err", err))
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func ParseVerificationToken(tokenStr, secret string) (int64, error) {
-	const op = "verification.ParseVerificationToken"
+// * SendEmailChange отправляет на новый адрес ссылку, подтверждающую смену email
+func SendEmailChange(
+	ctx context.Context,
+	pub Publisher,
+	confirmURL string,
+	user models.User,
+	token string,
+	tokenTTL time.Duration,
+) error {
+	const op = "verification.SendEmailChange"
+
+	msg := models.Message{
+		Type:   models.MessageEmailChange,
+		Email:  user.PendingEmail,
+		Locale: user.Locale,
+		Data: models.EmailChangeData{
+			Username:  user.Username,
+			Link:      fmt.Sprintf("%s?token=%s", confirmURL, url.QueryEscape(token)),
+			ExpiresIn: int(tokenTTL.Minutes()),
+		},
+	}
+
+	if err := pub.SendMessage(ctx, msg); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func parseToken(tokenStr, secret, purpose string) (int64, string, error) {
+	const op = "verification.parseToken"
 
 	claims := jwt.MapClaims{}
 
@@ -56,41 +158,58 @@ func ParseVerificationToken(tokenStr, secret string) (int64, error) {
 		return []byte(secret), nil
 	})
 	if err != nil {
-		return 0, fmt.Errorf("%s: failed to parse token: %w", op, err)
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return 0, "", fmt.Errorf("%s: %w", op, ErrTokenExpired)
+		}
+		return 0, "", fmt.Errorf("%s: failed to parse token: %v: %w", op, err, ErrTokenInvalid)
 	}
 
 	if !parsedToken.Valid {
-		return 0, fmt.Errorf("%s: invalid token", op)
+		return 0, "", fmt.Errorf("%s: %w", op, ErrTokenInvalid)
 	}
 
-	if purpose, ok := claims["purpose"].(string); !ok || purpose != "email_verification" {
-		return 0, fmt.Errorf("%s: invalid token purpose", op)
+	if p, ok := claims["purpose"].(string); !ok || p != purpose {
+		return 0, "", fmt.Errorf("%s: invalid token purpose: %w", op, ErrTokenInvalid)
 	}
 
 	if expFloat, ok := claims["exp"].(float64); ok {
 		if time.Now().Unix() > int64(expFloat) {
-			return 0, fmt.Errorf("%s: token expired", op)
+			return 0, "", fmt.Errorf("%s: %w", op, ErrTokenExpired)
 		}
 	} else {
-		return 0, fmt.Errorf("%s: missing exp claim", op)
+		return 0, "", fmt.Errorf("%s: missing exp claim: %w", op, ErrTokenInvalid)
 	}
 
 	subFloat, ok := claims["sub"].(float64)
 	if !ok {
-		return 0, fmt.Errorf("%s: missing sub claim", op)
+		return 0, "", fmt.Errorf("%s: missing sub claim: %w", op, ErrTokenInvalid)
 	}
 
-	return int64(subFloat), nil
+	jti, _ := claims["jti"].(string)
+
+	return int64(subFloat), jti, nil
 }
 
-func generateVerificationToken(userID int64, tokenTTL time.Duration, secret string) (string, error) {
+func generateToken(userID int64, purpose, jti string, tokenTTL time.Duration, secret string) (string, error) {
 	claims := jwt.MapClaims{
 		"sub":     userID,
-		"purpose": "email_verification",
+		"purpose": purpose,
 		"exp":     time.Now().Add(tokenTTL).Unix(),
 	}
 
+	claims["jti"] = jti
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	return token.SignedString([]byte(secret))
 }
+
+func newJTI() (string, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
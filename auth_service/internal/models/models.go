@@ -2,12 +2,66 @@ package models
 
 import "time"
 
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleAdmin Role = "admin"
+)
+
 type User struct {
-	ID         int64
-	Email      string
-	Username   string
-	PassHash   []byte
-	IsVerified bool
+	ID           int64
+	Email        string
+	PendingEmail string // * новый адрес, ожидающий подтверждения
+	Username     string
+	DisplayName  string
+	Locale       string
+	Timezone     string
+	Currency     string
+	PassHash     []byte
+	IsVerified   bool
+	Role         Role
+	CreatedAt    time.Time
+}
+
+// * Profile — данные аккаунта, которые видит пользователь
+type Profile struct {
+	ID           int64     `json:"id"`
+	Email        string    `json:"email"`
+	PendingEmail string    `json:"pending_email,omitempty"`
+	Username     string    `json:"username"`
+	DisplayName  string    `json:"display_name"`
+	Locale       string    `json:"locale"`
+	Timezone     string    `json:"timezone"`
+	Currency     string    `json:"currency"`
+	IsVerified   bool      `json:"is_verified"`
+	Role         Role      `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (u User) Profile() Profile {
+	return Profile{
+		ID:           u.ID,
+		Email:        u.Email,
+		PendingEmail: u.PendingEmail,
+		Username:     u.Username,
+		DisplayName:  u.DisplayName,
+		Locale:       u.Locale,
+		Timezone:     u.Timezone,
+		Currency:     u.Currency,
+		IsVerified:   u.IsVerified,
+		Role:         u.Role,
+		CreatedAt:    u.CreatedAt,
+	}
+}
+
+// * ProfileUpdate — изменяемые поля профиля; nil означает «не менять»
+type ProfileUpdate struct {
+	Username    *string
+	DisplayName *string
+	Locale      *string
+	Timezone    *string
+	Currency    *string
 }
 
 type App struct {
@@ -20,10 +74,109 @@ type RefreshToken struct {
 	TokenHash []byte
 	UserID    int64
 	AppID     int32
+	FamilyID  string
 	ExpiresAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+}
+
+// * TwoFactor — настройки TOTP пользователя; Secret зашифрован
+type TwoFactor struct {
+	UserID       int64
+	Secret       []byte
+	ConfirmedAt  *time.Time
+	LastUsedStep int64
+}
+
+// * LoginChallenge — вход, ожидающий второго фактора
+type LoginChallenge struct {
+	ID        int64
+	UserID    int64
+	AppID     int32
+	Attempts  int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// * ClientInfo — данные устройства, с которого открыта сессия
+type ClientInfo struct {
+	UserAgent string
+	IP        string
 }
 
+// * Session — активное семейство refresh токенов пользователя
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// * Типы событий безопасности в таблице security_events
+const (
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse"
+)
+
+const (
+	MessageVerification  = "verification"
+	MessagePasswordReset = "password_reset"
+	MessageEmailChange   = "email_change"
+)
+
+// * Message — письмо для email_sender: тип шаблона, адресат, язык и данные для него
 type Message struct {
-	Email string `json:"to"`
-	Link  string `json:"link"`
+	Type   string `json:"type"`
+	Email  string `json:"to"`
+	Locale string `json:"locale,omitempty"`
+	Data   any    `json:"data"`
+}
+
+type VerificationData struct {
+	Username string `json:"username"`
+	Link     string `json:"link"`
+}
+
+type PasswordResetData struct {
+	Username  string `json:"username"`
+	Link      string `json:"link"`
+	ExpiresIn int    `json:"expires_in_minutes"`
+}
+
+type EmailChangeData struct {
+	Username  string `json:"username"`
+	Link      string `json:"link"`
+	ExpiresIn int    `json:"expires_in_minutes"`
+}
+
+const (
+	EventUserDeleted = "user.deleted"
+	EventUserUpdated = "user.updated"
+)
+
+// * Event — событие об изменении аккаунта для остальных сервисов
+type Event struct {
+	Type       string        `json:"type"`
+	UserID     int64         `json:"user_id"`
+	OccurredAt time.Time     `json:"occurred_at"`
+	Profile    *EventProfile `json:"profile,omitempty"` // * только для user.updated
+}
+
+// * EventProfile — настройки, нужные остальным сервисам для уведомлений
+type EventProfile struct {
+	Email       string `json:"email"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Locale      string `json:"locale"`
+	Timezone    string `json:"timezone"`
+	Currency    string `json:"currency"`
+}
+
+// * AccountExport — данные аккаунта для выгрузки персональных данных
+type AccountExport struct {
+	Profile          Profile   `json:"profile"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	Sessions         []Session `json:"sessions"`
 }
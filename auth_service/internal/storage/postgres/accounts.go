@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"auth_service/internal/models"
+	"auth_service/internal/storage"
+	"messaging/envelope"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// * DeleteUser в одной транзакции удаляет пользователя (сессии, токены и 2FA
+// * удаляются каскадом), его счётчик неудачных входов и записывает
+// * событие user.deleted в outbox
+func (r *PostgresRepo) DeleteUser(ctx context.Context, userID int64, occurredAt time.Time) error {
+	const op = "storage.postgres.DeleteUser"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var email string
+
+	err = tx.QueryRow(ctx, `DELETE FROM users WHERE id = $1 RETURNING email`, userID).Scan(&email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrUserNotFound
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	const attemptsQuery = `DELETE FROM login_attempts WHERE key = 'account:' || lower(trim($1))`
+
+	if _, err := tx.Exec(ctx, attemptsQuery, email); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	event := models.Event{
+		Type:       models.EventUserDeleted,
+		UserID:     userID,
+		OccurredAt: occurredAt,
+	}
+
+	if err := insertUserEvent(ctx, tx, event); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * insertUserEvent кладёт конверт события в outbox в транзакции изменения.
+// * Конверт кодируется здесь, поэтому его id не меняется при повторной публикации
+func insertUserEvent(ctx context.Context, tx pgx.Tx, event models.Event) error {
+	env, err := envelope.New(event.Type, event)
+	if err != nil {
+		return err
+	}
+
+	env.OccurredAt = event.OccurredAt
+	env.CorrelationID = envelope.CorrelationID(ctx)
+
+	payload, err := env.Encode()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO outbox (payload, created_at) VALUES ($1, $2)`, payload, event.OccurredAt)
+
+	return err
+}
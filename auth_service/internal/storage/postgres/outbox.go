@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"messaging/outbox"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// * ClaimOutbox выбирает неопубликованные сообщения по порядку записи и помечает
+// * их взятыми (claimed_at). Сообщение без подтверждения дольше claimTimeout
+// * снова доступно: relay мог упасть между публикацией и отметкой. Отложенные
+// * после неудачи (available_at) и неудавшиеся (failed_at) сообщения пропускаются
+func (r *PostgresRepo) ClaimOutbox(ctx context.Context, claimTimeout time.Duration, limit int) ([]outbox.Message, error) {
+	const op = "storage.postgres.ClaimOutbox"
+
+	const query = `
+		UPDATE outbox o
+		SET claimed_at = now()
+		FROM (
+			SELECT id
+			FROM outbox
+			WHERE published_at IS NULL
+				AND failed_at IS NULL
+				AND available_at <= now()
+				AND (claimed_at IS NULL OR claimed_at < now() - make_interval(secs => $1))
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) pending
+		WHERE o.id = pending.id
+		RETURNING o.id, o.payload, o.attempts
+	`
+
+	rows, err := r.pool.Query(ctx, query, claimTimeout.Seconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: query: %w", op, err)
+	}
+
+	messages, err := pgx.CollectRows(rows, pgx.RowToStructByPos[outbox.Message])
+	if err != nil {
+		return nil, fmt.Errorf("%s: collect: %w", op, err)
+	}
+
+	return messages, nil
+}
+
+// * MarkOutboxPublished отмечает сообщения, подтверждённые брокером
+func (r *PostgresRepo) MarkOutboxPublished(ctx context.Context, ids []int64) error {
+	const op = "storage.postgres.MarkOutboxPublished"
+
+	const query = `
+		UPDATE outbox
+		SET published_at = now()
+		WHERE id = ANY($1)
+	`
+
+	if _, err := r.pool.Exec(ctx, query, ids); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * ReleaseOutbox снимает отметку с сообщений, которые не удалось опубликовать,
+// * чтобы следующий проход не ждал claimTimeout
+func (r *PostgresRepo) ReleaseOutbox(ctx context.Context, ids []int64) error {
+	const op = "storage.postgres.ReleaseOutbox"
+
+	const query = `
+		UPDATE outbox
+		SET claimed_at = NULL
+		WHERE id = ANY($1) AND published_at IS NULL
+	`
+
+	if _, err := r.pool.Exec(ctx, query, ids); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * RetryOutbox освобождает неопубликованное сообщение, откладывая его на delay,
+// * и сохраняет число попыток, посчитанное relay
+func (r *PostgresRepo) RetryOutbox(ctx context.Context, id int64, attempts int, delay time.Duration, reason string) error {
+	const op = "storage.postgres.RetryOutbox"
+
+	const query = `
+		UPDATE outbox
+		SET attempts = $2,
+			available_at = now() + make_interval(secs => $3),
+			last_error = $4,
+			claimed_at = NULL
+		WHERE id = $1 AND published_at IS NULL
+	`
+
+	if _, err := r.pool.Exec(ctx, query, id, attempts, delay.Seconds(), reason); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * FailOutbox помечает сообщение неудавшимся: оно больше не публикуется
+// * и не удаляется DeletePublishedOutbox, чтобы его можно было разобрать
+func (r *PostgresRepo) FailOutbox(ctx context.Context, id int64, reason string) error {
+	const op = "storage.postgres.FailOutbox"
+
+	const query = `
+		UPDATE outbox
+		SET attempts = attempts + 1,
+			failed_at = now(),
+			last_error = $2,
+			claimed_at = NULL
+		WHERE id = $1 AND published_at IS NULL
+	`
+
+	if _, err := r.pool.Exec(ctx, query, id, reason); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * DeletePublishedOutbox удаляет сообщения, опубликованные раньше before
+func (r *PostgresRepo) DeletePublishedOutbox(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.postgres.DeletePublishedOutbox"
+
+	const query = `
+		DELETE FROM outbox
+		WHERE published_at IS NOT NULL AND published_at < $1
+	`
+
+	cmd, err := r.pool.Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cmd.RowsAffected(), nil
+}
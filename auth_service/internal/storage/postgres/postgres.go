@@ -13,7 +13,6 @@ import (
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type PostgresRepo struct {
@@ -71,148 +70,536 @@ func (r *PostgresRepo) SaveUser(ctx context.Context, email, username string, pas
 	return id, nil
 }
 
-func (r *PostgresRepo) User(ctx context.Context, email string) (models.User, error) {
-	query := `
-		SELECT id, email, username, password_hash, is_verified
-		FROM users
-		WHERE email = $1;
-	`
-
-	row := r.pool.QueryRow(ctx, query, email)
+// * userColumns — колонки users в порядке полей, которые читает scanUser
+const userColumns = `id, email, COALESCE(pending_email, ''), username, display_name, locale, timezone,
+	currency, password_hash, is_verified, role, created_at`
 
+func scanUser(row pgx.Row) (models.User, error) {
 	var u models.User
+
 	err := row.Scan(
 		&u.ID,
 		&u.Email,
+		&u.PendingEmail,
 		&u.Username,
+		&u.DisplayName,
+		&u.Locale,
+		&u.Timezone,
+		&u.Currency,
 		&u.PassHash,
 		&u.IsVerified,
+		&u.Role,
+		&u.CreatedAt,
 	)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return models.User{}, storage.ErrUserNotFound
-		}
-
-		return models.User{}, err
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, storage.ErrUserNotFound
 	}
 
 	return u, err
 }
 
+func (r *PostgresRepo) User(ctx context.Context, email string) (models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+
+	return scanUser(r.pool.QueryRow(ctx, query, email))
+}
+
 func (r *PostgresRepo) UserByID(ctx context.Context, id int64) (models.User, error) {
-	query := `
-		SELECT id, email, username, password_hash, is_verified
-		FROM users
-		WHERE id = $1;
-	`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
-	row := r.pool.QueryRow(ctx, query, id)
+	return scanUser(r.pool.QueryRow(ctx, query, id))
+}
 
-	var u models.User
-	err := row.Scan(
-		&u.ID,
-		&u.Email,
-		&u.Username,
-		&u.PassHash,
-		&u.IsVerified,
-	)
-	if errors.Is(err, pgx.ErrNoRows) {
-		return models.User{}, storage.ErrUserNotFound
+// * VerifyEmail гасит токен подтверждения и отмечает email пользователя подтверждённым
+func (r *PostgresRepo) VerifyEmail(ctx context.Context, userID int64, jti, purpose string) error {
+	const op = "storage.postgres.VerifyEmail"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
+	defer tx.Rollback(ctx)
 
-	return u, err
-}
+	if err := consumeOneTimeToken(ctx, tx, jti, userID, purpose); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET is_verified = TRUE WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
-func (r *PostgresRepo) SetEmailVerified(ctx context.Context, userID int64) error {
-	query := `UPDATE users SET is_verified = TRUE WHERE id = $1`
+	if tag.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
 
-	_, err := r.pool.Exec(ctx, query, userID)
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
-	return err
+	return nil
 }
 
+// * SaveRefreshToken открывает новую сессию и возвращает её идентификатор (family_id)
 func (r *PostgresRepo) SaveRefreshToken(
 	ctx context.Context,
 	userID int64,
 	appID int32,
 	tokenHash []byte,
 	expiresAt time.Time,
-) error {
+	client models.ClientInfo,
+) (string, error) {
+	const op = "storage.postgres.SaveRefreshToken"
+
 	const query = `
-		INSERT INTO refresh_tokens (user_id, app_id ,token_hash, expires_at)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO refresh_tokens (user_id, app_id, token_hash, expires_at, user_agent, ip)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING family_id::text
 	`
 
-	_, err := r.pool.Exec(ctx, query, userID, appID, tokenHash, expiresAt)
-	return err
+	var familyID string
+
+	err := r.pool.QueryRow(ctx, query, userID, appID, tokenHash, expiresAt, client.UserAgent, client.IP).Scan(&familyID)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return familyID, nil
 }
 
-func (r *PostgresRepo) UpdateRefreshToken(
+// * RotateRefreshToken помечает старый токен как ротированный и выпускает новый
+// * в том же семействе. Условный UPDATE гарантирует, что из двух одновременных
+// * запросов с одним токеном выиграет только один: второй получит
+// * storage.ErrRefreshTokenReused
+func (r *PostgresRepo) RotateRefreshToken(
 	ctx context.Context,
-	userID int64,
 	oldTokenHash []byte,
 	newTokenHash []byte,
 	expiresAt time.Time,
+	client models.ClientInfo,
 ) error {
+	const op = "storage.postgres.RotateRefreshToken"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	const rotateQuery = `
+		UPDATE refresh_tokens
+		SET rotated_at = NOW()
+		WHERE token_hash = $1
+		  AND rotated_at IS NULL
+		  AND revoked_at IS NULL
+		  AND expires_at > NOW()
+		RETURNING user_id, app_id, family_id;
+	`
+
+	var (
+		userID   int64
+		appID    int32
+		familyID string
+	)
+
+	err = tx.QueryRow(ctx, rotateQuery, oldTokenHash).Scan(&userID, &appID, &familyID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrRefreshTokenReused
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	const insertQuery = `
+		INSERT INTO refresh_tokens (user_id, app_id, token_hash, expires_at, family_id, user_agent, ip)
+		VALUES ($1, $2, $3, $4, $5::uuid, $6, $7)
+	`
+
+	_, err = tx.Exec(ctx, insertQuery, userID, appID, newTokenHash, expiresAt, familyID, client.UserAgent, client.IP)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * GetRefreshToken ищет токен по SHA-256 дайджесту через уникальный индекс.
+// * Ротированные и отозванные токены тоже возвращаются, чтобы вызывающий
+// * мог распознать повторное использование
+func (r *PostgresRepo) GetRefreshToken(ctx context.Context, tokenHash []byte) (models.RefreshToken, error) {
+	const op = "storage.postgres.GetRefreshToken"
+
 	const query = `
+		SELECT user_id, app_id, token_hash, family_id::text, expires_at, rotated_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1;
+	`
+
+	var rt models.RefreshToken
+
+	err := r.pool.QueryRow(ctx, query, tokenHash).Scan(
+		&rt.UserID,
+		&rt.AppID,
+		&rt.TokenHash,
+		&rt.FamilyID,
+		&rt.ExpiresAt,
+		&rt.RotatedAt,
+		&rt.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.RefreshToken{}, storage.ErrRefreshTokenNotFound
+		}
+
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rt, nil
+}
+
+// * RevokeRefreshTokenFamily отзывает все токены семейства и записывает событие безопасности
+func (r *PostgresRepo) RevokeRefreshTokenFamily(
+	ctx context.Context,
+	userID int64,
+	familyID string,
+	eventType string,
+) error {
+	const op = "storage.postgres.RevokeRefreshTokenFamily"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	const revokeQuery = `
 		UPDATE refresh_tokens
-		SET token_hash = $1, expires_at = $2
-		WHERE user_id = $3 AND token_hash = $4
+		SET revoked_at = NOW()
+		WHERE family_id = $1::uuid AND revoked_at IS NULL
 	`
 
-	_, err := r.pool.Exec(ctx, query, newTokenHash, expiresAt, userID, oldTokenHash)
+	if _, err := tx.Exec(ctx, revokeQuery, familyID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	const eventQuery = `
+		INSERT INTO security_events (user_id, type, family_id)
+		VALUES ($1, $2, $3::uuid)
+	`
+
+	if _, err := tx.Exec(ctx, eventQuery, userID, eventType, familyID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * DeleteRefreshTokenFamily удаляет все токены сессии, включая ротированные
+func (r *PostgresRepo) DeleteRefreshTokenFamily(ctx context.Context, familyID string) error {
+	query := `DELETE FROM refresh_tokens WHERE family_id = $1::uuid`
+
+	_, err := r.pool.Exec(ctx, query, familyID)
+
 	return err
 }
 
-func (r *PostgresRepo) GetRefreshToken(ctx context.Context, rawToken string) (models.RefreshToken, error) {
+// * Sessions возвращает активные сессии пользователя: по одной действующей
+// * строке на семейство, начиная с последней использованной
+func (r *PostgresRepo) Sessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	const op = "storage.postgres.Sessions"
+
 	const query = `
-		SELECT user_id, app_id, token_hash, expires_at
-		FROM refresh_tokens
-		WHERE expires_at > NOW();
+		SELECT
+			t.family_id::text,
+			t.user_agent,
+			t.ip,
+			(SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id),
+			t.last_used_at,
+			t.expires_at
+		FROM refresh_tokens t
+		WHERE t.user_id = $1
+		  AND t.rotated_at IS NULL
+		  AND t.revoked_at IS NULL
+		  AND t.expires_at > NOW()
+		ORDER BY t.last_used_at DESC
 	`
 
-	rows, err := r.pool.Query(ctx, query)
+	rows, err := r.pool.Query(ctx, query, userID)
 	if err != nil {
-		return models.RefreshToken{}, err
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer rows.Close()
 
-	var rt models.RefreshToken
+	sessions := make([]models.Session, 0)
 
 	for rows.Next() {
-		var (
-			userID    int64
-			appID     int32
-			tokenHash []byte
-			expiresAt time.Time
-		)
+		var s models.Session
 
-		err := rows.Scan(&userID, &appID, &tokenHash, &expiresAt)
-		if err != nil {
-			return models.RefreshToken{}, err
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 
-		if bcrypt.CompareHashAndPassword(tokenHash, []byte(rawToken)) == nil {
-			rt.UserID = userID
-			rt.AppID = appID
-			rt.TokenHash = tokenHash
-			rt.ExpiresAt = expiresAt
-			return rt, nil
+		sessions = append(sessions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// * DeleteSession завершает сессию пользователя, удаляя всё семейство токенов
+func (r *PostgresRepo) DeleteSession(ctx context.Context, userID int64, familyID string) error {
+	const op = "storage.postgres.DeleteSession"
+
+	const query = `DELETE FROM refresh_tokens WHERE user_id = $1 AND family_id = $2::uuid`
+
+	tag, err := r.pool.Exec(ctx, query, userID, familyID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// * SessionActive сообщает, что сессия не завершена: у семейства есть
+// * действующий refresh токен. Пустой sessionID (токены до появления сессий)
+// * считается завершённой сессией
+func (r *PostgresRepo) SessionActive(ctx context.Context, userID int64, sessionID string) (bool, error) {
+	const op = "storage.postgres.SessionActive"
+
+	if sessionID == "" {
+		return false, nil
+	}
+
+	const query = `
+		SELECT EXISTS (
+			SELECT 1 FROM refresh_tokens
+			WHERE family_id = $1::uuid
+			  AND user_id = $2
+			  AND rotated_at IS NULL
+			  AND revoked_at IS NULL
+			  AND expires_at > NOW()
+		)
+	`
+
+	var active bool
+	if err := r.pool.QueryRow(ctx, query, sessionID, userID).Scan(&active); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return active, nil
+}
+
+// * DeleteSessions завершает все сессии пользователя
+func (r *PostgresRepo) DeleteSessions(ctx context.Context, userID int64) error {
+	const op = "storage.postgres.DeleteSessions"
+
+	if _, err := r.pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepo) SaveOneTimeToken(
+	ctx context.Context,
+	jti string,
+	userID int64,
+	purpose string,
+	expiresAt time.Time,
+) error {
+	const op = "storage.postgres.SaveOneTimeToken"
+
+	const query = `
+		INSERT INTO one_time_tokens (jti, user_id, purpose, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := r.pool.Exec(ctx, query, jti, userID, purpose, expiresAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * ResetPassword в одной транзакции гасит токен сброса, меняет пароль
+// * и удаляет все refresh токены пользователя
+func (r *PostgresRepo) ResetPassword(
+	ctx context.Context,
+	userID int64,
+	jti string,
+	purpose string,
+	passHash []byte,
+) error {
+	const op = "storage.postgres.ResetPassword"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := consumeOneTimeToken(ctx, tx, jti, userID, purpose); err != nil {
+		return err
+	}
+
+	const passwordQuery = `UPDATE users SET password_hash = $1 WHERE id = $2`
+
+	tag, err := tx.Exec(ctx, passwordQuery, string(passHash), userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	const revokeQuery = `DELETE FROM refresh_tokens WHERE user_id = $1`
+
+	if _, err := tx.Exec(ctx, revokeQuery, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * consumeOneTimeToken помечает токен использованным. Для уже использованного
+// * токена возвращает storage.ErrOneTimeTokenUsed, для неизвестного или
+// * просроченного — storage.ErrOneTimeTokenNotFound
+func consumeOneTimeToken(ctx context.Context, tx pgx.Tx, jti string, userID int64, purpose string) error {
+	const op = "storage.postgres.consumeOneTimeToken"
+
+	const consumeQuery = `
+		UPDATE one_time_tokens
+		SET used_at = NOW()
+		WHERE jti = $1
+		  AND user_id = $2
+		  AND purpose = $3
+		  AND used_at IS NULL
+		  AND expires_at > NOW()
+	`
+
+	tag, err := tx.Exec(ctx, consumeQuery, jti, userID, purpose)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	const usedQuery = `
+		SELECT used_at IS NOT NULL
+		FROM one_time_tokens
+		WHERE jti = $1 AND user_id = $2 AND purpose = $3
+	`
+
+	var used bool
+
+	err = tx.QueryRow(ctx, usedQuery, jti, userID, purpose).Scan(&used)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrOneTimeTokenNotFound
 		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if used {
+		return storage.ErrOneTimeTokenUsed
 	}
-	if rows.Err() != nil {
-		return models.RefreshToken{}, rows.Err()
+
+	return storage.ErrOneTimeTokenNotFound
+}
+
+// * LoginLockedUntil возвращает самую позднюю блокировку среди ключей
+func (r *PostgresRepo) LoginLockedUntil(ctx context.Context, keys []string) (time.Time, error) {
+	const op = "storage.postgres.LoginLockedUntil"
+
+	const query = `
+		SELECT COALESCE(MAX(locked_until), 'epoch'::timestamptz)
+		FROM login_attempts
+		WHERE key = ANY($1)
+	`
+
+	var lockedUntil time.Time
+
+	if err := r.pool.QueryRow(ctx, query, keys).Scan(&lockedUntil); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return models.RefreshToken{}, storage.ErrRefreshTokenNotFound
+	return lockedUntil, nil
 }
 
-func (r *PostgresRepo) DeleteRefreshToken(ctx context.Context, tokenHash []byte) error {
-	query := `DELETE FROM refresh_tokens WHERE token_hash = $1`
+// * RegisterLoginFailure увеличивает счётчик неудач ключа (или начинает его заново,
+// * если прошлая неудача старше resetBefore) и возвращает новое значение
+func (r *PostgresRepo) RegisterLoginFailure(ctx context.Context, key string, now, resetBefore time.Time) (int, error) {
+	const op = "storage.postgres.RegisterLoginFailure"
 
-	_, err := r.pool.Exec(ctx, query, tokenHash)
+	const query = `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE
+				WHEN login_attempts.last_failure_at < $3 THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = $2
+		RETURNING failures
+	`
 
-	return err
+	var failures int
+
+	if err := r.pool.QueryRow(ctx, query, key, now, resetBefore).Scan(&failures); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return failures, nil
+}
+
+func (r *PostgresRepo) LockLogin(ctx context.Context, key string, until time.Time) error {
+	const op = "storage.postgres.LockLogin"
+
+	const query = `
+		UPDATE login_attempts
+		SET locked_until = GREATEST(COALESCE(locked_until, $2), $2)
+		WHERE key = $1
+	`
+
+	if _, err := r.pool.Exec(ctx, query, key, until); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepo) ResetLoginFailures(ctx context.Context, key string) error {
+	const op = "storage.postgres.ResetLoginFailures"
+
+	if _, err := r.pool.Exec(ctx, `DELETE FROM login_attempts WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
 }
 
 func (r *PostgresRepo) App(ctx context.Context, appID int32) (models.App, error) {
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"auth_service/internal/models"
+	"auth_service/internal/storage"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// * UpdateProfile меняет переданные поля профиля и в той же транзакции
+// * ставит в очередь событие user.updated
+func (r *PostgresRepo) UpdateProfile(
+	ctx context.Context,
+	userID int64,
+	upd models.ProfileUpdate,
+	occurredAt time.Time,
+) (models.User, error) {
+	const op = "storage.postgres.UpdateProfile"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE users
+		SET username = COALESCE($2, username),
+			display_name = COALESCE($3, display_name),
+			locale = COALESCE($4, locale),
+			timezone = COALESCE($5, timezone),
+			currency = COALESCE($6, currency)
+		WHERE id = $1
+		RETURNING ` + userColumns
+
+	user, err := scanUser(tx.QueryRow(ctx, query,
+		userID, upd.Username, upd.DisplayName, upd.Locale, upd.Timezone, upd.Currency,
+	))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.User{}, storage.ErrUsernameTaken
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, storage.ErrUserNotFound
+		}
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := insertUserEvent(ctx, tx, userUpdatedEvent(user, occurredAt)); err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// * ChangePassword меняет пароль и завершает все сессии, кроме keepSessionID
+func (r *PostgresRepo) ChangePassword(ctx context.Context, userID int64, passHash []byte, keepSessionID string) error {
+	const op = "storage.postgres.ChangePassword"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET password_hash = $1 WHERE id = $2`, string(passHash), userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	const revokeQuery = `DELETE FROM refresh_tokens WHERE user_id = $1 AND family_id::text <> $2`
+
+	if _, err := tx.Exec(ctx, revokeQuery, userID, keepSessionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * SetPendingEmail запоминает новый адрес до подтверждения и гасит ссылки,
+// * выданные для предыдущего запроса смены: иначе старая ссылка подтвердила бы новый адрес
+func (r *PostgresRepo) SetPendingEmail(ctx context.Context, userID int64, email, purpose string) error {
+	const op = "storage.postgres.SetPendingEmail"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE users SET pending_email = $1 WHERE id = $2`, email, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+
+	const expireQuery = `
+		UPDATE one_time_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`
+
+	if _, err := tx.Exec(ctx, expireQuery, userID, purpose); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * ConfirmEmailChange гасит токен, заменяет email подтверждённым pending_email
+// * и ставит в очередь событие user.updated
+func (r *PostgresRepo) ConfirmEmailChange(
+	ctx context.Context,
+	userID int64,
+	jti, purpose string,
+	occurredAt time.Time,
+) (models.User, error) {
+	const op = "storage.postgres.ConfirmEmailChange"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := consumeOneTimeToken(ctx, tx, jti, userID, purpose); err != nil {
+		return models.User{}, err
+	}
+
+	query := `
+		UPDATE users
+		SET email = pending_email,
+			pending_email = NULL,
+			is_verified = TRUE
+		WHERE id = $1 AND pending_email IS NOT NULL
+		RETURNING ` + userColumns
+
+	user, err := scanUser(tx.QueryRow(ctx, query, userID))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.User{}, storage.ErrEmailTaken
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return models.User{}, storage.ErrNoPendingEmail
+		}
+
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := insertUserEvent(ctx, tx, userUpdatedEvent(user, occurredAt)); err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+func userUpdatedEvent(user models.User, occurredAt time.Time) models.Event {
+	return models.Event{
+		Type:       models.EventUserUpdated,
+		UserID:     user.ID,
+		OccurredAt: occurredAt,
+		Profile: &models.EventProfile{
+			Email:       user.Email,
+			Username:    user.Username,
+			DisplayName: user.DisplayName,
+			Locale:      user.Locale,
+			Timezone:    user.Timezone,
+			Currency:    user.Currency,
+		},
+	}
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
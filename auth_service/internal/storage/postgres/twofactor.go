@@ -0,0 +1,265 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"auth_service/internal/models"
+	"auth_service/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *PostgresRepo) TwoFactor(ctx context.Context, userID int64) (models.TwoFactor, error) {
+	const op = "storage.postgres.TwoFactor"
+
+	const query = `
+		SELECT user_id, secret_encrypted, confirmed_at, last_used_step
+		FROM user_totp
+		WHERE user_id = $1
+	`
+
+	var tf models.TwoFactor
+
+	err := r.pool.QueryRow(ctx, query, userID).Scan(&tf.UserID, &tf.Secret, &tf.ConfirmedAt, &tf.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.TwoFactor{}, storage.ErrTwoFactorNotFound
+		}
+
+		return models.TwoFactor{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tf, nil
+}
+
+// * SavePendingTwoFactor сохраняет новый, ещё не подтверждённый секрет.
+// * Подтверждённая настройка не перезаписывается: возвращается false
+func (r *PostgresRepo) SavePendingTwoFactor(ctx context.Context, userID int64, secret []byte) (bool, error) {
+	const op = "storage.postgres.SavePendingTwoFactor"
+
+	const query = `
+		INSERT INTO user_totp (user_id, secret_encrypted)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted,
+			last_used_step = 0,
+			created_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL
+	`
+
+	tag, err := r.pool.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// * UseTOTPStep запоминает шаг принятого кода; повтор того же или более раннего
+// * шага возвращает storage.ErrTOTPStepUsed
+func (r *PostgresRepo) UseTOTPStep(ctx context.Context, userID int64, step int64) error {
+	const op = "storage.postgres.UseTOTPStep"
+
+	const query = `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+	`
+
+	tag, err := r.pool.Exec(ctx, query, userID, step)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrTOTPStepUsed
+	}
+
+	return nil
+}
+
+// * ConfirmTwoFactor включает 2FA и заменяет коды восстановления
+func (r *PostgresRepo) ConfirmTwoFactor(ctx context.Context, userID int64, recoveryCodeHashes [][]byte) error {
+	const op = "storage.postgres.ConfirmTwoFactor"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE user_totp SET confirmed_at = NOW() WHERE user_id = $1 AND confirmed_at IS NULL`, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrTwoFactorNotFound
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepo) ReplaceRecoveryCodes(ctx context.Context, userID int64, recoveryCodeHashes [][]byte) error {
+	const op = "storage.postgres.ReplaceRecoveryCodes"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int64, hashes [][]byte) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	const query = `
+		INSERT INTO totp_recovery_codes (user_id, code_hash)
+		SELECT $1, unnest($2::bytea[])
+	`
+
+	_, err := tx.Exec(ctx, query, userID, hashes)
+
+	return err
+}
+
+func (r *PostgresRepo) UseRecoveryCode(ctx context.Context, userID int64, codeHash []byte) error {
+	const op = "storage.postgres.UseRecoveryCode"
+
+	const query = `
+		UPDATE totp_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	tag, err := r.pool.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresRepo) DeleteTwoFactor(ctx context.Context, userID int64) error {
+	const op = "storage.postgres.DeleteTwoFactor"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepo) SaveLoginChallenge(
+	ctx context.Context,
+	tokenHash []byte,
+	userID int64,
+	appID int32,
+	expiresAt time.Time,
+) error {
+	const op = "storage.postgres.SaveLoginChallenge"
+
+	const query = `
+		INSERT INTO login_challenges (token_hash, user_id, app_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := r.pool.Exec(ctx, query, tokenHash, userID, appID, expiresAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * ClaimChallengeAttempt одним UPDATE расходует попытку ввода кода: challenge
+// * должен быть не использован, не истёк и иметь меньше maxAttempts попыток.
+// * Параллельные запросы не могут проверить больше maxAttempts кодов, так как
+// * попытка учитывается до проверки. Иначе возвращает storage.ErrChallengeNotFound
+func (r *PostgresRepo) ClaimChallengeAttempt(
+	ctx context.Context,
+	tokenHash []byte,
+	now time.Time,
+	maxAttempts int,
+) (models.LoginChallenge, error) {
+	const op = "storage.postgres.ClaimChallengeAttempt"
+
+	const query = `
+		UPDATE login_challenges
+		SET attempts = attempts + 1
+		WHERE token_hash = $1
+		  AND used_at IS NULL
+		  AND expires_at > $2
+		  AND attempts < $3
+		RETURNING id, user_id, app_id, attempts, expires_at
+	`
+
+	var c models.LoginChallenge
+
+	err := r.pool.QueryRow(ctx, query, tokenHash, now, maxAttempts).Scan(&c.ID, &c.UserID, &c.AppID, &c.Attempts, &c.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.LoginChallenge{}, storage.ErrChallengeNotFound
+		}
+
+		return models.LoginChallenge{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return c, nil
+}
+
+// * ConsumeLoginChallenge гасит challenge; повторное использование возвращает storage.ErrChallengeNotFound
+func (r *PostgresRepo) ConsumeLoginChallenge(ctx context.Context, id int64) error {
+	const op = "storage.postgres.ConsumeLoginChallenge"
+
+	tag, err := r.pool.Exec(ctx, `UPDATE login_challenges SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return storage.ErrChallengeNotFound
+	}
+
+	return nil
+}
@@ -7,4 +7,15 @@ var (
 	ErrUserNotFound         = errors.New("user not found")
 	ErrAppNotFound          = errors.New("app not found")
 	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenReused   = errors.New("refresh token already rotated")
+	ErrOneTimeTokenNotFound = errors.New("one-time token not found")
+	ErrOneTimeTokenUsed     = errors.New("one-time token already used")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrTwoFactorNotFound    = errors.New("two-factor authentication not configured")
+	ErrTOTPStepUsed         = errors.New("totp code already used")
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found or already used")
+	ErrChallengeNotFound    = errors.New("login challenge not found")
+	ErrUsernameTaken        = errors.New("username already taken")
+	ErrEmailTaken           = errors.New("email already taken")
+	ErrNoPendingEmail       = errors.New("no pending email change")
 )
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * удалённые аккаунты: строка пишется в одной транзакции с удалением пользователя
+-- * и живёт, пока событие user.deleted не будет опубликовано для остальных сервисов
+CREATE TABLE deleted_users (
+  user_id BIGINT PRIMARY KEY,
+  deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  event_published_at TIMESTAMPTZ
+);
+
+CREATE INDEX deleted_users_pending_idx ON deleted_users(deleted_at)
+  WHERE event_published_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS deleted_users;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * счётчики неудачных входов по ключу: "ip:<адрес>" или "account:<email>"
+CREATE TABLE login_attempts (
+  key TEXT PRIMARY KEY,
+  failures INTEGER NOT NULL DEFAULT 0,
+  last_failure_at TIMESTAMPTZ NOT NULL,
+  locked_until TIMESTAMPTZ
+);
+
+CREATE INDEX login_attempts_last_failure_idx ON login_attempts(last_failure_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_attempts;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * message_id — id конверта события: при повторной публикации он не меняется,
+-- * и получатель может отбросить дубликат
+ALTER TABLE user_events
+  ADD COLUMN message_id UUID NOT NULL DEFAULT gen_random_uuid();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user_events
+  DROP COLUMN IF EXISTS message_id;
+-- +goose StatementEnd
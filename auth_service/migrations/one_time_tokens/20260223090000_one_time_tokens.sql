@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * одноразовые токены (сброс пароля и т.п.): сам токен — подписанный JWT,
+-- * здесь хранится его jti, чтобы токен нельзя было использовать повторно
+CREATE TABLE one_time_tokens (
+  jti TEXT PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  purpose TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ
+);
+
+CREATE INDEX one_time_tokens_user_idx ON one_time_tokens(user_id, purpose);
+CREATE INDEX one_time_tokens_expires_idx ON one_time_tokens(expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS one_time_tokens;
+-- +goose StatementEnd
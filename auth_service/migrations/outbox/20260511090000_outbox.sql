@@ -0,0 +1,69 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * события об аккаунтах для остальных сервисов: строка пишется в одной
+-- * транзакции с изменением аккаунта, payload — закодированный конверт.
+-- * claimed_at — отметка relay, взявшего строку; published_at — подтверждение брокера
+CREATE TABLE outbox (
+  id BIGSERIAL PRIMARY KEY,
+  payload JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  claimed_at TIMESTAMPTZ,
+  published_at TIMESTAMPTZ
+);
+
+CREATE INDEX outbox_pending_idx ON outbox(id)
+  WHERE published_at IS NULL;
+
+CREATE INDEX outbox_published_at_idx ON outbox(published_at)
+  WHERE published_at IS NOT NULL;
+
+-- * outbox заменяет user_events: неопубликованные события переносятся
+-- * конвертами с прежним message_id, чтобы получатель распознал дубликат
+-- * события, опубликованного старым relay перед остановкой
+INSERT INTO outbox (payload, created_at)
+SELECT jsonb_build_object(
+    'type', type,
+    'version', 1,
+    'id', message_id::text,
+    'occurred_at', payload -> 'occurred_at',
+    'payload', payload
+  ),
+  created_at
+FROM user_events
+WHERE published_at IS NULL
+ORDER BY id;
+
+DROP TABLE user_events;
+
+-- * deleted_users уже перенесена в user_events миграцией profile,
+-- * если база накатывалась по порядку
+DROP TABLE IF EXISTS deleted_users;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE user_events (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  type TEXT NOT NULL,
+  payload JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  published_at TIMESTAMPTZ,
+  message_id UUID NOT NULL DEFAULT gen_random_uuid()
+);
+
+CREATE INDEX user_events_pending_idx ON user_events(id)
+  WHERE published_at IS NULL;
+
+INSERT INTO user_events (user_id, type, payload, created_at, message_id)
+SELECT (payload -> 'payload' ->> 'user_id')::bigint,
+  payload ->> 'type',
+  payload -> 'payload',
+  created_at,
+  (payload ->> 'id')::uuid
+FROM outbox
+WHERE published_at IS NULL
+ORDER BY id;
+
+DROP TABLE IF EXISTS outbox;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * attempts — неудачные публикации события; до available_at оно отложено
+-- * после неудачи и не задерживает следующие. Исчерпавшее попытки событие
+-- * получает failed_at и больше не публикуется, last_error — причина
+ALTER TABLE outbox
+  ADD COLUMN attempts INT NOT NULL DEFAULT 0,
+  ADD COLUMN available_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ADD COLUMN failed_at TIMESTAMPTZ,
+  ADD COLUMN last_error TEXT;
+
+DROP INDEX IF EXISTS outbox_pending_idx;
+
+CREATE INDEX outbox_pending_idx ON outbox(id)
+  WHERE published_at IS NULL AND failed_at IS NULL;
+
+CREATE INDEX outbox_failed_at_idx ON outbox(failed_at)
+  WHERE failed_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS outbox_failed_at_idx;
+DROP INDEX IF EXISTS outbox_pending_idx;
+
+CREATE INDEX outbox_pending_idx ON outbox(id)
+  WHERE published_at IS NULL;
+
+ALTER TABLE outbox
+  DROP COLUMN IF EXISTS attempts,
+  DROP COLUMN IF EXISTS available_at,
+  DROP COLUMN IF EXISTS failed_at,
+  DROP COLUMN IF EXISTS last_error;
+-- +goose StatementEnd
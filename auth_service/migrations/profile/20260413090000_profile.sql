@@ -0,0 +1,64 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * настройки профиля; pending_email — новый адрес, ожидающий подтверждения
+ALTER TABLE users
+  ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+  ADD COLUMN locale TEXT NOT NULL DEFAULT 'ru',
+  ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC',
+  ADD COLUMN currency TEXT NOT NULL DEFAULT '',
+  ADD COLUMN pending_email TEXT;
+
+-- * события об аккаунтах для остальных сервисов: строка пишется в одной
+-- * транзакции с изменением и публикуется фоновым relay
+CREATE TABLE user_events (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  type TEXT NOT NULL,
+  payload JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  published_at TIMESTAMPTZ
+);
+
+CREATE INDEX user_events_pending_idx ON user_events(id)
+  WHERE published_at IS NULL;
+
+-- * deleted_users заменяется user_events: переносим все удаления,
+-- * неопубликованные останутся в очереди relay
+INSERT INTO user_events (user_id, type, payload, created_at, published_at)
+SELECT user_id,
+  'user.deleted',
+  jsonb_build_object('type', 'user.deleted', 'user_id', user_id, 'occurred_at', deleted_at),
+  deleted_at,
+  event_published_at
+FROM deleted_users
+ORDER BY deleted_at;
+
+DROP TABLE deleted_users;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE deleted_users (
+  user_id BIGINT PRIMARY KEY,
+  deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  event_published_at TIMESTAMPTZ
+);
+
+CREATE INDEX deleted_users_pending_idx ON deleted_users(deleted_at)
+  WHERE event_published_at IS NULL;
+
+INSERT INTO deleted_users (user_id, deleted_at, event_published_at)
+SELECT user_id, MIN(created_at), MIN(published_at)
+FROM user_events
+WHERE type = 'user.deleted'
+GROUP BY user_id;
+
+DROP TABLE IF EXISTS user_events;
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS pending_email,
+  DROP COLUMN IF EXISTS currency,
+  DROP COLUMN IF EXISTS timezone,
+  DROP COLUMN IF EXISTS locale,
+  DROP COLUMN IF EXISTS display_name;
+-- +goose StatementEnd
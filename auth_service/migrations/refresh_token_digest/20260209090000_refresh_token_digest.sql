@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * старые токены хранились как bcrypt-хэши и не могут быть найдены по индексу,
+-- * поэтому они удаляются: пользователям нужно будет войти заново
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+  ALTER COLUMN token_hash TYPE BYTEA USING convert_to(token_hash, 'UTF8');
+
+CREATE UNIQUE INDEX refresh_tokens_token_hash_uniq ON refresh_tokens(token_hash);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS refresh_tokens_token_hash_uniq;
+
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+  ALTER COLUMN token_hash TYPE TEXT USING encode(token_hash, 'hex');
+-- +goose StatementEnd
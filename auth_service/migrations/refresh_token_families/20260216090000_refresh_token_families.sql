@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * family_id объединяет все токены одной сессии: при ротации старая строка
+-- * помечается rotated_at и остаётся для обнаружения повторного использования
+ALTER TABLE refresh_tokens
+  ADD COLUMN family_id UUID NOT NULL DEFAULT gen_random_uuid(),
+  ADD COLUMN rotated_at TIMESTAMPTZ,
+  ADD COLUMN revoked_at TIMESTAMPTZ;
+
+CREATE INDEX refresh_tokens_family_idx ON refresh_tokens(family_id);
+
+CREATE TABLE security_events (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  type TEXT NOT NULL,
+  family_id UUID,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX security_events_user_idx ON security_events(user_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS security_events;
+
+DELETE FROM refresh_tokens WHERE rotated_at IS NOT NULL OR revoked_at IS NOT NULL;
+
+DROP INDEX IF EXISTS refresh_tokens_family_idx;
+
+ALTER TABLE refresh_tokens
+  DROP COLUMN IF EXISTS revoked_at,
+  DROP COLUMN IF EXISTS rotated_at,
+  DROP COLUMN IF EXISTS family_id;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * роль пользователя попадает в access токен claim'ом role
+ALTER TABLE users
+  ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+  CONSTRAINT users_role_check CHECK (role IN ('user', 'admin'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+-- +goose StatementEnd
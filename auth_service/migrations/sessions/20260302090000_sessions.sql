@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * сессия — семейство refresh токенов (family_id); клиентские данные
+-- * копируются в каждую новую строку при ротации
+ALTER TABLE refresh_tokens
+  ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+  ADD COLUMN ip TEXT NOT NULL DEFAULT '',
+  ADD COLUMN last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX refresh_tokens_user_active_idx ON refresh_tokens(user_id)
+  WHERE rotated_at IS NULL AND revoked_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS refresh_tokens_user_active_idx;
+
+ALTER TABLE refresh_tokens
+  DROP COLUMN IF EXISTS last_used_at,
+  DROP COLUMN IF EXISTS ip,
+  DROP COLUMN IF EXISTS user_agent;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * TOTP секрет хранится зашифрованным (AES-GCM, ключ из конфига);
+-- * last_used_step не даёт предъявить один и тот же код дважды
+CREATE TABLE user_totp (
+  user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret_encrypted BYTEA NOT NULL,
+  confirmed_at TIMESTAMPTZ,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE totp_recovery_codes (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash BYTEA NOT NULL,
+  used_at TIMESTAMPTZ,
+  UNIQUE (user_id, code_hash)
+);
+
+-- * промежуточный шаг входа: пароль проверен, ожидается второй фактор
+CREATE TABLE login_challenges (
+  id BIGSERIAL PRIMARY KEY,
+  token_hash BYTEA NOT NULL UNIQUE,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  app_id INTEGER NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ
+);
+
+CREATE INDEX login_challenges_expires_idx ON login_challenges(expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_challenges;
+DROP TABLE IF EXISTS totp_recovery_codes;
+DROP TABLE IF EXISTS user_totp;
+-- +goose StatementEnd
@@ -6,12 +6,15 @@ import (
 	sl "email_sender/internal/lib/logger"
 	mailer "email_sender/internal/mail-sender"
 	"email_sender/internal/models"
-	"email_sender/internal/rabbitmq"
-	"encoding/json"
+	"email_sender/internal/templates"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+
+	"messaging/broker"
+	"messaging/envelope"
 )
 
 const (
@@ -33,12 +36,30 @@ func main() {
 }
 
 func startServer(ctx context.Context, cfg *config.Config, log *slog.Logger) {
-	r, err := rabbitmq.New(cfg.RabbitMQURL)
+	conn, err := broker.Connect(cfg.RabbitMQURL, log)
 	if err != nil {
 		log.Error("failed to init rabbitmq", sl.Err(err))
 		return
 	}
-	defer r.Close()
+	defer conn.Close()
+
+	// * publisher default exchange перекладывает письма в очереди повторов и DLQ
+	publisher := broker.NewPublisher(conn, "")
+	defer publisher.Close()
+
+	retry := broker.NewRetryPolicy(publisher, cfg.Retry.MaxAttempts, cfg.Retry.BaseDelay)
+
+	topology := []broker.Topology{
+		broker.Queue(cfg.Exchange, cfg.QueueName, envelope.TypeEmailRequested),
+		retry.Topology(cfg.QueueName),
+	}
+
+	for _, t := range topology {
+		if err := conn.Declare(t); err != nil {
+			log.Error("failed to declare queues", sl.Err(err))
+			return
+		}
+	}
 
 	m := &mailer.Mailer{
 		Host:     cfg.Email.Host,
@@ -47,28 +68,21 @@ func startServer(ctx context.Context, cfg *config.Config, log *slog.Logger) {
 		Password: cfg.Email.Password,
 	}
 
+	renderer, err := templates.New()
+	if err != nil {
+		log.Error("failed to parse email templates", sl.Err(err))
+		return
+	}
+
+	consumer := broker.NewConsumer(conn, log, cfg.QueueName, cfg.WorkerPoolSize, retry, cfg.DrainTimeout)
+
 	done := make(chan struct{})
 
 	go func() {
 		defer close(done)
 
-		err := r.StartReading(ctx, cfg.QueueName, func(msg []byte) {
-			var emailMsg models.EmailMessage
-			if err := json.Unmarshal(msg, &emailMsg); err != nil {
-				log.Error("failed to unmarshal message", sl.Err(err))
-				return
-			}
-
-			err := m.Send(emailMsg.Email,
-				emailMsg.Subject,
-				"http://localhost"+emailMsg.MessageText,
-			)
-			if err != nil {
-				log.Error("failed to send message", sl.Err(err))
-				return
-			}
-
-			log.Info("message sent successfully")
+		err := consumer.Consume(ctx, func(_ context.Context, msg []byte) error {
+			return deliver(log, renderer, m, msg)
 		})
 		if err != nil {
 			log.Error("failed to start reading", sl.Err(err))
@@ -81,6 +95,10 @@ func startServer(ctx context.Context, cfg *config.Config, log *slog.Logger) {
 	select {
 	case <-ctx.Done():
 		log.Info("shutting down consumer...")
+
+		// * Consume возвращается, когда отправка текущих писем завершена
+		// * или отменена по истечении drain_timeout
+		<-done
 	case <-done:
 		log.Info("consumer finished the work")
 	}
@@ -88,6 +106,39 @@ func startServer(ctx context.Context, cfg *config.Config, log *slog.Logger) {
 	log.Info("service gracefully stopped")
 }
 
+// * deliver рендерит и отправляет одно письмо. Некорректное сообщение или
+// * неизвестный шаблон помечаются как Permanent: повтор их не исправит
+func deliver(log *slog.Logger, renderer *templates.Renderer, m *mailer.Mailer, body []byte) error {
+	var msg models.Message
+
+	env, err := envelope.Unmarshal(body, envelope.TypeEmailRequested, &msg)
+	if err != nil {
+		return broker.Permanent(fmt.Errorf("unmarshal message: %w", err))
+	}
+
+	log = log.With(
+		slog.String("message_id", env.ID),
+		slog.String("correlation_id", env.Correlation()),
+	)
+
+	email, err := renderer.Render(msg)
+	if err != nil {
+		return broker.Permanent(fmt.Errorf("render %s email: %w", msg.Type, err))
+	}
+
+	if err := m.Send(email); err != nil {
+		err = fmt.Errorf("send %s email: %w", msg.Type, err)
+		if mailer.IsPermanent(err) {
+			return broker.Permanent(err)
+		}
+		return err
+	}
+
+	log.Info("message sent successfully", slog.String("type", string(msg.Type)))
+
+	return nil
+}
+
 func setupLogger(env string) *slog.Logger {
 	var log *slog.Logger
 
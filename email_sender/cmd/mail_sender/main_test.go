@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	mailer "email_sender/internal/mail-sender"
+	"email_sender/internal/models"
+	"email_sender/internal/templates"
+	"messaging/broker"
+	"messaging/envelope"
+)
+
+// * fakeSMTP — SMTP сервер на localhost без AUTH и STARTTLS. rcptReply и
+// * dataReply задают ответы на RCPT TO и на конец DATA, по умолчанию 250
+type fakeSMTP struct {
+	ln        net.Listener
+	rcptReply string
+	dataReply string
+
+	mu       sync.Mutex
+	messages []string
+}
+
+func newFakeSMTP(t *testing.T, rcptReply, dataReply string) *fakeSMTP {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	s := &fakeSMTP{ln: ln, rcptReply: rcptReply, dataReply: dataReply}
+	t.Cleanup(func() { ln.Close() })
+
+	go s.serve()
+
+	return s
+}
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 fake ESMTP")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 fake")
+		case strings.HasPrefix(cmd, "RCPT"):
+			reply(orOK(s.rcptReply))
+		case cmd == "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+
+			var body strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				body.WriteString(line)
+			}
+
+			if strings.HasPrefix(orOK(s.dataReply), "250") {
+				s.mu.Lock()
+				s.messages = append(s.messages, body.String())
+				s.mu.Unlock()
+			}
+
+			reply(orOK(s.dataReply))
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func orOK(reply string) string {
+	if reply == "" {
+		return "250 OK"
+	}
+
+	return reply
+}
+
+func (s *fakeSMTP) mailer(t *testing.T) *mailer.Mailer {
+	t.Helper()
+
+	host, port, err := net.SplitHostPort(s.ln.Addr().String())
+	if err != nil {
+		t.Fatalf("split addr: %v", err)
+	}
+
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatalf("parse port: %v", err)
+	}
+
+	return &mailer.Mailer{Host: host, Port: p, Username: "noreply@example.com"}
+}
+
+func emailMessage(t *testing.T, emailType models.EmailType, data string) []byte {
+	t.Helper()
+
+	body, err := envelope.Marshal(envelope.TypeEmailRequested, models.Message{
+		Type: emailType,
+		To:   "alice@example.com",
+		Data: json.RawMessage(data),
+	}, "")
+	if err != nil {
+		t.Fatalf("marshal message: %v", err)
+	}
+
+	return body
+}
+
+func TestDeliverClassification(t *testing.T) {
+	renderer, err := templates.New()
+	if err != nil {
+		t.Fatalf("templates.New: %v", err)
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	verification := `{"username": "alice", "link": "https://example.com/verify"}`
+
+	tests := []struct {
+		name          string
+		body          []byte
+		rcptReply     string
+		dataReply     string
+		down          bool
+		wantErr       bool
+		wantPermanent bool
+		wantSent      bool
+	}{
+		{
+			name:     "sent",
+			body:     emailMessage(t, models.TypeVerification, verification),
+			wantSent: true,
+		},
+		{
+			name:          "malformed envelope goes to DLQ",
+			body:          []byte(`{"type": "verification"}`),
+			wantErr:       true,
+			wantPermanent: true,
+		},
+		{
+			name:          "unknown template goes to DLQ",
+			body:          emailMessage(t, "newsletter", `{}`),
+			wantErr:       true,
+			wantPermanent: true,
+		},
+		{
+			name:          "rejected recipient goes to DLQ",
+			body:          emailMessage(t, models.TypeVerification, verification),
+			rcptReply:     "550 5.1.1 mailbox unavailable",
+			wantErr:       true,
+			wantPermanent: true,
+		},
+		{
+			name:      "greylisted recipient is retried",
+			body:      emailMessage(t, models.TypeVerification, verification),
+			rcptReply: "451 4.7.1 try again later",
+			wantErr:   true,
+		},
+		{
+			name:      "temporary data failure is retried",
+			body:      emailMessage(t, models.TypeVerification, verification),
+			dataReply: "452 4.3.1 insufficient storage",
+			wantErr:   true,
+		},
+		{
+			name:          "rejected message goes to DLQ",
+			body:          emailMessage(t, models.TypeVerification, verification),
+			dataReply:     "554 5.7.1 message rejected as spam",
+			wantErr:       true,
+			wantPermanent: true,
+		},
+		{
+			name:      "authentication required is retried",
+			body:      emailMessage(t, models.TypeVerification, verification),
+			rcptReply: "530 5.7.0 authentication required",
+			wantErr:   true,
+		},
+		{
+			name:      "mechanism too weak is retried",
+			body:      emailMessage(t, models.TypeVerification, verification),
+			rcptReply: "534 5.7.9 authentication mechanism is too weak",
+			wantErr:   true,
+		},
+		{
+			name:      "rejected credentials are retried",
+			body:      emailMessage(t, models.TypeVerification, verification),
+			dataReply: "535 5.7.8 authentication credentials invalid",
+			wantErr:   true,
+		},
+		{
+			name:      "server error is retried",
+			body:      emailMessage(t, models.TypeVerification, verification),
+			dataReply: "500 5.5.1 command unrecognized",
+			wantErr:   true,
+		},
+		{
+			name:    "unreachable server is retried",
+			body:    emailMessage(t, models.TypeVerification, verification),
+			down:    true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeSMTP(t, tt.rcptReply, tt.dataReply)
+			m := srv.mailer(t)
+
+			if tt.down {
+				srv.ln.Close()
+			}
+
+			err := deliver(log, renderer, m, tt.body)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("deliver error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if got := broker.IsPermanent(err); got != tt.wantPermanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, got, tt.wantPermanent)
+			}
+
+			srv.mu.Lock()
+			sent := len(srv.messages)
+			srv.mu.Unlock()
+
+			if tt.wantSent && sent != 1 {
+				t.Fatalf("server received %d messages, want 1", sent)
+			}
+
+			if tt.wantSent && !strings.Contains(srv.messages[0], "multipart/alternative") {
+				t.Errorf("message is not multipart/alternative:\n%s", srv.messages[0])
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -11,11 +12,22 @@ type Config struct {
 	Env      string `yaml:"env" env-default:"local"`
 	RabbitMQ `yaml:"rabbitmq"`
 	Email    `yaml:"email"`
+	Retry    `yaml:"retry"`
 }
 
 type RabbitMQ struct {
-	RabbitMQURL string `yaml:"url" env-required:"true"`
-	QueueName   string `yaml:"queue_name" env-required:"true"`
+	RabbitMQURL    string        `yaml:"url" env-required:"true"`
+	Exchange       string        `yaml:"exchange" env-default:"price_monitoring"`
+	QueueName      string        `yaml:"queue_name" env-required:"true"`
+	WorkerPoolSize int           `yaml:"worker_pool_size" env-default:"4"`
+	DrainTimeout   time.Duration `yaml:"drain_timeout" env-default:"20s"`
+}
+
+// * Retry — повторная доставка письма при временных ошибках:
+// * задержка перед n-й повторной попыткой равна BaseDelay * 2^(n-1)
+type Retry struct {
+	MaxAttempts int           `yaml:"max_attempts" env-default:"5"`
+	BaseDelay   time.Duration `yaml:"base_delay" env-default:"30s"`
 }
 
 type Email struct {
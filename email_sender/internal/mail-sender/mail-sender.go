@@ -1,6 +1,13 @@
 package mailSender
 
-import "gopkg.in/gomail.v2"
+import (
+	"email_sender/internal/models"
+	"errors"
+	"fmt"
+	"net/textproto"
+
+	"gopkg.in/gomail.v2"
+)
 
 type Mailer struct {
 	Host     string
@@ -9,14 +16,43 @@ type Mailer struct {
 	Password string
 }
 
-func (m *Mailer) Send(to, username, body string) error {
+// * Send отправляет письмо как multipart/alternative: text/plain и text/html.
+// * Ошибки SMTP возвращаются без потери типа, чтобы их можно было классифицировать через IsPermanent
+func (m *Mailer) Send(email models.Email) error {
+	const op = "mailSender.Send"
+
 	msg := gomail.NewMessage()
-	msg.SetHeader("To", to)
+	msg.SetHeader("To", email.To)
 	msg.SetHeader("From", m.Username)
-	msg.SetHeader("Subject", "Подтверждение почты")
+	msg.SetHeader("Subject", email.Subject)
 
-	msg.SetBody("text/plain", body)
+	msg.SetBody("text/plain", email.Text)
+	msg.AddAlternative("text/html", email.HTML)
 
 	dialer := gomail.NewDialer(m.Host, m.Port, m.Username, m.Password)
-	return dialer.DialAndSend(msg)
+
+	sc, err := dialer.Dial()
+	if err != nil {
+		return fmt.Errorf("%s: dial: %w", op, err)
+	}
+	defer sc.Close()
+
+	if err := sc.Send(m.Username, []string{email.To}, msg); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * IsPermanent сообщает, что повторная отправка не поможет: SMTP-сервер
+// * отверг само письмо или получателя (коды 550–554). Остальные коды, включая
+// * ошибки авторизации 530/534/535 и синтаксиса команд, зависят от настроек
+// * отправителя и сервера, поэтому, как и 4xx и сетевые ошибки, считаются временными
+func IsPermanent(err error) bool {
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code >= 550 && smtpErr.Code <= 554
+	}
+
+	return false
 }
@@ -1,7 +1,89 @@
 package models
 
-type EmailMessage struct {
-	Email       string `json:"to"`
-	MessageText string `json:"link"`
-	Subject     string `json:"subject"`
+import (
+	"encoding/json"
+	"time"
+)
+
+type EmailType string
+
+const (
+	TypeVerification  EmailType = "verification"
+	TypePriceAlert    EmailType = "price_alert"
+	TypeBackInStock   EmailType = "back_in_stock"
+	TypeDigest        EmailType = "digest"
+	TypePasswordReset EmailType = "password_reset"
+	TypeEmailChange   EmailType = "email_change"
+)
+
+// * Message — payload сообщения email.requested: тип письма, адресат, язык и
+// * часовой пояс получателя и данные для шаблона. Пустые Locale и Timezone
+// * означают ru и UTC
+type Message struct {
+	Type     EmailType       `json:"type"`
+	To       string          `json:"to"`
+	Locale   string          `json:"locale,omitempty"`
+	Timezone string          `json:"timezone,omitempty"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// * Email — готовое к отправке письмо (multipart/alternative)
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type VerificationData struct {
+	Username string `json:"username"`
+	Link     string `json:"link"`
+}
+
+type PasswordResetData struct {
+	Username  string `json:"username"`
+	Link      string `json:"link"`
+	ExpiresIn int    `json:"expires_in_minutes"`
+}
+
+type EmailChangeData struct {
+	Username  string `json:"username"`
+	Link      string `json:"link"`
+	ExpiresIn int    `json:"expires_in_minutes"`
+}
+
+type PriceAlertData struct {
+	ProductID int64     `json:"product_id"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	AlertType string    `json:"alert_type"`
+	Threshold int       `json:"threshold"`
+	OldPrice  int       `json:"old_price"`
+	NewPrice  int       `json:"new_price"`
+	Currency  string    `json:"currency"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type BackInStockData struct {
+	ProductID int64     `json:"product_id"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	Price     int       `json:"price"`
+	Currency  string    `json:"currency"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type DigestData struct {
+	Username string       `json:"username"`
+	Period   string       `json:"period"`
+	Items    []DigestItem `json:"items"`
+}
+
+type DigestItem struct {
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	OldPrice int    `json:"old_price"`
+	NewPrice int    `json:"new_price"`
+	Currency string `json:"currency"`
+	InStock  bool   `json:"in_stock"`
 }
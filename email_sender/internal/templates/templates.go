@@ -0,0 +1,221 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmlTemplate "html/template"
+	"strconv"
+	"strings"
+	textTemplate "text/template"
+	"time"
+
+	"email_sender/internal/models"
+)
+
+//go:embed files/*/*.tmpl
+var files embed.FS
+
+var ErrUnknownType = errors.New("unknown email type")
+
+// * DefaultLocale используется, если язык получателя не указан или шаблонов для него нет
+const DefaultLocale = "ru"
+
+// * locales — поддерживаемые языки: шаблоны лежат в files/<locale>/
+var locales = map[string]format{
+	"ru": {decimal: ",", group: " ", dateTime: "02.01.2006 15:04 MST"},
+	"en": {decimal: ".", group: ",", dateTime: "Jan 2, 2006 15:04 MST"},
+}
+
+// * format — правила вывода чисел и дат для языка
+type format struct {
+	decimal  string
+	group    string
+	dateTime string
+}
+
+// * dataTypes задаёт структуру данных для каждого типа письма,
+// * чтобы некорректный payload отбрасывался до рендеринга
+var dataTypes = map[models.EmailType]func() any{
+	models.TypeVerification:  func() any { return &models.VerificationData{} },
+	models.TypePriceAlert:    func() any { return &models.PriceAlertData{} },
+	models.TypeBackInStock:   func() any { return &models.BackInStockData{} },
+	models.TypeDigest:        func() any { return &models.DigestData{} },
+	models.TypePasswordReset: func() any { return &models.PasswordResetData{} },
+	models.TypeEmailChange:   func() any { return &models.EmailChangeData{} },
+}
+
+type set struct {
+	text *textTemplate.Template
+	html *htmlTemplate.Template
+}
+
+type Renderer struct {
+	sets map[string]map[models.EmailType]set
+}
+
+// * New разбирает встроенные шаблоны всех типов писем для всех языков
+func New() (*Renderer, error) {
+	const op = "templates.New"
+
+	r := &Renderer{sets: make(map[string]map[models.EmailType]set, len(locales))}
+
+	for locale, f := range locales {
+		// * datetime переопределяется при рендеринге часовым поясом получателя
+		funcs := map[string]any{
+			"price":    f.price,
+			"datetime": func(t time.Time) string { return f.formatTime(t, time.UTC) },
+		}
+
+		r.sets[locale] = make(map[models.EmailType]set, len(dataTypes))
+
+		for emailType := range dataTypes {
+			name := string(emailType)
+			dir := "files/" + locale + "/"
+
+			text, err := textTemplate.New(name).Funcs(funcs).ParseFS(files, dir+name+".txt.tmpl")
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s/%s text: %w", op, locale, name, err)
+			}
+
+			html, err := htmlTemplate.New(name).Funcs(funcs).ParseFS(files, dir+"layout.html.tmpl", dir+name+".html.tmpl")
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s/%s html: %w", op, locale, name, err)
+			}
+
+			r.sets[locale][emailType] = set{text: text, html: html}
+		}
+	}
+
+	return r, nil
+}
+
+// * Render собирает письмо из сообщения: тема и текстовая версия из text/template,
+// * HTML версия из html/template с общим layout. Неизвестный язык заменяется
+// * DefaultLocale, неизвестный часовой пояс — UTC
+func (r *Renderer) Render(msg models.Message) (models.Email, error) {
+	const op = "templates.Render"
+
+	newData, ok := dataTypes[msg.Type]
+	if !ok {
+		return models.Email{}, fmt.Errorf("%s: %w: %q", op, ErrUnknownType, msg.Type)
+	}
+
+	data := newData()
+	if err := json.Unmarshal(msg.Data, data); err != nil {
+		return models.Email{}, fmt.Errorf("%s: decode %s data: %w", op, msg.Type, err)
+	}
+
+	locale := msg.Locale
+	if _, ok := r.sets[locale]; !ok {
+		locale = DefaultLocale
+	}
+
+	s, err := r.sets[locale][msg.Type].forTimezone(locales[locale], location(msg.Timezone))
+	if err != nil {
+		return models.Email{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var subject, text, html bytes.Buffer
+
+	if err := s.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return models.Email{}, fmt.Errorf("%s: subject: %w", op, err)
+	}
+
+	if err := s.text.ExecuteTemplate(&text, "body", data); err != nil {
+		return models.Email{}, fmt.Errorf("%s: text: %w", op, err)
+	}
+
+	if err := s.html.ExecuteTemplate(&html, "layout.html.tmpl", data); err != nil {
+		return models.Email{}, fmt.Errorf("%s: html: %w", op, err)
+	}
+
+	return models.Email{
+		To:      msg.To,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// * forTimezone возвращает копию шаблонов, в которой datetime выводит время в loc
+func (s set) forTimezone(f format, loc *time.Location) (set, error) {
+	funcs := map[string]any{
+		"datetime": func(t time.Time) string { return f.formatTime(t, loc) },
+	}
+
+	text, err := s.text.Clone()
+	if err != nil {
+		return set{}, err
+	}
+
+	html, err := s.html.Clone()
+	if err != nil {
+		return set{}, err
+	}
+
+	return set{text: text.Funcs(funcs), html: html.Funcs(funcs)}, nil
+}
+
+func location(timezone string) *time.Location {
+	if timezone == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// * price форматирует цену по правилам языка: "1 234,56 RUB" или "1,234.56 USD"
+func (f format) price(minorUnits int, currency string) string {
+	sign := ""
+	if minorUnits < 0 {
+		sign = "-"
+		minorUnits = -minorUnits
+	}
+
+	price := fmt.Sprintf("%s%s%s%02d", sign, groupDigits(minorUnits/100, f.group), f.decimal, minorUnits%100)
+	if currency == "" {
+		return price
+	}
+
+	return price + " " + currency
+}
+
+// * formatTime возвращает пустую строку для нулевого времени, чтобы шаблон мог пропустить строку
+func (f format) formatTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.In(loc).Format(f.dateTime)
+}
+
+func groupDigits(n int, sep string) string {
+	digits := strconv.Itoa(n)
+	if len(digits) <= 3 {
+		return digits
+	}
+
+	var b strings.Builder
+
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+
+	return b.String()
+}
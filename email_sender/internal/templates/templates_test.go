@@ -0,0 +1,240 @@
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"email_sender/internal/models"
+)
+
+// * Эталонные письма лежат в testdata/<locale>/<type>.{txt,html}.golden.
+// * После намеренной правки шаблонов эталоны перезаписываются:
+// *
+// *	go test ./internal/templates/ -update
+var update = flag.Bool("update", false, "rewrite golden files")
+
+var checkedAt = time.Date(2026, time.April, 20, 9, 30, 0, 0, time.UTC)
+
+// * samples — данные письма каждого типа с граничными значениями цен
+var samples = map[models.EmailType]any{
+	models.TypeVerification: models.VerificationData{
+		Username: "alice",
+		Link:     "https://example.com/verify?token=abc",
+	},
+	models.TypePasswordReset: models.PasswordResetData{
+		Username:  "alice",
+		Link:      "https://example.com/reset?token=abc",
+		ExpiresIn: 30,
+	},
+	models.TypeEmailChange: models.EmailChangeData{
+		Username:  "alice",
+		Link:      "https://example.com/email?token=abc",
+		ExpiresIn: 60,
+	},
+	models.TypePriceAlert: models.PriceAlertData{
+		ProductID: 1,
+		Title:     "Linen Apron & Pockets",
+		URL:       "https://www.etsy.com/listing/1",
+		AlertType: "percent_drop",
+		Threshold: 10,
+		OldPrice:  123456,
+		NewPrice:  99999,
+		Currency:  "EUR",
+		CheckedAt: checkedAt,
+	},
+	models.TypeBackInStock: models.BackInStockData{
+		ProductID: 2,
+		Title:     "USB-C Cable <2m>",
+		URL:       "https://aliexpress.com/item/2.html",
+		Price:     99,
+		Currency:  "USD",
+		CheckedAt: checkedAt,
+	},
+	models.TypeDigest: models.DigestData{
+		Username: "alice",
+		Period:   "2026-04-13 – 2026-04-20",
+		Items: []models.DigestItem{
+			{Title: "Knitted Wool Scarf", URL: "https://www.etsy.com/listing/3", OldPrice: 129950, NewPrice: 99900, Currency: "GBP", InStock: true},
+			{Title: "Road Bike Helmet", URL: "https://www.ebay.com/itm/4", OldPrice: 4500, NewPrice: 4500, Currency: "CAD", InStock: false},
+		},
+	},
+}
+
+// * timezones — часовой пояс получателя в эталонах каждого языка
+var timezones = map[string]string{
+	"ru": "Europe/Moscow",
+	"en": "America/New_York",
+}
+
+func message(t *testing.T, emailType models.EmailType, locale, timezone string) models.Message {
+	t.Helper()
+
+	data, err := json.Marshal(samples[emailType])
+	if err != nil {
+		t.Fatalf("marshal %s data: %v", emailType, err)
+	}
+
+	return models.Message{Type: emailType, To: "alice@example.com", Locale: locale, Timezone: timezone, Data: data}
+}
+
+func newRenderer(t *testing.T) *Renderer {
+	t.Helper()
+
+	r, err := New()
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	return r
+}
+
+func golden(t *testing.T, path, got string) {
+	t.Helper()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create golden dir: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden (run with -update to create): %v", err)
+	}
+
+	if got != string(want) {
+		t.Errorf("%s mismatch\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+func TestRenderGolden(t *testing.T) {
+	r := newRenderer(t)
+
+	for locale := range locales {
+		for emailType := range dataTypes {
+			t.Run(locale+"/"+string(emailType), func(t *testing.T) {
+				email, err := r.Render(message(t, emailType, locale, timezones[locale]))
+				if err != nil {
+					t.Fatalf("Render: %v", err)
+				}
+
+				if email.To != "alice@example.com" {
+					t.Errorf("To = %q", email.To)
+				}
+
+				dir := filepath.Join("testdata", locale)
+
+				golden(t, filepath.Join(dir, string(emailType)+".txt.golden"), "Subject: "+email.Subject+"\n\n"+email.Text)
+				golden(t, filepath.Join(dir, string(emailType)+".html.golden"), email.HTML)
+			})
+		}
+	}
+}
+
+func TestRenderFallback(t *testing.T) {
+	r := newRenderer(t)
+
+	render := func(locale, timezone string) models.Email {
+		t.Helper()
+
+		email, err := r.Render(message(t, models.TypeBackInStock, locale, timezone))
+		if err != nil {
+			t.Fatalf("Render(%q, %q): %v", locale, timezone, err)
+		}
+
+		return email
+	}
+
+	want := render(DefaultLocale, "UTC")
+
+	tests := []struct {
+		name     string
+		locale   string
+		timezone string
+	}{
+		{name: "empty locale", locale: "", timezone: "UTC"},
+		{name: "unknown locale", locale: "de", timezone: "UTC"},
+		{name: "empty timezone", locale: DefaultLocale, timezone: ""},
+		{name: "unknown timezone", locale: DefaultLocale, timezone: "Mars/Olympus_Mons"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render(tt.locale, tt.timezone); got != want {
+				t.Errorf("Render(%q, %q) = %+v, want %+v", tt.locale, tt.timezone, got, want)
+			}
+		})
+	}
+
+	// * часовой пояс получателя действительно применяется
+	if got := render(DefaultLocale, "Asia/Tokyo"); got.Text == want.Text {
+		t.Error("Asia/Tokyo rendered the same time as UTC")
+	}
+}
+
+func TestRenderUnknownType(t *testing.T) {
+	_, err := newRenderer(t).Render(models.Message{Type: "newsletter", Data: json.RawMessage(`{}`)})
+	if !errors.Is(err, ErrUnknownType) {
+		t.Fatalf("Render error = %v, want %v", err, ErrUnknownType)
+	}
+}
+
+func TestPrice(t *testing.T) {
+	tests := []struct {
+		locale   string
+		amount   int
+		currency string
+		want     string
+	}{
+		{locale: "ru", amount: 123456, currency: "RUB", want: "1\u00a0234,56 RUB"},
+		{locale: "en", amount: 123456, currency: "USD", want: "1,234.56 USD"},
+		{locale: "ru", amount: 0, currency: "RUB", want: "0,00 RUB"},
+		{locale: "en", amount: 5, currency: "USD", want: "0.05 USD"},
+		{locale: "en", amount: 99, currency: "USD", want: "0.99 USD"},
+		{locale: "en", amount: 100, currency: "USD", want: "1.00 USD"},
+		{locale: "ru", amount: -5, currency: "RUB", want: "-0,05 RUB"},
+		{locale: "en", amount: -99, currency: "", want: "-0.99"},
+		{locale: "en", amount: -123456789, currency: "EUR", want: "-1,234,567.89 EUR"},
+		{locale: "ru", amount: 100000000, currency: "", want: "1\u00a0000\u00a0000,00"},
+	}
+
+	for _, tt := range tests {
+		if got := locales[tt.locale].price(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("%s price(%d, %q) = %q, want %q", tt.locale, tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestGroupDigits(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{n: 0, want: "0"},
+		{n: 7, want: "7"},
+		{n: 99, want: "99"},
+		{n: 999, want: "999"},
+		{n: 1000, want: "1,000"},
+		{n: 12345, want: "12,345"},
+		{n: 123456, want: "123,456"},
+		{n: 1234567, want: "1,234,567"},
+	}
+
+	for _, tt := range tests {
+		if got := groupDigits(tt.n, ","); got != tt.want {
+			t.Errorf("groupDigits(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -10,17 +10,39 @@ import (
 	"time"
 
 	"main_service/internal/config"
+	consumerStats "main_service/internal/http-server/handlers/admin/consumer_stats"
+	parserStats "main_service/internal/http-server/handlers/admin/parser_stats"
+	adminProducts "main_service/internal/http-server/handlers/admin/products"
+	"main_service/internal/http-server/handlers/admin/recheck"
+	addAlert "main_service/internal/http-server/handlers/alerts/add"
+	deleteAlert "main_service/internal/http-server/handlers/alerts/delete"
+	getAlerts "main_service/internal/http-server/handlers/alerts/get"
+	updateAlert "main_service/internal/http-server/handlers/alerts/update"
+	exportDownload "main_service/internal/http-server/handlers/export/download"
+	exportRequest "main_service/internal/http-server/handlers/export/request"
+	exportStatus "main_service/internal/http-server/handlers/export/status"
 	addProduct "main_service/internal/http-server/handlers/products/add"
+	backInStock "main_service/internal/http-server/handlers/products/back_in_stock"
 	deleteProduct "main_service/internal/http-server/handlers/products/delete"
 	getProducts "main_service/internal/http-server/handlers/products/get"
 	getByID "main_service/internal/http-server/handlers/products/get_by_id"
+	productHistory "main_service/internal/http-server/handlers/products/history"
+	"main_service/internal/lib/alerts"
+	"main_service/internal/lib/authclient"
+	"main_service/internal/lib/export"
 	"main_service/internal/lib/jwt"
 	"main_service/internal/lib/parser"
+	"main_service/internal/lib/sessions"
+	"main_service/internal/lib/userevents"
 	authMiddlware "main_service/internal/middleware/auth"
+	"main_service/internal/middleware/correlation"
 	"main_service/internal/middleware/products"
-	"main_service/internal/rabbitmq"
+	"main_service/internal/scheduler"
 	"main_service/internal/storage/postgres"
 	"main_service/internal/storage/redis"
+	"messaging/broker"
+	"messaging/envelope"
+	"messaging/outbox"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
@@ -44,7 +66,11 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	jwtParser := jwt.New(cfg.JWTSecret)
+	jwtParser := jwt.New(jwt.NewJWKSCache(
+		cfg.JWKS.URL,
+		&http.Client{Timeout: cfg.JWKS.Timeout},
+		cfg.JWKS.RefreshInterval,
+	))
 
 	redisClient, err := redis.New(ctx, cfg.Redis.Addr, cfg.Redis.Db, cfg.Redis.DefaultTTL)
 	if err != nil {
@@ -72,43 +98,142 @@ func main() {
 		slog.String("database", cfg.Postgres.DBName),
 	)
 
-	rabbitMQClient, err := rabbitmq.New(cfg.RabbitMQ.URL)
+	brokerConn, err := broker.Connect(cfg.RabbitMQ.URL, log)
 	if err != nil {
 		log.Error("failed to connect rabbitMQ", slog.String("err", err.Error()))
 		os.Exit(1)
 	}
-	defer rabbitMQClient.Close()
+	defer brokerConn.Close()
+
+	// * publisher default exchange перекладывает сообщения в очереди повторов и DLQ
+	retryPublisher := broker.NewPublisher(brokerConn, "")
+	defer retryPublisher.Close()
+
+	retry := broker.NewRetryPolicy(retryPublisher, cfg.Retry.MaxAttempts, cfg.Retry.BaseDelay)
+
+	if err := declareTopology(brokerConn, cfg.RabbitMQ, retry); err != nil {
+		log.Error("failed to declare rabbitmq topology", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
 
 	log.Info("rabbitmq connected successfully",
 		slog.Int("workers", cfg.RabbitMQ.WorkerPoolSize),
 	)
 
-	rabbitMQProducer := rabbitmq.NewProducer(
-		rabbitMQClient.Channel,
-		cfg.RabbitMQ.QueueName,
+	publisher := broker.NewPublisher(brokerConn, cfg.RabbitMQ.Exchange)
+	defer publisher.Close()
+
+	rabbitMQProducer := broker.NewProducer(publisher, envelope.TypeParseRequested)
+
+	rabbitMQConsumer := broker.NewConsumer(
+		brokerConn,
+		log,
+		cfg.RabbitMQ.ResultQueueName,
+		cfg.RabbitMQ.WorkerPoolSize,
+		retry,
+		cfg.RabbitMQ.DrainTimeout,
 	)
-	rabbitMQConsumer := rabbitmq.NewConsumer(
-		rabbitMQClient.Channel,
+
+	eventsConsumer := broker.NewConsumer(
+		brokerConn,
 		log,
-		cfg.RabbitMQ.QueueName,
+		cfg.RabbitMQ.EventsQueueName,
 		cfg.RabbitMQ.WorkerPoolSize,
+		retry,
+		cfg.RabbitMQ.DrainTimeout,
+	)
+
+	outboxRelay := outbox.NewRelay(
+		log,
+		postgresClient,
+		publisher,
+		cfg.Outbox.PollInterval,
+		cfg.Outbox.ClaimTimeout,
+		cfg.Outbox.Retention,
+		cfg.Outbox.BatchSize,
+		cfg.Outbox.MaxAttempts,
 	)
 
+	outboxDone := make(chan struct{})
+	go func() {
+		defer close(outboxDone)
+
+		log.Info("starting outbox relay")
+		outboxRelay.Run(ctx)
+	}()
+
 	prodOP := products.New(
 		postgresClient,
 		redisClient,
+		outboxRelay,
+		cfg.CheckInterval,
+	)
+
+	alertEvaluator := alerts.New(log)
+
+	parserClient := parser.New(postgresClient, rabbitMQConsumer, alertEvaluator, outboxRelay)
+
+	parserDone := make(chan struct{})
+	go func() {
+		defer close(parserDone)
+
+		log.Info("starting message parser")
+		if err := parserClient.Run(ctx); err != nil {
+			log.Error("message parser stopped", slog.String("error", err.Error()))
+		}
+	}()
+
+	userEvents := userevents.New(log, postgresClient, redisClient, eventsConsumer)
+
+	userEventsDone := make(chan struct{})
+	go func() {
+		defer close(userEventsDone)
+
+		log.Info("starting user events consumer")
+		if err := userEvents.Run(ctx); err != nil {
+			log.Error("user events consumer stopped", slog.String("error", err.Error()))
+		}
+	}()
+
+	recheckScheduler := scheduler.New(
+		log,
+		postgresClient,
 		rabbitMQProducer,
 		cfg.CheckInterval,
+		cfg.Scheduler.PollInterval,
+		cfg.Scheduler.InFlightTimeout,
+		cfg.Scheduler.BatchSize,
 	)
 
-	parserClient := parser.New(postgresClient, rabbitMQConsumer)
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
 
-	log.Info("starting message parser")
-	if err := parserClient.Run(ctx); err != nil {
-		log.Error("failed to start parser", slog.String("error", err.Error()))
-		os.Exit(1)
-	}
-	log.Info("parser started successfully")
+		log.Info("starting re-check scheduler", slog.Duration("check_interval", cfg.CheckInterval))
+		recheckScheduler.Run(ctx)
+	}()
+
+	exporter := export.New(postgresClient, time.Now)
+
+	exportWorker := export.NewWorker(
+		log,
+		postgresClient,
+		exporter,
+		cfg.Export.PollInterval,
+		cfg.Export.StaleAfter,
+		cfg.Export.ArchiveTTL,
+	)
+
+	exportDone := make(chan struct{})
+	go func() {
+		defer close(exportDone)
+
+		log.Info("starting export worker")
+		exportWorker.Run(ctx)
+	}()
+
+	authClient := authclient.New(cfg.AuthService.URL, &http.Client{Timeout: cfg.AuthService.Timeout})
+	sessionCache := sessions.New(authClient, cfg.AuthService.SessionCacheTTL)
 
 	requestValidator := validator.New()
 
@@ -117,7 +242,15 @@ func main() {
 		requestValidator,
 		postgresClient,
 		prodOP,
+		outboxRelay,
 		jwtParser,
+		authClient,
+		sessionCache,
+		exporter,
+		exportWorker,
+		cfg.Export.InlineMaxProducts,
+		rabbitMQConsumer,
+		eventsConsumer,
 	)
 
 	srv := &http.Server{
@@ -153,6 +286,36 @@ func main() {
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer shutdownCancel()
 
+		select {
+		case <-schedulerDone:
+		case <-shutdownCtx.Done():
+			log.Error("scheduler did not stop in time")
+		}
+
+		select {
+		case <-exportDone:
+		case <-shutdownCtx.Done():
+			log.Error("export worker did not stop in time")
+		}
+
+		select {
+		case <-outboxDone:
+		case <-shutdownCtx.Done():
+			log.Error("outbox relay did not stop in time")
+		}
+
+		select {
+		case <-parserDone:
+		case <-shutdownCtx.Done():
+			log.Error("message parser did not stop in time")
+		}
+
+		select {
+		case <-userEventsDone:
+		case <-shutdownCtx.Done():
+			log.Error("user events consumer did not stop in time")
+		}
+
 		log.Info("shutting down http server")
 
 		if err := srv.Shutdown(shutdownCtx); err != nil {
@@ -167,17 +330,47 @@ func main() {
 	}
 }
 
+// * declareTopology объявляет exchange и очереди, с которыми работает сервис.
+// * Очереди получателей объявляются и здесь: иначе сообщение, опубликованное
+// * до первого запуска получателя, было бы подтверждено брокером и потеряно
+func declareTopology(conn *broker.Connection, cfg config.RabbitMQ, retry *broker.RetryPolicy) error {
+	topology := []broker.Topology{
+		broker.Queue(cfg.Exchange, cfg.QueueName, envelope.TypeParseRequested),
+		broker.Queue(cfg.Exchange, cfg.EmailQueueName, envelope.TypeEmailRequested),
+		broker.Queue(cfg.Exchange, cfg.ResultQueueName, envelope.TypeParsed),
+		broker.Queue(cfg.Exchange, cfg.EventsQueueName, envelope.TypeUserDeleted, envelope.TypeUserUpdated),
+		retry.Topology(cfg.ResultQueueName),
+		retry.Topology(cfg.EventsQueueName),
+	}
+
+	for _, t := range topology {
+		if err := conn.Declare(t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func setupRouter(
 	log *slog.Logger,
 	validate *validator.Validate,
 	postgres *postgres.PostgresRepo,
 	prodOP *products.ProductOperator,
+	outboxRelay *outbox.Relay,
 	jwtParser *jwt.JWTParser,
+	authClient *authclient.Client,
+	sessionCache *sessions.Cache,
+	exporter *export.Exporter,
+	exportWorker *export.Worker,
+	exportInlineMaxProducts int64,
+	consumers ...consumerStats.StatsProvider,
 ) *chi.Mux {
 	r := chi.NewRouter()
 
-	r.Use(authMiddlware.New(log, jwtParser))
+	r.Use(authMiddlware.New(log, jwtParser, sessionCache))
 	r.Use(middleware.RequestID)
+	r.Use(correlation.New())
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
@@ -188,6 +381,34 @@ func setupRouter(
 	r.Get("/products", getProducts.New(log, postgres))
 	r.Get("/product", getByID.New(log, prodOP))
 	r.Delete("/product", deleteProduct.New(log, postgres))
+	r.Get("/product/history", productHistory.New(log, postgres))
+	r.Put("/product/back-in-stock", backInStock.New(log, postgres, validate))
+
+	r.Post("/alert", addAlert.New(log, postgres, validate))
+	r.Get("/alerts", getAlerts.New(log, postgres))
+	r.Patch("/alert", updateAlert.New(log, postgres, validate))
+	r.Delete("/alert", deleteAlert.New(log, postgres))
+
+	r.Get("/me/export", exportRequest.New(
+		log,
+		authClient,
+		postgres,
+		exporter,
+		postgres,
+		exportWorker,
+		exportInlineMaxProducts,
+	))
+	r.Get("/me/export/job", exportStatus.New(log, postgres, validate))
+	r.Get("/me/export/download", exportDownload.New(log, postgres, validate))
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(authMiddlware.RequireRole(log, authMiddlware.RoleAdmin))
+
+		r.Get("/products", adminProducts.New(log, postgres))
+		r.Post("/product/recheck", recheck.New(log, postgres, outboxRelay))
+		r.Get("/parser/stats", parserStats.New(log, postgres))
+		r.Get("/consumers/stats", consumerStats.New(consumers...))
+	})
 
 	return r
 }
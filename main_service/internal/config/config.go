@@ -9,13 +9,18 @@ import (
 )
 
 type Config struct {
-	Env           string        `yaml:"env" env-default:"local"`
-	JWTSecret     string        `yaml:"jwt_secret" env-required:"true"`
+	Env           string `yaml:"env" env-default:"local"`
+	JWKS          `yaml:"jwks"`
 	CheckInterval time.Duration `yaml:"check_interval" env-default:"30m"`
+	Scheduler     `yaml:"scheduler"`
+	Outbox        `yaml:"outbox"`
 	RabbitMQ      `yaml:"rabbitmq"`
+	Retry         `yaml:"retry"`
 	Postgres      `yaml:"postgres"`
 	HTTPServer    `yaml:"http_server"`
 	Redis         `yaml:"redis"`
+	AuthService   `yaml:"auth_service"`
+	Export        `yaml:"export"`
 }
 
 type HTTPServer struct {
@@ -33,10 +38,62 @@ type Postgres struct {
 	SSLMode  string `yaml:"sslmode" env-default:"disabled"`
 }
 
+// * JWKS — публичные ключи auth_service для проверки access токенов
+type JWKS struct {
+	URL             string        `yaml:"url" env-default:"http://auth_service:8082/.well-known/jwks.json"`
+	RefreshInterval time.Duration `yaml:"refresh_interval" env-default:"10m"`
+	Timeout         time.Duration `yaml:"timeout" env-default:"5s"`
+}
+
+// * AuthService — запросы к auth_service от имени пользователя
+type AuthService struct {
+	URL     string        `yaml:"url" env-default:"http://auth_service:8082"`
+	Timeout time.Duration `yaml:"timeout" env-default:"5s"`
+	// * SessionCacheTTL — как долго помнить, что сессия токена не завершена:
+	// * токен отозванной сессии принимается не дольше этого времени
+	SessionCacheTTL time.Duration `yaml:"session_cache_ttl" env-default:"30s"`
+}
+
+// * Export — выгрузка персональных данных: небольшие отдаются сразу,
+// * остальные собираются фоновым обработчиком
+type Export struct {
+	InlineMaxProducts int64         `yaml:"inline_max_products" env-default:"50"`
+	ArchiveTTL        time.Duration `yaml:"archive_ttl" env-default:"24h"`
+	PollInterval      time.Duration `yaml:"poll_interval" env-default:"30s"`
+	StaleAfter        time.Duration `yaml:"stale_after" env-default:"15m"`
+}
+
+type Scheduler struct {
+	PollInterval    time.Duration `yaml:"poll_interval" env-default:"1m"`
+	BatchSize       int           `yaml:"batch_size" env-default:"100"`
+	InFlightTimeout time.Duration `yaml:"in_flight_timeout" env-default:"15m"`
+}
+
+// * Outbox — публикация сообщений, записанных в одной транзакции с данными
+type Outbox struct {
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"5s"`
+	BatchSize    int           `yaml:"batch_size" env-default:"100"`
+	ClaimTimeout time.Duration `yaml:"claim_timeout" env-default:"1m"`
+	Retention    time.Duration `yaml:"retention" env-default:"24h"`
+	MaxAttempts  int           `yaml:"max_attempts" env-default:"10"`
+}
+
 type RabbitMQ struct {
-	URL            string `yaml:"url" env-required:"true"`
-	QueueName      string `yaml:"queue_name" env-default:"parsing_queue"`
-	WorkerPoolSize int    `yaml:"worker_pool_size" env-default:"10"`
+	URL             string        `yaml:"url" env-required:"true"`
+	Exchange        string        `yaml:"exchange" env-default:"price_monitoring"`
+	QueueName       string        `yaml:"queue_name" env-default:"parsing_queue"`
+	ResultQueueName string        `yaml:"result_queue_name" env-default:"parsed_queue"`
+	EmailQueueName  string        `yaml:"email_queue_name" env-default:"msgQueue"`
+	EventsQueueName string        `yaml:"events_queue_name" env-default:"user_events"`
+	WorkerPoolSize  int           `yaml:"worker_pool_size" env-default:"10"`
+	DrainTimeout    time.Duration `yaml:"drain_timeout" env-default:"20s"`
+}
+
+// * Retry — повторная обработка сообщений при временных ошибках:
+// * задержка перед n-й повторной попыткой равна BaseDelay * 2^(n-1)
+type Retry struct {
+	MaxAttempts int           `yaml:"max_attempts" env-default:"5"`
+	BaseDelay   time.Duration `yaml:"base_delay" env-default:"5s"`
 }
 
 type Redis struct {
@@ -0,0 +1,35 @@
+package consumerStats
+
+import (
+	"net/http"
+
+	resp "main_service/internal/lib/api/response"
+	"messaging/broker"
+
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Consumers []broker.Stats `json:"consumers"`
+}
+
+type StatsProvider interface {
+	Stats() broker.Stats
+}
+
+// * New возвращает метрики consumer'ов очередей: обрабатываемые сейчас
+// * сообщения, итоги обработки и время работы обработчиков
+func New(consumers ...StatsProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := make([]broker.Stats, 0, len(consumers))
+		for _, c := range consumers {
+			stats = append(stats, c.Stats())
+		}
+
+		render.JSON(w, r, Response{
+			Response:  resp.OK(),
+			Consumers: stats,
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package parserStats
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	resp "main_service/internal/lib/api/response"
+	sl "main_service/internal/lib/logger"
+	"main_service/internal/models"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+// * failingLimit — сколько продуктов с ошибками парсинга вернуть в ответе
+const failingLimit = 50
+
+type Response struct {
+	resp.Response
+	models.ParserStats
+}
+
+type StatsGetter interface {
+	ParserStats(ctx context.Context, failingLimit int) (models.ParserStats, error)
+}
+
+// * New возвращает статистику ошибок парсинга по маркетплейсам
+func New(
+	log *slog.Logger,
+	statsGetter StatsGetter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.parser_stats.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		stats, err := statsGetter.ParserStats(ctx, failingLimit)
+		if err != nil {
+			log.Error("Failed to get parser stats", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		if stats.Marketplaces == nil {
+			stats.Marketplaces = []models.MarketplaceParseStats{}
+		}
+		if stats.FailingProducts == nil {
+			stats.FailingProducts = []models.FailingProduct{}
+		}
+
+		render.JSON(w, r, Response{
+			Response:    resp.OK(),
+			ParserStats: stats,
+		})
+	}
+}
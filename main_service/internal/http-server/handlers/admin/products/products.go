@@ -0,0 +1,135 @@
+package adminProducts
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	getProducts "main_service/internal/http-server/handlers/products/get"
+	resp "main_service/internal/lib/api/response"
+	sl "main_service/internal/lib/logger"
+	authMiddlware "main_service/internal/middleware/auth"
+	"main_service/internal/models"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultLimit  = 20
+	maxLimit      = 100
+	defaultOffset = 0
+)
+
+type ProductsGetter interface {
+	Products(ctx context.Context, userID, limit, offset int64) ([]models.Product, int64, error)
+}
+
+// * New возвращает продукты произвольного пользователя (?user_id=) для администратора
+func New(
+	log *slog.Logger,
+	productsGetter ProductsGetter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.products.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID := parseUserID(r)
+		if userID == -1 {
+			log.Error("Invalid user_id")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Invalid user_id"))
+
+			return
+		}
+
+		limit := parseLimit(r)
+		offset := parseOffset(r)
+
+		principal, _ := authMiddlware.PrincipalFrom(r.Context())
+
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		products, total, err := productsGetter.Products(ctx, userID, limit, offset)
+		if err != nil {
+			log.Error("Failed to get products",
+				sl.Err(err),
+				slog.Int64("user_id", userID),
+				slog.Int64("limit", limit),
+				slog.Int64("offset", offset),
+			)
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		if products == nil {
+			products = []models.Product{}
+		}
+
+		log.Info("Products retrieved by admin",
+			slog.Int64("admin_id", principal.UserID),
+			slog.Int64("user_id", userID),
+			slog.Int("count", len(products)),
+			slog.Int64("total", total),
+		)
+
+		getProducts.ResponseOK(w, r, products, limit, offset, total)
+	}
+}
+
+func parseUserID(r *http.Request) int64 {
+	userIDStr := r.URL.Query().Get("user_id")
+	if userIDStr == "" {
+		return -1
+	}
+
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil || userID <= 0 {
+		return -1
+	}
+
+	return userID
+}
+
+func parseLimit(r *http.Request) int64 {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return defaultLimit
+	}
+
+	limit, err := strconv.ParseInt(limitStr, 10, 64)
+	if err != nil || limit <= 0 {
+		return defaultLimit
+	}
+
+	if limit > maxLimit {
+		return maxLimit
+	}
+
+	return limit
+}
+
+func parseOffset(r *http.Request) int64 {
+	offsetStr := r.URL.Query().Get("offset")
+	if offsetStr == "" {
+		return defaultOffset
+	}
+
+	offset, err := strconv.ParseInt(offsetStr, 10, 64)
+	if err != nil || offset < 0 {
+		return defaultOffset
+	}
+
+	return offset
+}
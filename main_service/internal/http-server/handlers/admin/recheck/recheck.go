@@ -0,0 +1,101 @@
+package recheck
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	resp "main_service/internal/lib/api/response"
+	sl "main_service/internal/lib/logger"
+	authMiddlware "main_service/internal/middleware/auth"
+	"main_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type ProductQueuer interface {
+	QueueProduct(ctx context.Context, productID int64) error
+}
+
+// * Outbox будит публикацию outbox, не дожидаясь очередного опроса
+type Outbox interface {
+	Trigger()
+}
+
+// * New ставит продукт (?id=) в очередь на парсинг вне расписания. Задание
+// * уходит через outbox: пока брокер недоступен, оно ждёт в базе
+func New(
+	log *slog.Logger,
+	queuer ProductQueuer,
+	outbox Outbox,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.recheck.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		productID := parseProductID(r)
+		if productID == -1 {
+			log.Error("Invalid id")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Invalid id"))
+
+			return
+		}
+
+		principal, _ := authMiddlware.PrincipalFrom(r.Context())
+
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		if err := queuer.QueueProduct(ctx, productID); err != nil {
+			if errors.Is(err, storage.ErrProductsNotFound) {
+				log.Warn("Product not found", slog.Int64("product_id", productID))
+
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("Product not found"))
+
+				return
+			}
+
+			log.Error("Failed to queue product", sl.Err(err), slog.Int64("product_id", productID))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		outbox.Trigger()
+
+		log.Info("Product queued for re-check by admin",
+			slog.Int64("admin_id", principal.UserID),
+			slog.Int64("product_id", productID),
+		)
+
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, resp.OK())
+	}
+}
+
+func parseProductID(r *http.Request) int64 {
+	productIDStr := r.URL.Query().Get("id")
+	if productIDStr == "" {
+		return -1
+	}
+
+	productID, err := strconv.ParseInt(productIDStr, 10, 64)
+	if err != nil || productID < 0 {
+		return -1
+	}
+
+	return productID
+}
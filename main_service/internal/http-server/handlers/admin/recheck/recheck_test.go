@@ -0,0 +1,80 @@
+package recheck_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"main_service/internal/http-server/handlers/admin/recheck"
+	"main_service/internal/storage"
+)
+
+// * fakeQueuer ставит в очередь только существующий продукт 1
+type fakeQueuer struct {
+	queued []int64
+	err    error
+}
+
+func (q *fakeQueuer) QueueProduct(_ context.Context, productID int64) error {
+	if q.err != nil {
+		return q.err
+	}
+
+	if productID != 1 {
+		return storage.ErrProductsNotFound
+	}
+
+	q.queued = append(q.queued, productID)
+
+	return nil
+}
+
+type fakeOutbox struct {
+	triggered int
+}
+
+func (o *fakeOutbox) Trigger() {
+	o.triggered++
+}
+
+func TestRecheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		queueErr      error
+		wantCode      int
+		wantTriggered int
+	}{
+		{name: "queued", query: "id=1", wantCode: http.StatusAccepted, wantTriggered: 1},
+		{name: "missing product", query: "id=2", wantCode: http.StatusNotFound},
+		{name: "invalid id", query: "id=abc", wantCode: http.StatusBadRequest},
+		{name: "storage failure", query: "id=1", queueErr: errors.New("db down"), wantCode: http.StatusInternalServerError},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queuer := &fakeQueuer{err: tt.queueErr}
+			outbox := &fakeOutbox{}
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/product/recheck?"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			recheck.New(log, queuer, outbox).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+
+			// * задание публикует только outbox: без сохранённого задания будить его незачем
+			if outbox.triggered != tt.wantTriggered {
+				t.Errorf("outbox triggered %d times, want %d", outbox.triggered, tt.wantTriggered)
+			}
+		})
+	}
+}
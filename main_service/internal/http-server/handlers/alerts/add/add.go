@@ -0,0 +1,182 @@
+package addAlert
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	resp "main_service/internal/lib/api/response"
+	sl "main_service/internal/lib/logger"
+	authMiddlware "main_service/internal/middleware/auth"
+	"main_service/internal/models"
+	"main_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	validator "github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	ProductID int64            `json:"product_id" validate:"required,gt=0"`
+	Type      models.AlertType `json:"type" validate:"required,oneof=price_below percent_drop below_30d_min"`
+	Threshold int              `json:"threshold" validate:"gte=0"`
+}
+
+type Response struct {
+	resp.Response
+	AlertID int64 `json:"alert_id"`
+}
+
+type AlertSaver interface {
+	SaveUserContact(ctx context.Context, userID int64, email string) error
+	SaveAlertRule(ctx context.Context, userID, productID int64, alertType models.AlertType, threshold int) (int64, error)
+}
+
+func New(
+	log *slog.Logger,
+	alertSaver AlertSaver,
+	validate *validator.Validate,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.alerts.add.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // * 1 МБ лимит запроса
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("Failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Failed to decode request"))
+
+			return
+		}
+
+		log.Info("Request body decoded")
+
+		if err := validate.Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("Invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		if msg := ValidateThreshold(req.Type, req.Threshold); msg != "" {
+			log.Error("Invalid threshold", slog.Int("threshold", req.Threshold))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(msg))
+
+			return
+		}
+
+		userID, ok := r.Context().Value(authMiddlware.UserIDKey).(int64)
+		if !ok {
+			log.Error("User ID not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		if userID <= 0 {
+			log.Error("Invalid user ID", slog.Int64("user_id", userID))
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		email, _ := r.Context().Value(authMiddlware.EmailKey).(string)
+		if email == "" {
+			log.Error("Email not found in token", slog.Int64("user_id", userID))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Email is required for alerts"))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		if err := alertSaver.SaveUserContact(ctx, userID, email); err != nil {
+			log.Error("Failed to save user contact", sl.Err(err), slog.Int64("user_id", userID))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		alertID, err := alertSaver.SaveAlertRule(ctx, userID, req.ProductID, req.Type, req.Threshold)
+		if err != nil {
+			if errors.Is(err, storage.ErrProductsNotFound) {
+				log.Warn("Product not found", slog.Int64("product_id", req.ProductID))
+
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("Product not found"))
+
+				return
+			}
+
+			log.Error("Failed to save alert rule", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		log.Info("Alert rule saved successfully",
+			slog.Int64("alert_id", alertID),
+			slog.Int64("product_id", req.ProductID),
+			slog.Int64("user_id", userID),
+		)
+
+		render.Status(r, http.StatusCreated)
+		ResponseOK(w, r, alertID)
+	}
+}
+
+// * ValidateThreshold проверяет порог для типа правила: цена в минимальных
+// * единицах валюты для price_below, проценты для percent_drop
+func ValidateThreshold(alertType models.AlertType, threshold int) string {
+	switch alertType {
+	case models.AlertPriceBelow:
+		if threshold <= 0 {
+			return "Threshold must be a positive price"
+		}
+	case models.AlertPercentDrop:
+		if threshold < 1 || threshold > 99 {
+			return "Threshold must be a percentage between 1 and 99"
+		}
+	case models.AlertBelow30dMin:
+		if threshold != 0 {
+			return "Threshold is not used for below_30d_min"
+		}
+	}
+
+	return ""
+}
+
+func ResponseOK(w http.ResponseWriter, r *http.Request, id int64) {
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+		AlertID:  id,
+	})
+}
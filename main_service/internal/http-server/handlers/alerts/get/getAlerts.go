@@ -0,0 +1,119 @@
+package getAlerts
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	resp "main_service/internal/lib/api/response"
+	sl "main_service/internal/lib/logger"
+	authMiddlware "main_service/internal/middleware/auth"
+	"main_service/internal/models"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Alerts []models.AlertRule `json:"alerts"`
+}
+
+type AlertsGetter interface {
+	AlertRules(ctx context.Context, userID, productID int64) ([]models.AlertRule, error)
+}
+
+func New(
+	log *slog.Logger,
+	alertsGetter AlertsGetter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.alerts.get.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		productID := parseProductID(r)
+		if productID == -1 {
+			log.Error("Invalid product_id")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Invalid product_id"))
+
+			return
+		}
+
+		userID, ok := r.Context().Value(authMiddlware.UserIDKey).(int64)
+		if !ok {
+			log.Error("User ID not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		if userID <= 0 {
+			log.Error("Invalid user ID", slog.Int64("user_id", userID))
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		alerts, err := alertsGetter.AlertRules(ctx, userID, productID)
+		if err != nil {
+			log.Error("Failed to get alert rules",
+				sl.Err(err),
+				slog.Int64("user_id", userID),
+				slog.Int64("product_id", productID),
+			)
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		if alerts == nil {
+			alerts = []models.AlertRule{}
+		}
+
+		log.Info("Alert rules got successfully",
+			slog.Int64("user_id", userID),
+			slog.Int("count", len(alerts)),
+		)
+
+		ResponseOK(w, r, alerts)
+	}
+}
+
+func ResponseOK(w http.ResponseWriter, r *http.Request, alerts []models.AlertRule) {
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+		Alerts:   alerts,
+	})
+}
+
+// * parseProductID возвращает 0, если фильтр по продукту не задан
+func parseProductID(r *http.Request) int64 {
+	productIDStr := r.URL.Query().Get("product_id")
+	if productIDStr == "" {
+		return 0
+	}
+
+	productID, err := strconv.ParseInt(productIDStr, 10, 64)
+	if err != nil || productID <= 0 {
+		return -1
+	}
+
+	return productID
+}
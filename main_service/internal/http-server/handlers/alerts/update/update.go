@@ -0,0 +1,176 @@
+package updateAlert
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	addAlert "main_service/internal/http-server/handlers/alerts/add"
+	resp "main_service/internal/lib/api/response"
+	sl "main_service/internal/lib/logger"
+	authMiddlware "main_service/internal/middleware/auth"
+	"main_service/internal/models"
+	"main_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	validator "github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	Threshold int `json:"threshold" validate:"gte=0"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+type AlertUpdater interface {
+	AlertRule(ctx context.Context, ruleID, userID int64) (models.AlertRule, error)
+	UpdateAlertRule(ctx context.Context, ruleID, userID int64, threshold int) error
+}
+
+func New(
+	log *slog.Logger,
+	alertUpdater AlertUpdater,
+	validate *validator.Validate,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.alerts.update.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alertID := parseAlertID(r)
+		if alertID == -1 {
+			log.Error("Invalid id")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Invalid id"))
+
+			return
+		}
+
+		var req Request
+
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // * 1 МБ лимит запроса
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("Failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Failed to decode request"))
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("Invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		userID, ok := r.Context().Value(authMiddlware.UserIDKey).(int64)
+		if !ok {
+			log.Error("User ID not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		if userID <= 0 {
+			log.Error("Invalid user ID", slog.Int64("user_id", userID))
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		rule, err := alertUpdater.AlertRule(ctx, alertID, userID)
+		if err != nil {
+			if errors.Is(err, storage.ErrAlertRuleNotFound) {
+				log.Warn("Alert rule not found", slog.Int64("alert_id", alertID))
+
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("Alert not found"))
+
+				return
+			}
+
+			log.Error("Failed to get alert rule", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		if msg := addAlert.ValidateThreshold(rule.Type, req.Threshold); msg != "" {
+			log.Error("Invalid threshold", slog.Int("threshold", req.Threshold))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(msg))
+
+			return
+		}
+
+		if err := alertUpdater.UpdateAlertRule(ctx, alertID, userID, req.Threshold); err != nil {
+			if errors.Is(err, storage.ErrAlertRuleNotFound) {
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("Alert not found"))
+
+				return
+			}
+
+			log.Error("Failed to update alert rule", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		log.Info("Alert rule updated successfully",
+			slog.Int64("alert_id", alertID),
+			slog.Int64("user_id", userID),
+		)
+
+		ResponseOK(w, r)
+	}
+}
+
+func ResponseOK(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+	})
+}
+
+func parseAlertID(r *http.Request) int64 {
+	alertIDStr := r.URL.Query().Get("id")
+	if alertIDStr == "" {
+		return -1
+	}
+
+	alertID, err := strconv.ParseInt(alertIDStr, 10, 64)
+	if err != nil || alertID < 0 {
+		return -1
+	}
+
+	return alertID
+}
@@ -0,0 +1,85 @@
+package exportDownload
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	exportRequest "main_service/internal/http-server/handlers/export/request"
+	resp "main_service/internal/lib/api/response"
+	sl "main_service/internal/lib/logger"
+	authMiddlware "main_service/internal/middleware/auth"
+	"main_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type ArchiveGetter interface {
+	ExportArchive(ctx context.Context, jobID string, userID int64) ([]byte, error)
+}
+
+// * New отдаёт архив завершённой фоновой выгрузки (?id=)
+func New(
+	log *slog.Logger,
+	archives ArchiveGetter,
+	validate *validator.Validate,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.export.download.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		jobID := r.URL.Query().Get("id")
+		if err := validate.Var(jobID, "required,uuid"); err != nil {
+			log.Error("Invalid id")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Invalid id"))
+
+			return
+		}
+
+		userID, ok := r.Context().Value(authMiddlware.UserIDKey).(int64)
+		if !ok || userID <= 0 {
+			log.Error("User ID not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		archive, err := archives.ExportArchive(ctx, jobID, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrExportJobNotFound):
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("Export not found"))
+			case errors.Is(err, storage.ErrExportNotReady):
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, resp.Error("Export is not ready"))
+			default:
+				log.Error("Failed to get export archive", sl.Err(err), slog.String("job_id", jobID))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("Internal error"))
+			}
+
+			return
+		}
+
+		log.Info("Export downloaded", slog.Int64("user_id", userID), slog.String("job_id", jobID))
+
+		exportRequest.WriteArchive(w, archive, "export-"+jobID+".zip")
+	}
+}
@@ -0,0 +1,160 @@
+package exportRequest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	resp "main_service/internal/lib/api/response"
+	"main_service/internal/lib/authclient"
+	sl "main_service/internal/lib/logger"
+	authMiddlware "main_service/internal/middleware/auth"
+	"main_service/internal/models"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type Response struct {
+	resp.Response
+	Job         models.ExportJob `json:"job"`
+	StatusURL   string           `json:"status_url"`
+	DownloadURL string           `json:"download_url"`
+}
+
+type AccountFetcher interface {
+	Account(ctx context.Context, authHeader string) (json.RawMessage, error)
+}
+
+type ProductsCounter interface {
+	Products(ctx context.Context, userID, limit, offset int64) ([]models.Product, int64, error)
+}
+
+type ArchiveBuilder interface {
+	Build(ctx context.Context, userID int64, account json.RawMessage) ([]byte, error)
+}
+
+type JobCreator interface {
+	CreateExportJob(ctx context.Context, userID int64, account []byte) (models.ExportJob, error)
+}
+
+type WorkerTrigger interface {
+	Trigger()
+}
+
+// * New выгружает все данные пользователя ZIP архивом. Если продуктов больше
+// * inlineMaxProducts, создаётся фоновое задание и возвращается 202 со ссылками
+// * на статус и скачивание
+func New(
+	log *slog.Logger,
+	auth AccountFetcher,
+	products ProductsCounter,
+	builder ArchiveBuilder,
+	jobs JobCreator,
+	worker WorkerTrigger,
+	inlineMaxProducts int64,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.export.request.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, ok := r.Context().Value(authMiddlware.UserIDKey).(int64)
+		if !ok || userID <= 0 {
+			log.Error("User ID not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+		defer cancel()
+
+		// * данные аккаунта запрашиваются сразу: к запуску фонового задания
+		// * access токен пользователя может истечь
+		account, err := auth.Account(ctx, r.Header.Get("Authorization"))
+		if err != nil {
+			if errors.Is(err, authclient.ErrUnauthorized) {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("Unauthorized"))
+
+				return
+			}
+
+			log.Error("Failed to get account from auth service", sl.Err(err), slog.Int64("user_id", userID))
+
+			render.Status(r, http.StatusBadGateway)
+			render.JSON(w, r, resp.Error("Auth service unavailable"))
+
+			return
+		}
+
+		_, total, err := products.Products(ctx, userID, 1, 0)
+		if err != nil {
+			log.Error("Failed to count products", sl.Err(err), slog.Int64("user_id", userID))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		if total > inlineMaxProducts {
+			job, err := jobs.CreateExportJob(ctx, userID, account)
+			if err != nil {
+				log.Error("Failed to create export job", sl.Err(err), slog.Int64("user_id", userID))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("Internal error"))
+
+				return
+			}
+
+			worker.Trigger()
+
+			log.Info("Export job created", slog.Int64("user_id", userID), slog.String("job_id", job.ID))
+
+			render.Status(r, http.StatusAccepted)
+			render.JSON(w, r, Response{
+				Response:    resp.OK(),
+				Job:         job,
+				StatusURL:   "/me/export/job?id=" + job.ID,
+				DownloadURL: "/me/export/download?id=" + job.ID,
+			})
+
+			return
+		}
+
+		archive, err := builder.Build(ctx, userID, account)
+		if err != nil {
+			log.Error("Failed to build export", sl.Err(err), slog.Int64("user_id", userID))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		log.Info("Export built", slog.Int64("user_id", userID), slog.Int("size", len(archive)))
+
+		WriteArchive(w, archive, fmt.Sprintf("export-%d.zip", userID))
+	}
+}
+
+// * WriteArchive отдаёт ZIP архив как вложение
+func WriteArchive(w http.ResponseWriter, archive []byte, filename string) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(archive)
+}
@@ -0,0 +1,96 @@
+package exportStatus
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	resp "main_service/internal/lib/api/response"
+	sl "main_service/internal/lib/logger"
+	authMiddlware "main_service/internal/middleware/auth"
+	"main_service/internal/models"
+	"main_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type Response struct {
+	resp.Response
+	Job         models.ExportJob `json:"job"`
+	DownloadURL string           `json:"download_url,omitempty"`
+}
+
+type JobGetter interface {
+	ExportJob(ctx context.Context, jobID string, userID int64) (models.ExportJob, error)
+}
+
+// * New возвращает статус фоновой выгрузки (?id=) и ссылку на архив, когда он готов
+func New(
+	log *slog.Logger,
+	jobs JobGetter,
+	validate *validator.Validate,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.export.status.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		jobID := r.URL.Query().Get("id")
+		if err := validate.Var(jobID, "required,uuid"); err != nil {
+			log.Error("Invalid id")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Invalid id"))
+
+			return
+		}
+
+		userID, ok := r.Context().Value(authMiddlware.UserIDKey).(int64)
+		if !ok || userID <= 0 {
+			log.Error("User ID not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		job, err := jobs.ExportJob(ctx, jobID, userID)
+		if err != nil {
+			if errors.Is(err, storage.ErrExportJobNotFound) {
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("Export not found"))
+
+				return
+			}
+
+			log.Error("Failed to get export job", sl.Err(err), slog.String("job_id", jobID))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		response := Response{
+			Response: resp.OK(),
+			Job:      job,
+		}
+
+		if job.Status == models.ExportDone {
+			response.DownloadURL = "/me/export/download?id=" + job.ID
+		}
+
+		render.JSON(w, r, response)
+	}
+}
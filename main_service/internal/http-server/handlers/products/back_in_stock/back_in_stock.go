@@ -0,0 +1,147 @@
+package backInStock
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	resp "main_service/internal/lib/api/response"
+	sl "main_service/internal/lib/logger"
+	authMiddlware "main_service/internal/middleware/auth"
+	"main_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	validator "github.com/go-playground/validator/v10"
+)
+
+type Request struct {
+	ProductID int64 `json:"product_id" validate:"required,gt=0"`
+	Enabled   *bool `json:"enabled" validate:"required"`
+}
+
+type Response struct {
+	resp.Response
+}
+
+type SubscriptionSaver interface {
+	SaveUserContact(ctx context.Context, userID int64, email string) error
+	SetBackInStockNotification(ctx context.Context, productID, userID int64, enabled bool) error
+}
+
+func New(
+	log *slog.Logger,
+	subscriptionSaver SubscriptionSaver,
+	validate *validator.Validate,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.products.back_in_stock.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // * 1 МБ лимит запроса
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("Failed to decode request body", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Failed to decode request"))
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			validateErr := err.(validator.ValidationErrors)
+
+			log.Error("Invalid request", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.ValidationError(validateErr))
+
+			return
+		}
+
+		userID, ok := r.Context().Value(authMiddlware.UserIDKey).(int64)
+		if !ok {
+			log.Error("User ID not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		if userID <= 0 {
+			log.Error("Invalid user ID", slog.Int64("user_id", userID))
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		if *req.Enabled {
+			email, _ := r.Context().Value(authMiddlware.EmailKey).(string)
+			if email == "" {
+				log.Error("Email not found in token", slog.Int64("user_id", userID))
+
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, resp.Error("Email is required for notifications"))
+
+				return
+			}
+
+			if err := subscriptionSaver.SaveUserContact(ctx, userID, email); err != nil {
+				log.Error("Failed to save user contact", sl.Err(err), slog.Int64("user_id", userID))
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("Internal error"))
+
+				return
+			}
+		}
+
+		err = subscriptionSaver.SetBackInStockNotification(ctx, req.ProductID, userID, *req.Enabled)
+		if err != nil {
+			if errors.Is(err, storage.ErrProductsNotFound) {
+				log.Warn("Product not found", slog.Int64("product_id", req.ProductID))
+
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("Product not found"))
+
+				return
+			}
+
+			log.Error("Failed to update back in stock notification", sl.Err(err))
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		log.Info("Back in stock notification updated",
+			slog.Int64("product_id", req.ProductID),
+			slog.Int64("user_id", userID),
+			slog.Bool("enabled", *req.Enabled),
+		)
+
+		ResponseOK(w, r)
+	}
+}
+
+func ResponseOK(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, Response{
+		Response: resp.OK(),
+	})
+}
@@ -0,0 +1,209 @@
+package productHistory
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	resp "main_service/internal/lib/api/response"
+	sl "main_service/internal/lib/logger"
+	authMiddlware "main_service/internal/middleware/auth"
+	"main_service/internal/models"
+	"main_service/internal/storage"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultPeriod = 30 * 24 * time.Hour
+	maxPeriod     = 366 * 24 * time.Hour
+)
+
+type Response struct {
+	resp.Response
+	ProductID int64               `json:"product_id"`
+	From      time.Time           `json:"from"`
+	To        time.Time           `json:"to"`
+	History   []models.PricePoint `json:"history"`
+}
+
+type HistoryGetter interface {
+	PriceHistory(ctx context.Context, productID, userID int64, from, to time.Time) ([]models.PricePoint, error)
+}
+
+func New(
+	log *slog.Logger,
+	historyGetter HistoryGetter,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.products.history.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		productID := parseProductID(r)
+		if productID == -1 {
+			log.Error("Invalid id")
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("Invalid id"))
+
+			return
+		}
+
+		from, to, err := parsePeriod(r)
+		if err != nil {
+			log.Error("Invalid period", sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(err.Error()))
+
+			return
+		}
+
+		userID, ok := r.Context().Value(authMiddlware.UserIDKey).(int64)
+		if !ok {
+			log.Error("User ID not found in context")
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		if userID <= 0 {
+			log.Error("Invalid user ID", slog.Int64("user_id", userID))
+
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("Unauthorized"))
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		history, err := historyGetter.PriceHistory(ctx, productID, userID, from, to)
+		if err != nil {
+			if errors.Is(err, storage.ErrProductsNotFound) {
+				log.Warn("Product not found",
+					slog.Int64("user_id", userID),
+					slog.Int64("productID", productID),
+				)
+
+				render.Status(r, http.StatusNotFound)
+				render.JSON(w, r, resp.Error("Product not found"))
+
+				return
+			}
+
+			log.Error("Failed to get price history",
+				sl.Err(err),
+				slog.Int64("user_id", userID),
+				slog.Int64("productID", productID),
+			)
+
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("Internal error"))
+
+			return
+		}
+
+		if history == nil {
+			history = []models.PricePoint{}
+		}
+
+		log.Info("Price history got successfully",
+			slog.Int64("user_id", userID),
+			slog.Int64("product_id", productID),
+			slog.Int("points", len(history)),
+		)
+
+		ResponseOK(w, r, productID, from, to, history)
+	}
+}
+
+func ResponseOK(
+	w http.ResponseWriter,
+	r *http.Request,
+	productID int64,
+	from, to time.Time,
+	history []models.PricePoint,
+) {
+	render.JSON(w, r, Response{
+		Response:  resp.OK(),
+		ProductID: productID,
+		From:      from,
+		To:        to,
+		History:   history,
+	})
+}
+
+func parseProductID(r *http.Request) int64 {
+	productIDStr := r.URL.Query().Get("id")
+	if productIDStr == "" {
+		return -1
+	}
+
+	productID, err := strconv.ParseInt(productIDStr, 10, 64)
+	if err != nil || productID < 0 {
+		return -1
+	}
+
+	return productID
+}
+
+// * parsePeriod читает from и to в формате RFC3339 или YYYY-MM-DD и возвращает
+// * полуинтервал [from, to). Дата без времени в to включает весь этот день:
+// * граница сдвигается на полночь следующего. По умолчанию возвращаются
+// * последние 30 дней
+func parsePeriod(r *http.Request) (time.Time, time.Time, error) {
+	to := time.Now().UTC()
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, dateOnly, err := parseTime(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("Invalid to")
+		}
+
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		to = t
+	}
+
+	from := to.Add(-defaultPeriod)
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, _, err := parseTime(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("Invalid from")
+		}
+		from = t
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, errors.New("from must be before to")
+	}
+
+	if to.Sub(from) > maxPeriod {
+		return time.Time{}, time.Time{}, errors.New("Period must not exceed 366 days")
+	}
+
+	return from, to, nil
+}
+
+// * parseTime сообщает, была ли передана только дата
+func parseTime(v string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, false, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, v)
+
+	return t, true, err
+}
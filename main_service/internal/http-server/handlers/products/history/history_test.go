@@ -0,0 +1,103 @@
+package productHistory_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	productHistory "main_service/internal/http-server/handlers/products/history"
+	authMiddlware "main_service/internal/middleware/auth"
+	"main_service/internal/models"
+)
+
+// * fakeHistory запоминает период, с которым обработчик запросил историю
+type fakeHistory struct {
+	from, to time.Time
+}
+
+func (h *fakeHistory) PriceHistory(_ context.Context, _, _ int64, from, to time.Time) ([]models.PricePoint, error) {
+	h.from, h.to = from, to
+	return nil, nil
+}
+
+func TestHistoryPeriod(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, time.October, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{
+			name:     "date-only from and to on the same day",
+			query:    "from=2026-10-17&to=2026-10-17",
+			wantCode: http.StatusOK,
+			wantFrom: day(17),
+			wantTo:   day(18),
+		},
+		{
+			name:     "date-only to includes the last day",
+			query:    "from=2026-10-01&to=2026-10-17",
+			wantCode: http.StatusOK,
+			wantFrom: day(1),
+			wantTo:   day(18),
+		},
+		{
+			name:     "RFC3339 bounds are kept as is",
+			query:    "from=2026-10-17T00:00:00Z&to=2026-10-17T12:30:00Z",
+			wantCode: http.StatusOK,
+			wantFrom: day(17),
+			wantTo:   day(17).Add(12*time.Hour + 30*time.Minute),
+		},
+		{
+			name:     "date-only to with RFC3339 from",
+			query:    "from=2026-10-17T08:00:00Z&to=2026-10-17",
+			wantCode: http.StatusOK,
+			wantFrom: day(17).Add(8 * time.Hour),
+			wantTo:   day(18),
+		},
+		{
+			name:     "from after to",
+			query:    "from=2026-10-18&to=2026-10-17",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid to",
+			query:    "to=17.10.2026",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getter := &fakeHistory{}
+			h := productHistory.New(log, getter)
+
+			req := httptest.NewRequest(http.MethodGet, "/products/history?id=1&"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), authMiddlware.UserIDKey, int64(1)))
+			rec := httptest.NewRecorder()
+
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			if !getter.from.Equal(tt.wantFrom) || !getter.to.Equal(tt.wantTo) {
+				t.Errorf("period = [%s, %s), want [%s, %s)", getter.from, getter.to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
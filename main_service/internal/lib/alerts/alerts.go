@@ -0,0 +1,106 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"main_service/internal/models"
+	"main_service/internal/storage"
+)
+
+// * Evaluator проверяет правила оповещений после обновления цены продукта.
+// * Сработавшее правило не срабатывает повторно, пока условие не перестанет выполняться.
+// * Проверка идёт в транзакции, сохраняющей результат парсинга: сбой откатывает
+// * результат вместе со срабатываниями, и повторная доставка проверит правила снова
+type Evaluator struct {
+	log *slog.Logger
+}
+
+func New(log *slog.Logger) *Evaluator {
+	return &Evaluator{log: log}
+}
+
+// * Evaluate подходит как storage.EvaluateAlerts: rules работает в транзакции
+// * UpdateParsedData. Возвращает true, если поставлено хотя бы одно письмо
+func (e *Evaluator) Evaluate(ctx context.Context, rules storage.AlertRules, update models.PriceUpdate) (bool, error) {
+	const op = "alerts.Evaluate"
+
+	productRules, err := rules.ProductAlertRules(ctx, update.ProductID)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	notified := false
+
+	for _, rule := range productRules {
+		matched := Matches(rule, update)
+
+		if matched == rule.Triggered {
+			continue
+		}
+
+		if !matched {
+			if _, err := rules.ResetAlertTriggered(ctx, rule.ID); err != nil {
+				return false, fmt.Errorf("%s: %w", op, err)
+			}
+			continue
+		}
+
+		// * письмо ставит только тот, кто изменил флаг
+		changed, err := rules.TriggerAlertRule(ctx, rule, priceAlertData(rule, update))
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if changed {
+			notified = true
+
+			e.log.Info("price alert triggered",
+				slog.String("op", op),
+				slog.Int64("rule_id", rule.ID),
+				slog.Int64("product_id", update.ProductID),
+			)
+		}
+	}
+
+	return notified, nil
+}
+
+// * Matches сообщает, выполняется ли условие правила для нового наблюдения.
+// * Для отсутствующего товара ценовые условия считаются невыполненными
+func Matches(rule models.AlertRule, u models.PriceUpdate) bool {
+	if !u.NewInStock || u.NewPrice < 0 {
+		return false
+	}
+
+	switch rule.Type {
+	case models.AlertPriceBelow:
+		return u.NewPrice <= rule.Threshold
+
+	case models.AlertPercentDrop:
+		if !u.WasChecked || u.OldPrice <= 0 || u.NewPrice >= u.OldPrice {
+			return false
+		}
+		return (u.OldPrice-u.NewPrice)*100 >= rule.Threshold*u.OldPrice
+
+	case models.AlertBelow30dMin:
+		return u.MinPrice30d != nil && u.NewPrice < *u.MinPrice30d
+	}
+
+	return false
+}
+
+func priceAlertData(rule models.AlertRule, u models.PriceUpdate) models.PriceAlertData {
+	return models.PriceAlertData{
+		ProductID: u.ProductID,
+		Title:     u.Title,
+		URL:       u.URL,
+		AlertType: rule.Type,
+		Threshold: rule.Threshold,
+		OldPrice:  u.OldPrice,
+		NewPrice:  u.NewPrice,
+		Currency:  u.Currency,
+		CheckedAt: u.CheckedAt,
+	}
+}
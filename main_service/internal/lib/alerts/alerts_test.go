@@ -0,0 +1,102 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"main_service/internal/models"
+)
+
+// * fakeRules хранит флаги срабатывания правил; triggerErr имитирует сбой транзакции
+type fakeRules struct {
+	rules      []models.AlertRule
+	triggered  map[int64]bool
+	emails     []int64
+	triggerErr error
+}
+
+func (s *fakeRules) ProductAlertRules(context.Context, int64) ([]models.AlertRule, error) {
+	return s.rules, nil
+}
+
+func (s *fakeRules) TriggerAlertRule(_ context.Context, rule models.AlertRule, _ models.PriceAlertData) (bool, error) {
+	if s.triggerErr != nil {
+		return false, s.triggerErr
+	}
+
+	if s.triggered[rule.ID] {
+		return false, nil
+	}
+
+	s.triggered[rule.ID] = true
+	s.emails = append(s.emails, rule.ID)
+
+	return true, nil
+}
+
+func (s *fakeRules) ResetAlertTriggered(_ context.Context, ruleID int64) (bool, error) {
+	changed := s.triggered[ruleID]
+	s.triggered[ruleID] = false
+
+	return changed, nil
+}
+
+func newEvaluator() *Evaluator {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestEvaluateTriggersMatchedRules(t *testing.T) {
+	rules := &fakeRules{
+		rules: []models.AlertRule{
+			{ID: 1, Type: models.AlertPriceBelow, Threshold: 1000},
+			{ID: 2, Type: models.AlertPriceBelow, Threshold: 500},
+			{ID: 3, Type: models.AlertPriceBelow, Threshold: 500, Triggered: true},
+		},
+		triggered: map[int64]bool{3: true},
+	}
+
+	update := models.PriceUpdate{ProductID: 10, NewPrice: 900, NewInStock: true}
+
+	notified, err := newEvaluator().Evaluate(context.Background(), rules, update)
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+
+	if !notified {
+		t.Error("Evaluate reported no emails")
+	}
+
+	if len(rules.emails) != 1 || rules.emails[0] != 1 {
+		t.Errorf("emails for rules %v, want [1]", rules.emails)
+	}
+
+	// * условие правила 3 больше не выполняется: оно снова может сработать
+	if rules.triggered[3] {
+		t.Error("rule 3 is still triggered")
+	}
+}
+
+func TestEvaluateReturnsTriggerError(t *testing.T) {
+	errTx := errors.New("connection reset")
+
+	rules := &fakeRules{
+		rules:      []models.AlertRule{{ID: 1, Type: models.AlertPriceBelow, Threshold: 1000}},
+		triggered:  map[int64]bool{},
+		triggerErr: errTx,
+	}
+
+	update := models.PriceUpdate{ProductID: 10, NewPrice: 900, NewInStock: true}
+
+	// * ошибка откатывает транзакцию результата: UpdateParsedData вернёт его на повтор
+	_, err := newEvaluator().Evaluate(context.Background(), rules, update)
+	if !errors.Is(err, errTx) {
+		t.Fatalf("Evaluate error = %v, want %v", err, errTx)
+	}
+
+	if rules.triggered[1] {
+		t.Error("rule is triggered after failed transaction")
+	}
+}
@@ -0,0 +1,99 @@
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var ErrUnauthorized = errors.New("auth service rejected token")
+
+// * maxResponseSize ограничивает ответ auth_service
+const maxResponseSize = 1 << 20
+
+// * Client обращается к auth_service от имени пользователя, передавая его access токен
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+func New(baseURL string, client *http.Client) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// * Account возвращает профиль, сессии и состояние 2FA пользователя
+// * (поле account ответа GET /me/export) без разбора
+func (c *Client) Account(ctx context.Context, authHeader string) (json.RawMessage, error) {
+	const op = "authclient.Account"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/me/export", nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	req.Header.Set("Authorization", authHeader)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusUnauthorized:
+		return nil, ErrUnauthorized
+	case res.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s: unexpected status %d", op, res.StatusCode)
+	}
+
+	var body struct {
+		Account json.RawMessage `json:"account"`
+	}
+
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s: decode: %w", op, err)
+	}
+
+	if len(body.Account) == 0 {
+		return nil, fmt.Errorf("%s: empty account", op)
+	}
+
+	return body.Account, nil
+}
+
+// * SessionActive спрашивает auth_service, не завершена ли сессия токена:
+// * токен отозванной сессии auth_service отклоняет с 401
+func (c *Client) SessionActive(ctx context.Context, authHeader string) (bool, error) {
+	const op = "authclient.SessionActive"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/sessions/current", nil)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	req.Header.Set("Authorization", authHeader)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer res.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxResponseSize))
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusUnauthorized:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%s: unexpected status %d", op, res.StatusCode)
+	}
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"main_service/internal/models"
+)
+
+// * pageSize — сколько продуктов читается за один запрос Products
+const pageSize = 100
+
+type Storage interface {
+	Products(ctx context.Context, userID, limit, offset int64) ([]models.Product, int64, error)
+	PriceHistory(ctx context.Context, productID, userID int64, from, to time.Time) ([]models.PricePoint, error)
+	AlertRules(ctx context.Context, userID, productID int64) ([]models.AlertRule, error)
+	Notifications(ctx context.Context, userID int64) ([]models.NotificationRecord, error)
+}
+
+// * ProductExport — продукт вместе со всей историей цен
+type ProductExport struct {
+	models.Product
+	History []models.PricePoint `json:"history"`
+}
+
+// * Exporter собирает ZIP архив со всеми данными пользователя
+type Exporter struct {
+	storage Storage
+	now     func() time.Time
+}
+
+func New(storage Storage, now func() time.Time) *Exporter {
+	return &Exporter{
+		storage: storage,
+		now:     now,
+	}
+}
+
+// * Build собирает архив: account.json (ответ auth_service), products.json
+// * с историей цен, alert_rules.json и notifications.json
+func (e *Exporter) Build(ctx context.Context, userID int64, account json.RawMessage) ([]byte, error) {
+	const op = "export.Build"
+
+	products, err := e.products(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rules, err := e.storage.AlertRules(ctx, userID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	notifications, err := e.storage.Notifications(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if rules == nil {
+		rules = []models.AlertRule{}
+	}
+	if notifications == nil {
+		notifications = []models.NotificationRecord{}
+	}
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"account.json", account},
+		{"products.json", products},
+		{"alert_rules.json", rules},
+		{"notifications.json", notifications},
+	}
+
+	for _, f := range files {
+		if err := writeJSON(zw, f.name, f.data, e.now()); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, f.name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// * products читает продукты постранично существующим запросом Products
+// * и добавляет к каждому историю цен с момента создания
+func (e *Exporter) products(ctx context.Context, userID int64) ([]ProductExport, error) {
+	result := []ProductExport{}
+
+	for offset := int64(0); ; offset += pageSize {
+		page, total, err := e.storage.Products(ctx, userID, pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, product := range page {
+			history, err := e.storage.PriceHistory(ctx, product.ID, userID, product.Created_at, e.now())
+			if err != nil {
+				return nil, fmt.Errorf("product %d history: %w", product.ID, err)
+			}
+
+			if history == nil {
+				history = []models.PricePoint{}
+			}
+
+			result = append(result, ProductExport{Product: product, History: history})
+		}
+
+		if len(page) < pageSize || offset+int64(len(page)) >= total {
+			return result, nil
+		}
+	}
+}
+
+func writeJSON(zw *zip.Writer, name string, data any, modified time.Time) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(data)
+}
@@ -0,0 +1,134 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	sl "main_service/internal/lib/logger"
+	"main_service/internal/models"
+	"main_service/internal/storage"
+)
+
+type JobStorage interface {
+	ClaimExportJob(ctx context.Context, staleAfter time.Duration) (models.ExportJob, []byte, error)
+	CompleteExportJob(ctx context.Context, jobID string, archive []byte, expiresAt time.Time) error
+	FailExportJob(ctx context.Context, jobID, reason string) error
+	DeleteExpiredExportJobs(ctx context.Context) (int64, error)
+}
+
+// * Worker выполняет фоновые выгрузки из export_jobs и удаляет просроченные архивы
+type Worker struct {
+	log          *slog.Logger
+	jobs         JobStorage
+	exporter     *Exporter
+	pollInterval time.Duration
+	staleAfter   time.Duration
+	archiveTTL   time.Duration
+	trigger      chan struct{}
+}
+
+func NewWorker(
+	log *slog.Logger,
+	jobs JobStorage,
+	exporter *Exporter,
+	pollInterval, staleAfter, archiveTTL time.Duration,
+) *Worker {
+	return &Worker{
+		log:          log,
+		jobs:         jobs,
+		exporter:     exporter,
+		pollInterval: pollInterval,
+		staleAfter:   staleAfter,
+		archiveTTL:   archiveTTL,
+		trigger:      make(chan struct{}, 1),
+	}
+}
+
+// * Trigger будит Worker после создания задания. Не блокируется
+func (w *Worker) Trigger() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// * Run блокируется до отмены контекста
+func (w *Worker) Run(ctx context.Context) {
+	const op = "export.Worker.Run"
+
+	log := w.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.processPending(ctx, log)
+		w.deleteExpired(ctx, log)
+
+		select {
+		case <-ctx.Done():
+			log.Info("export worker stopped")
+			return
+		case <-ticker.C:
+		case <-w.trigger:
+		}
+	}
+}
+
+// * processPending выполняет задания по одному, пока очередь не опустеет
+func (w *Worker) processPending(ctx context.Context, log *slog.Logger) {
+	for ctx.Err() == nil {
+		job, account, err := w.jobs.ClaimExportJob(ctx, w.staleAfter)
+		if err != nil {
+			if !errors.Is(err, storage.ErrExportJobNotFound) && ctx.Err() == nil {
+				log.Error("failed to claim export job", sl.Err(err))
+			}
+			return
+		}
+
+		w.process(ctx, log.With(slog.String("job_id", job.ID), slog.Int64("user_id", job.UserID)), job, account)
+	}
+}
+
+func (w *Worker) process(ctx context.Context, log *slog.Logger, job models.ExportJob, account []byte) {
+	archive, err := w.exporter.Build(ctx, job.UserID, json.RawMessage(account))
+	if err != nil {
+		// * при остановке сервиса задание остаётся running и будет подхвачено
+		// * заново после staleAfter
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Error("failed to build export", sl.Err(err))
+
+		if err := w.jobs.FailExportJob(ctx, job.ID, "failed to build export"); err != nil {
+			log.Error("failed to mark export job failed", sl.Err(err))
+		}
+
+		return
+	}
+
+	if err := w.jobs.CompleteExportJob(ctx, job.ID, archive, w.exporter.now().Add(w.archiveTTL)); err != nil {
+		log.Error("failed to save export archive", sl.Err(err))
+		return
+	}
+
+	log.Info("export completed", slog.Int("size", len(archive)))
+}
+
+func (w *Worker) deleteExpired(ctx context.Context, log *slog.Logger) {
+	deleted, err := w.jobs.DeleteExpiredExportJobs(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error("failed to delete expired exports", sl.Err(err))
+		}
+		return
+	}
+
+	if deleted > 0 {
+		log.Info("expired exports deleted", slog.Int64("count", deleted))
+	}
+}
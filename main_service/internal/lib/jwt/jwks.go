@@ -0,0 +1,174 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var ErrUnknownKeyID = errors.New("unknown key id")
+
+// * minRefetchInterval ограничивает частоту запросов JWKS при токенах с неизвестным kid
+const minRefetchInterval = 10 * time.Second
+
+type jwk struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	KeyID   string `json:"kid"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// * JWKSCache загружает публичные ключи auth_service и кеширует их.
+// * Набор перечитывается раз в refreshInterval, а также при встрече
+// * неизвестного kid — так новый ключ после ротации подхватывается сразу.
+// * Запрос к auth_service выполняется без блокировки кеша, параллельные
+// * обновления схлопываются в один, а попытки (и удачные, и нет) не чаще
+// * minRefetchInterval
+type JWKSCache struct {
+	url             string
+	client          *http.Client
+	refreshInterval time.Duration
+	now             func() time.Time
+	group           singleflight.Group
+
+	mu          sync.Mutex
+	keys        map[string]ed25519.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+func NewJWKSCache(url string, client *http.Client, refreshInterval time.Duration) *JWKSCache {
+	return &JWKSCache{
+		url:             url,
+		client:          client,
+		refreshInterval: refreshInterval,
+		now:             time.Now,
+		keys:            make(map[string]ed25519.PublicKey),
+	}
+}
+
+// * PublicKey возвращает ключ по kid. Если обновить набор не удалось,
+// * используется последний успешно загруженный
+func (c *JWKSCache) PublicKey(ctx context.Context, kid string) (ed25519.PublicKey, error) {
+	const op = "jwt.JWKSCache.PublicKey"
+
+	key, ok, stale, throttled := c.lookup(kid)
+
+	if ok && (!stale || throttled) {
+		return key, nil
+	}
+
+	if !ok && throttled {
+		return nil, ErrUnknownKeyID
+	}
+
+	// * загрузка не привязана к отмене запроса, первым её начавшего:
+	// * её результат ждут и остальные запросы
+	ch := c.group.DoChan("jwks", func() (any, error) {
+		return nil, c.refresh(context.WithoutCancel(ctx))
+	})
+
+	var err error
+	select {
+	case res := <-ch:
+		err = res.Err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		if ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key, ok, _, _ = c.lookup(kid)
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+
+	return key, nil
+}
+
+// * lookup ищет ключ в кеше: stale — набор старше refreshInterval,
+// * throttled — с последней попытки загрузки не прошло minRefetchInterval
+func (c *JWKSCache) lookup(kid string) (key ed25519.PublicKey, ok, stale, throttled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	key, ok = c.keys[kid]
+
+	return key, ok, now.Sub(c.fetchedAt) >= c.refreshInterval, now.Sub(c.attemptedAt) < minRefetchInterval
+}
+
+// * refresh загружает набор и отмечает попытку по её завершении: пока запрос
+// * идёт, остальные вызовы присоединяются к нему, а не получают отказ
+func (c *JWKSCache) refresh(ctx context.Context) error {
+	keys, err := c.fetch(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.attemptedAt = c.now()
+	if err != nil {
+		return err
+	}
+
+	c.keys = keys
+	c.fetchedAt = c.attemptedAt
+
+	return nil
+}
+
+func (c *JWKSCache) fetch(ctx context.Context) (map[string]ed25519.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(set.Keys))
+
+	for _, k := range set.Keys {
+		if k.KeyType != "OKP" || k.Curve != "Ed25519" || k.KeyID == "" {
+			continue
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			continue
+		}
+
+		keys[k.KeyID] = ed25519.PublicKey(x)
+	}
+
+	return keys, nil
+}
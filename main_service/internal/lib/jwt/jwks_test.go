@@ -0,0 +1,306 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// * fakeAuth отдаёт JWKS из текущего набора ключей и считает запросы.
+// * Пока block не закрыт, ответы задерживаются
+type fakeAuth struct {
+	mu       sync.Mutex
+	keys     map[string]ed25519.PrivateKey
+	failing  bool
+	block    chan struct{}
+	requests atomic.Int32
+}
+
+func newFakeAuth(t *testing.T) (*fakeAuth, *httptest.Server) {
+	t.Helper()
+
+	a := &fakeAuth{keys: make(map[string]ed25519.PrivateKey)}
+
+	srv := httptest.NewServer(http.HandlerFunc(a.serveJWKS))
+	t.Cleanup(srv.Close)
+
+	return a, srv
+}
+
+func (a *fakeAuth) serveJWKS(w http.ResponseWriter, _ *http.Request) {
+	a.requests.Add(1)
+
+	a.mu.Lock()
+	block, failing := a.block, a.failing
+
+	set := jwks{}
+	for kid, private := range a.keys {
+		set.Keys = append(set.Keys, jwk{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       base64.RawURLEncoding.EncodeToString(private.Public().(ed25519.PublicKey)),
+			KeyID:   kid,
+		})
+	}
+	a.mu.Unlock()
+
+	if block != nil {
+		<-block
+	}
+
+	if failing {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(set)
+}
+
+// * addKey публикует новый ключ и возвращает токен, подписанный им
+func (a *fakeAuth) addKey(t *testing.T, kid string) string {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	a.mu.Lock()
+	a.keys[kid] = private
+	a.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{
+		"uid": 7,
+		"sid": "session",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(private)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	return "Bearer " + signed
+}
+
+func (a *fakeAuth) set(f func(a *fakeAuth)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f(a)
+}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func newTestCache(srv *httptest.Server) (*JWKSCache, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	cache := NewJWKSCache(srv.URL, srv.Client(), 10*time.Minute)
+	cache.now = clock.Now
+
+	return cache, clock
+}
+
+func parseUserID(t *testing.T, p *JWTParser, header string) {
+	t.Helper()
+
+	uid, err := p.ParseToken(context.Background(), header)
+	if err != nil {
+		t.Fatalf("ParseToken: %v", err)
+	}
+
+	if uid != 7 {
+		t.Fatalf("uid = %d, want 7", uid)
+	}
+}
+
+// * После ротации в auth_service токены и старого, и нового ключа проходят
+// * проверку: неизвестный kid перечитывает JWKS
+func TestJWKSCacheRotation(t *testing.T) {
+	auth, srv := newFakeAuth(t)
+	cache, clock := newTestCache(srv)
+	parser := New(cache)
+
+	oldToken := auth.addKey(t, "old")
+	parseUserID(t, parser, oldToken)
+
+	newToken := auth.addKey(t, "new")
+
+	// * сразу после загрузки неизвестный kid не перечитывает набор
+	if _, err := parser.ParseToken(context.Background(), newToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("ParseToken within min refetch interval: %v, want %v", err, ErrInvalidToken)
+	}
+
+	clock.Advance(minRefetchInterval)
+
+	parseUserID(t, parser, newToken)
+	parseUserID(t, parser, oldToken)
+
+	if got := auth.requests.Load(); got != 2 {
+		t.Errorf("JWKS requests = %d, want 2", got)
+	}
+}
+
+// * Пока auth_service недоступен, проверка идёт по последнему загруженному
+// * набору, а повторные попытки не чаще minRefetchInterval
+func TestJWKSCacheUnavailable(t *testing.T) {
+	auth, srv := newFakeAuth(t)
+	cache, clock := newTestCache(srv)
+	parser := New(cache)
+
+	token := auth.addKey(t, "key")
+	parseUserID(t, parser, token)
+
+	auth.set(func(a *fakeAuth) { a.failing = true })
+	clock.Advance(cache.refreshInterval)
+
+	for range 10 {
+		parseUserID(t, parser, token)
+	}
+
+	if got := auth.requests.Load(); got != 2 {
+		t.Errorf("JWKS requests during outage = %d, want 2", got)
+	}
+
+	unknown := auth.addKey(t, "unknown")
+	for range 10 {
+		if _, err := parser.ParseToken(context.Background(), unknown); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("ParseToken with unknown kid: %v, want %v", err, ErrInvalidToken)
+		}
+	}
+
+	if got := auth.requests.Load(); got != 2 {
+		t.Errorf("JWKS requests for unknown kid during outage = %d, want 2", got)
+	}
+
+	clock.Advance(minRefetchInterval)
+	parseUserID(t, parser, token)
+
+	if got := auth.requests.Load(); got != 3 {
+		t.Errorf("JWKS requests after min refetch interval = %d, want 3", got)
+	}
+}
+
+// * Медленный JWKS не блокирует проверку по закешированным ключам,
+// * а параллельные обновления схлопываются в один запрос
+func TestJWKSCacheFetchOutsideLock(t *testing.T) {
+	auth, srv := newFakeAuth(t)
+	cache, clock := newTestCache(srv)
+	parser := New(cache)
+
+	token := auth.addKey(t, "key")
+	parseUserID(t, parser, token)
+
+	block := make(chan struct{})
+	auth.set(func(a *fakeAuth) { a.block = block })
+
+	rotated := auth.addKey(t, "rotated")
+	clock.Advance(minRefetchInterval)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := parser.ParseToken(context.Background(), rotated); err != nil {
+				t.Errorf("ParseToken rotated: %v", err)
+			}
+		}()
+	}
+
+	// * ждём, пока обновление дойдёт до сервера
+	deadline := time.Now().Add(5 * time.Second)
+	for auth.requests.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("JWKS refetch did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		parseUserID(t, parser, token)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cached key lookup blocked by in-flight JWKS fetch")
+	}
+
+	close(block)
+	wg.Wait()
+
+	if got := auth.requests.Load(); got != 2 {
+		t.Errorf("JWKS requests = %d, want 2", got)
+	}
+}
+
+// * Отмена запроса, ожидающего загрузку, не ломает загрузку для остальных
+func TestJWKSCacheCallerCancel(t *testing.T) {
+	auth, srv := newFakeAuth(t)
+	cache, _ := newTestCache(srv)
+
+	auth.addKey(t, "key")
+
+	block := make(chan struct{})
+	auth.set(func(a *fakeAuth) { a.block = block })
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := cache.PublicKey(ctx, "key")
+		errc <- err
+	}()
+
+	for auth.requests.Load() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("PublicKey after cancel: %v, want %v", err, context.Canceled)
+	}
+
+	close(block)
+
+	if _, err := cache.PublicKey(context.Background(), "key"); err != nil {
+		t.Fatalf("PublicKey after cancelled caller: %v", err)
+	}
+
+	if got := auth.requests.Load(); got != 1 {
+		t.Errorf("JWKS requests = %d, want 1", got)
+	}
+}
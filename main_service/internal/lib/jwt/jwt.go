@@ -1,6 +1,8 @@
 package jwt
 
 import (
+	"context"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
 	"strings"
@@ -13,51 +15,100 @@ var (
 	ErrInvalidAuthHeader  = errors.New("invalid Authorization header")
 	ErrInvalidToken       = errors.New("invalid token")
 	ErrMissingUserIDClaim = errors.New("user_id missing in token")
+	ErrMissingSessionID   = errors.New("session id missing in token")
 )
 
+// * DefaultRole назначается токенам, выпущенным до появления claim'а role
+const DefaultRole = "user"
+
+type Claims struct {
+	UserID    int64
+	SessionID string
+	Email     string
+	Role      string
+}
+
+// * KeyProvider возвращает публичный ключ проверки подписи по kid
+type KeyProvider interface {
+	PublicKey(ctx context.Context, kid string) (ed25519.PublicKey, error)
+}
+
 type JWTParser struct {
-	Secret string
+	keys KeyProvider
 }
 
-func New(secret string) *JWTParser {
+func New(keys KeyProvider) *JWTParser {
 	return &JWTParser{
-		Secret: secret,
+		keys: keys,
 	}
 }
 
 // * ParseToken извлекает userID из JWT токена
-func (p *JWTParser) ParseToken(authHeader string) (int64, error) {
+func (p *JWTParser) ParseToken(ctx context.Context, authHeader string) (int64, error) {
+	claims, err := p.ParseClaims(ctx, authHeader)
+	if err != nil {
+		return 0, err
+	}
+
+	return claims.UserID, nil
+}
+
+// * ParseClaims проверяет подпись JWT токена ключом из JWKS и возвращает данные пользователя.
+// * Отзыв сессии по подписи не виден: его проверяет middleware по SessionID
+func (p *JWTParser) ParseClaims(ctx context.Context, authHeader string) (Claims, error) {
 	if authHeader == "" {
-		return 0, ErrMissingAuthHeader
+		return Claims{}, ErrMissingAuthHeader
 	}
 
 	parts := strings.SplitN(authHeader, " ", 2)
 	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-		return 0, ErrInvalidAuthHeader
+		return Claims{}, ErrInvalidAuthHeader
 	}
 
 	tokenString := parts[1]
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(p.Secret), nil
-	})
+
+		kid, ok := token.Header["kid"].(string)
+		if !ok || kid == "" {
+			return nil, ErrUnknownKeyID
+		}
+
+		return p.keys.PublicKey(ctx, kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}))
 	if err != nil || !token.Valid {
-		return 0, ErrInvalidToken
+		return Claims{}, ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return 0, ErrInvalidToken
+		return Claims{}, ErrInvalidToken
 	}
 
 	uidFloat, ok := claims["uid"].(float64)
 	if !ok {
-		return 0, ErrMissingUserIDClaim
+		return Claims{}, ErrMissingUserIDClaim
+	}
+
+	sid, _ := claims["sid"].(string)
+	if sid == "" {
+		return Claims{}, ErrMissingSessionID
+	}
+
+	email, _ := claims["email"].(string)
+
+	role, _ := claims["role"].(string)
+	if role == "" {
+		role = DefaultRole
 	}
-	userID := int64(uidFloat)
 
-	return userID, nil
+	return Claims{
+		UserID:    int64(uidFloat),
+		SessionID: sid,
+		Email:     email,
+		Role:      role,
+	}, nil
 }
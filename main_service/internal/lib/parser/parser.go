@@ -2,37 +2,58 @@ package parser
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 
 	"main_service/internal/models"
+	"main_service/internal/storage"
+	"messaging/broker"
+	"messaging/envelope"
 )
 
 type PostgresStorage interface {
 	UpdateParsedData(
 		ctx context.Context,
+		messageID string,
 		productID int64,
 		price int,
+		currency string,
 		inStock bool,
-	) error
+		evaluate storage.EvaluateAlerts,
+	) (models.PriceUpdate, error)
+	RecordParseFailure(ctx context.Context, messageID string, productID int64, reason string) error
 }
 
 type Consumer interface {
 	Consume(ctx context.Context, handler func(ctx context.Context, body []byte) error) error
 }
 
+type AlertEvaluator interface {
+	Evaluate(ctx context.Context, rules storage.AlertRules, update models.PriceUpdate) (bool, error)
+}
+
+// * OutboxTrigger будит relay outbox после записи письма
+type OutboxTrigger interface {
+	Trigger()
+}
+
 type Parser struct {
 	postgres         PostgresStorage
 	rabbitmqConsumer Consumer
+	alerts           AlertEvaluator
+	outbox           OutboxTrigger
 }
 
-func New(pg PostgresStorage, c Consumer) *Parser {
+func New(pg PostgresStorage, c Consumer, alerts AlertEvaluator, outbox OutboxTrigger) *Parser {
 	return &Parser{
 		postgres:         pg,
 		rabbitmqConsumer: c,
+		alerts:           alerts,
+		outbox:           outbox,
 	}
 }
 
+// * Run обрабатывает результаты парсинга и блокируется до отмены ctx
 func (s *Parser) Run(ctx context.Context) error {
 	return s.rabbitmqConsumer.Consume(ctx, s.handleMessage)
 }
@@ -40,14 +61,52 @@ func (s *Parser) Run(ctx context.Context) error {
 func (p *Parser) handleMessage(ctx context.Context, body []byte) error {
 	var msg models.ParsedProduct
 
-	if err := json.Unmarshal(body, &msg); err != nil {
+	env, err := envelope.Unmarshal(body, envelope.TypeParsed, &msg)
+	if err != nil {
 		return fmt.Errorf("invalid message format: %w", err)
 	}
 
-	return p.postgres.UpdateParsedData(
+	// * уведомления продолжают цепочку задания на парсинг
+	ctx = envelope.WithCorrelationID(ctx, env.Correlation())
+
+	// * парсер не смог разобрать страницу: фиксируем ошибку для статистики
+	if msg.Error != "" {
+		return classify(p.postgres.RecordParseFailure(ctx, env.ID, msg.ID, msg.Error))
+	}
+
+	update, err := p.postgres.UpdateParsedData(
 		ctx,
+		env.ID,
 		msg.ID,
 		msg.Price,
+		msg.Currency,
 		msg.In_stock,
+		p.alerts.Evaluate,
 	)
+	if err != nil {
+		return classify(err)
+	}
+
+	// * письма поставлены в транзакции результата: будим relay, не дожидаясь опроса
+	if update.StockNotified || update.AlertsNotified {
+		p.outbox.Trigger()
+	}
+
+	return nil
+}
+
+// * classify решает судьбу сообщения по ошибке хранилища. Результат, уже
+// * применённый при прошлой доставке, подтверждается без повторной обработки:
+// * id конверта выводится из задания, поэтому и повторно выполненное задание
+// * даёт дубликат. Результат для удалённого продукта уходит сразу в DLQ:
+// * повторная обработка его не найдёт. Остальные ошибки считаются временными
+func classify(err error) error {
+	switch {
+	case err == nil, errors.Is(err, storage.ErrMessageAlreadyProcessed):
+		return nil
+	case errors.Is(err, storage.ErrProductsNotFound):
+		return broker.Permanent(err)
+	default:
+		return err
+	}
 }
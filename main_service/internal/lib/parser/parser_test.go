@@ -0,0 +1,216 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"main_service/internal/lib/alerts"
+	"main_service/internal/models"
+	"main_service/internal/storage"
+	"messaging/broker"
+	"messaging/envelope"
+)
+
+// * fakeStorage отбрасывает повторно доставленные id конвертов, как processed_messages.
+// * Как и транзакция UpdateParsedData, не отмечает результат, если проверка правил не удалась
+type fakeStorage struct {
+	processed map[string]bool
+	rules     *fakeRules
+	updates   int
+	failures  int
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{processed: make(map[string]bool), rules: &fakeRules{}}
+}
+
+func (s *fakeStorage) check(messageID string, productID int64) error {
+	if productID != 1 {
+		return storage.ErrProductsNotFound
+	}
+
+	if s.processed[messageID] {
+		return storage.ErrMessageAlreadyProcessed
+	}
+
+	return nil
+}
+
+func (s *fakeStorage) UpdateParsedData(
+	ctx context.Context,
+	messageID string,
+	productID int64,
+	price int,
+	_ string,
+	inStock bool,
+	evaluate storage.EvaluateAlerts,
+) (models.PriceUpdate, error) {
+	if err := s.check(messageID, productID); err != nil {
+		return models.PriceUpdate{}, err
+	}
+
+	u := models.PriceUpdate{ProductID: productID, NewPrice: price, NewInStock: inStock}
+
+	notified, err := evaluate(ctx, s.rules, u)
+	if err != nil {
+		return models.PriceUpdate{}, err
+	}
+
+	s.processed[messageID] = true
+	s.updates++
+	u.AlertsNotified = notified
+
+	return u, nil
+}
+
+func (s *fakeStorage) RecordParseFailure(_ context.Context, messageID string, productID int64, _ string) error {
+	if err := s.check(messageID, productID); err != nil {
+		return err
+	}
+
+	s.processed[messageID] = true
+	s.failures++
+
+	return nil
+}
+
+// * fakeRules хранит одно правило "цена ниже 1000"; первые failures срабатываний
+// * завершаются ошибкой, как оборванная транзакция
+type fakeRules struct {
+	triggered bool
+	failures  int
+	emails    int
+}
+
+func (r *fakeRules) ProductAlertRules(_ context.Context, productID int64) ([]models.AlertRule, error) {
+	return []models.AlertRule{{ID: 1, ProductID: productID, Type: models.AlertPriceBelow, Threshold: 1000, Triggered: r.triggered}}, nil
+}
+
+func (r *fakeRules) TriggerAlertRule(context.Context, models.AlertRule, models.PriceAlertData) (bool, error) {
+	if r.failures > 0 {
+		r.failures--
+		return false, errors.New("connection reset")
+	}
+
+	if r.triggered {
+		return false, nil
+	}
+
+	r.triggered = true
+	r.emails++
+
+	return true, nil
+}
+
+func (r *fakeRules) ResetAlertTriggered(context.Context, int64) (bool, error) {
+	changed := r.triggered
+	r.triggered = false
+
+	return changed, nil
+}
+
+type fakeOutbox struct {
+	triggers int
+}
+
+func (o *fakeOutbox) Trigger() {
+	o.triggers++
+}
+
+func newParser(pg *fakeStorage, outbox *fakeOutbox) *Parser {
+	return New(pg, nil, alerts.New(slog.New(slog.NewTextHandler(io.Discard, nil))), outbox)
+}
+
+func resultMessage(t *testing.T, taskID string, result models.ParsedProduct) []byte {
+	t.Helper()
+
+	env, err := envelope.New(envelope.TypeParsed, result)
+	if err != nil {
+		t.Fatalf("envelope.New: %v", err)
+	}
+
+	env.ID = envelope.DerivedID(taskID, envelope.TypeParsed)
+
+	body, err := env.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	return body
+}
+
+func TestHandleMessageSkipsDuplicates(t *testing.T) {
+	tests := []struct {
+		name         string
+		result       models.ParsedProduct
+		wantUpdates  int
+		wantFailures int
+	}{
+		{name: "price", result: models.ParsedProduct{ID: 1, Price: 1000, Currency: "USD", In_stock: true}, wantUpdates: 1},
+		{name: "parse failure", result: models.ParsedProduct{ID: 1, Error: "price not found"}, wantFailures: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pg := newFakeStorage()
+			outbox := &fakeOutbox{}
+			p := newParser(pg, outbox)
+
+			// * задание выполнено дважды: оба результата несут один id и второй подтверждается без изменений
+			task := envelope.NewID()
+			for range 2 {
+				if err := p.handleMessage(context.Background(), resultMessage(t, task, tt.result)); err != nil {
+					t.Fatalf("handleMessage: %v", err)
+				}
+			}
+
+			if pg.updates != tt.wantUpdates || pg.failures != tt.wantFailures {
+				t.Errorf("updates = %d, failures = %d; want %d, %d", pg.updates, pg.failures, tt.wantUpdates, tt.wantFailures)
+			}
+
+			if pg.rules.emails != tt.wantUpdates || outbox.triggers != tt.wantUpdates {
+				t.Errorf("alert emails = %d, outbox triggers = %d; want %d", pg.rules.emails, outbox.triggers, tt.wantUpdates)
+			}
+		})
+	}
+}
+
+func TestHandleMessageUnknownProductIsPermanent(t *testing.T) {
+	p := newParser(newFakeStorage(), &fakeOutbox{})
+
+	err := p.handleMessage(context.Background(), resultMessage(t, envelope.NewID(), models.ParsedProduct{ID: 2, Price: 100}))
+	if !errors.Is(err, storage.ErrProductsNotFound) || !broker.IsPermanent(err) {
+		t.Fatalf("handleMessage error = %v, want permanent %v", err, storage.ErrProductsNotFound)
+	}
+}
+
+func TestHandleMessageRetriesFailedAlertEvaluation(t *testing.T) {
+	pg := newFakeStorage()
+	pg.rules.failures = 1
+	outbox := &fakeOutbox{}
+	p := newParser(pg, outbox)
+
+	body := resultMessage(t, envelope.NewID(), models.ParsedProduct{ID: 1, Price: 900, Currency: "USD", In_stock: true})
+
+	// * сбой проверки правил откатывает результат: сообщение уходит на повтор
+	err := p.handleMessage(context.Background(), body)
+	if err == nil || broker.IsPermanent(err) {
+		t.Fatalf("handleMessage error = %v, want transient error", err)
+	}
+
+	if pg.updates != 0 || pg.rules.emails != 0 || outbox.triggers != 0 {
+		t.Fatalf("after failure: updates = %d, emails = %d, triggers = %d; want none", pg.updates, pg.rules.emails, outbox.triggers)
+	}
+
+	// * повторная доставка не считается дубликатом, и правило срабатывает
+	if err := p.handleMessage(context.Background(), body); err != nil {
+		t.Fatalf("redelivered handleMessage: %v", err)
+	}
+
+	if pg.updates != 1 || pg.rules.emails != 1 || outbox.triggers != 1 {
+		t.Errorf("after redelivery: updates = %d, emails = %d, triggers = %d; want 1, 1, 1", pg.updates, pg.rules.emails, outbox.triggers)
+	}
+}
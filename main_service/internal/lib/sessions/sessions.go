@@ -0,0 +1,109 @@
+package sessions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// * Introspector спрашивает auth_service, не завершена ли сессия access токена
+type Introspector interface {
+	SessionActive(ctx context.Context, authHeader string) (bool, error)
+}
+
+type entry struct {
+	active    bool
+	checkedAt time.Time
+}
+
+// * Cache проверяет сессии access токенов через auth_service и помнит ответ
+// * ttl: отозванная сессия отклоняется не позже чем через ttl, а не через
+// * access_token_ttl. Параллельные проверки одной сессии схлопываются в один
+// * запрос. Ответ auth_service с ошибкой не кешируется
+type Cache struct {
+	introspector Introspector
+	ttl          time.Duration
+	now          func() time.Time
+	group        singleflight.Group
+
+	mu       sync.Mutex
+	entries  map[string]entry
+	prunedAt time.Time
+}
+
+func New(introspector Introspector, ttl time.Duration) *Cache {
+	return &Cache{
+		introspector: introspector,
+		ttl:          ttl,
+		now:          time.Now,
+		entries:      make(map[string]entry),
+	}
+}
+
+// * SessionActive сообщает, не завершена ли сессия sessionID. authHeader —
+// * заголовок Authorization запроса с токеном этой сессии
+func (c *Cache) SessionActive(ctx context.Context, sessionID, authHeader string) (bool, error) {
+	const op = "sessions.Cache.SessionActive"
+
+	if active, ok := c.lookup(sessionID); ok {
+		return active, nil
+	}
+
+	// * проверка не привязана к отмене запроса, первым её начавшего:
+	// * её результат ждут и остальные запросы
+	ch := c.group.DoChan(sessionID, func() (any, error) {
+		active, err := c.introspector.SessionActive(context.WithoutCancel(ctx), authHeader)
+		if err != nil {
+			return false, err
+		}
+
+		c.store(sessionID, active)
+
+		return active, nil
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return false, fmt.Errorf("%s: %w", op, res.Err)
+		}
+		return res.Val.(bool), nil
+	case <-ctx.Done():
+		return false, fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
+
+func (c *Cache) lookup(sessionID string) (active, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[sessionID]
+	if !ok || c.now().Sub(e.checkedAt) >= c.ttl {
+		return false, false
+	}
+
+	return e.active, true
+}
+
+// * store запоминает ответ и раз в ttl удаляет устаревшие записи,
+// * чтобы кеш не рос вместе с числом когда-либо встреченных сессий
+func (c *Cache) store(sessionID string, active bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+
+	if now.Sub(c.prunedAt) >= c.ttl {
+		for id, e := range c.entries {
+			if now.Sub(e.checkedAt) >= c.ttl {
+				delete(c.entries, id)
+			}
+		}
+		c.prunedAt = now
+	}
+
+	c.entries[sessionID] = entry{active: active, checkedAt: now}
+}
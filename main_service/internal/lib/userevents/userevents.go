@@ -0,0 +1,120 @@
+package userevents
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"main_service/internal/models"
+	"main_service/internal/storage"
+	"messaging/envelope"
+)
+
+type PostgresStorage interface {
+	DeleteUserData(ctx context.Context, userID int64, purge storage.PurgeProducts) ([]int64, error)
+	SaveUserProfile(ctx context.Context, userID int64, profile models.UserEventProfile, occurredAt time.Time) (bool, error)
+}
+
+type CacheStorage interface {
+	DeleteProducts(ctx context.Context, productIDs []int64) error
+}
+
+type Consumer interface {
+	Consume(ctx context.Context, handler func(ctx context.Context, body []byte) error) error
+}
+
+// * Handler применяет события об аккаунтах из auth_service. Событие может
+// * прийти повторно, поэтому обработка идемпотентна
+type Handler struct {
+	log      *slog.Logger
+	postgres PostgresStorage
+	cache    CacheStorage
+	consumer Consumer
+}
+
+func New(log *slog.Logger, pg PostgresStorage, cache CacheStorage, c Consumer) *Handler {
+	return &Handler{
+		log:      log,
+		postgres: pg,
+		cache:    cache,
+		consumer: c,
+	}
+}
+
+// * Run обрабатывает события и блокируется до отмены ctx
+func (h *Handler) Run(ctx context.Context) error {
+	return h.consumer.Consume(ctx, h.handleMessage)
+}
+
+func (h *Handler) handleMessage(ctx context.Context, body []byte) error {
+	const op = "userevents.handleMessage"
+
+	env, err := envelope.Decode(body)
+	if err != nil {
+		return fmt.Errorf("%s: invalid message format: %w", op, err)
+	}
+
+	var event models.UserEvent
+
+	if err := env.DecodePayload(&event); err != nil {
+		return fmt.Errorf("%s: invalid message format: %w", op, err)
+	}
+
+	switch event.Type {
+	case models.EventUserDeleted:
+		return h.userDeleted(ctx, event.UserID)
+	case models.EventUserUpdated:
+		if event.Profile == nil {
+			return fmt.Errorf("%s: user.updated without profile", op)
+		}
+		return h.userUpdated(ctx, event.UserID, *event.Profile, event.OccurredAt)
+	default:
+		h.log.Debug("user event skipped", slog.String("op", op), slog.String("type", event.Type))
+		return nil
+	}
+}
+
+// * userDeleted удаляет данные пользователя и его продукты из кеша. Кеш
+// * очищается до фиксации удаления: если он не очистился, удаление откатывается,
+// * и повторная доставка снова найдёт продукты
+func (h *Handler) userDeleted(ctx context.Context, userID int64) error {
+	const op = "userevents.userDeleted"
+
+	productIDs, err := h.postgres.DeleteUserData(ctx, userID, h.cache.DeleteProducts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	h.log.Info("user data deleted",
+		slog.String("op", op),
+		slog.Int64("user_id", userID),
+		slog.Int("products", len(productIDs)),
+	)
+
+	return nil
+}
+
+// * userUpdated сохраняет адрес и настройки локализации уведомлений
+func (h *Handler) userUpdated(
+	ctx context.Context,
+	userID int64,
+	profile models.UserEventProfile,
+	occurredAt time.Time,
+) error {
+	const op = "userevents.userUpdated"
+
+	applied, err := h.postgres.SaveUserProfile(ctx, userID, profile, occurredAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !applied {
+		h.log.Debug("stale or deleted user profile skipped", slog.String("op", op), slog.Int64("user_id", userID))
+		return nil
+	}
+
+	h.log.Info("user profile updated", slog.String("op", op), slog.Int64("user_id", userID))
+
+	return nil
+}
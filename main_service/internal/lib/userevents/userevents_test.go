@@ -0,0 +1,95 @@
+package userevents
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"main_service/internal/models"
+	"main_service/internal/storage"
+	"messaging/envelope"
+)
+
+// * fakeStorage удаляет продукты пользователя, как транзакция DeleteUserData:
+// * если purge вернул ошибку, продукты остаются на месте
+type fakeStorage struct {
+	products map[int64][]int64
+}
+
+func (s *fakeStorage) DeleteUserData(ctx context.Context, userID int64, purge storage.PurgeProducts) ([]int64, error) {
+	productIDs := s.products[userID]
+
+	if err := purge(ctx, productIDs); err != nil {
+		return nil, err
+	}
+
+	delete(s.products, userID)
+
+	return productIDs, nil
+}
+
+func (s *fakeStorage) SaveUserProfile(context.Context, int64, models.UserEventProfile, time.Time) (bool, error) {
+	return true, nil
+}
+
+// * fakeCache хранит ключи продуктов; err имитирует недоступный redis
+type fakeCache struct {
+	keys map[int64]bool
+	err  error
+}
+
+func (c *fakeCache) DeleteProducts(_ context.Context, productIDs []int64) error {
+	if c.err != nil {
+		return c.err
+	}
+
+	for _, id := range productIDs {
+		delete(c.keys, id)
+	}
+
+	return nil
+}
+
+func TestUserDeletedRetriesCachePurge(t *testing.T) {
+	pg := &fakeStorage{products: map[int64][]int64{7: {1, 2}}}
+	cache := &fakeCache{keys: map[int64]bool{1: true, 2: true, 3: true}, err: errors.New("redis down")}
+
+	h := New(slog.New(slog.NewTextHandler(io.Discard, nil)), pg, cache, nil)
+
+	body, err := envelope.Marshal(envelope.TypeUserDeleted, models.UserEvent{
+		Type:       models.EventUserDeleted,
+		UserID:     7,
+		OccurredAt: time.Now(),
+	}, "")
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	if err := h.handleMessage(context.Background(), body); err == nil {
+		t.Fatal("handleMessage succeeded with cache down")
+	}
+
+	// * удаление откатилось: повторная доставка снова найдёт продукты и очистит их ключи
+	if len(pg.products[7]) != 2 {
+		t.Fatalf("products after failed purge = %v, want both kept", pg.products[7])
+	}
+
+	cache.err = nil
+
+	if err := h.handleMessage(context.Background(), body); err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+
+	if keys := slices.Sorted(maps.Keys(cache.keys)); !slices.Equal(keys, []int64{3}) {
+		t.Errorf("cached products = %v, want only 3", keys)
+	}
+
+	if _, ok := pg.products[7]; ok {
+		t.Error("products are kept after redelivery")
+	}
+}
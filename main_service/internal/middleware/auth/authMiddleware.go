@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 
 	resp "main_service/internal/lib/api/response"
@@ -14,9 +15,38 @@ import (
 
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	UserIDKey    contextKey = "user_id"
+	EmailKey     contextKey = "email"
+	PrincipalKey contextKey = "principal"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// * Principal — аутентифицированный пользователь запроса
+type Principal struct {
+	UserID int64
+	Email  string
+	Role   string
+}
+
+// * PrincipalFrom достаёт Principal, положенный в контекст middleware New
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(PrincipalKey).(Principal)
+	return p, ok
+}
 
-func New(log *slog.Logger, jwtParser *jwt.JWTParser) func(next http.Handler) http.Handler {
+// * SessionChecker сообщает, не завершена ли сессия access токена в auth_service
+type SessionChecker interface {
+	SessionActive(ctx context.Context, sessionID, authHeader string) (bool, error)
+}
+
+// * New проверяет access токен и его сессию: токен завершённой сессии
+// * (выход, смена пароля) отклоняется, хотя его подпись и срок ещё действительны
+func New(log *slog.Logger, jwtParser *jwt.JWTParser, sessions SessionChecker) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			const op = "middleware.auth.New"
@@ -34,8 +64,7 @@ func New(log *slog.Logger, jwtParser *jwt.JWTParser) func(next http.Handler) htt
 				return
 			}
 
-			token := strings.TrimPrefix(authHeader, "Bearer ")
-			if token == "" {
+			if strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer ")) == "" {
 				log.Warn("Empty token",
 					slog.String("op", op),
 					slog.String("path", r.URL.Path),
@@ -47,7 +76,7 @@ func New(log *slog.Logger, jwtParser *jwt.JWTParser) func(next http.Handler) htt
 				return
 			}
 
-			userID, err := jwtParser.ParseToken(token)
+			claims, err := jwtParser.ParseClaims(r.Context(), authHeader)
 			if err != nil {
 				log.Warn("Invalid token",
 					slog.String("op", op),
@@ -61,11 +90,45 @@ func New(log *slog.Logger, jwtParser *jwt.JWTParser) func(next http.Handler) htt
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			active, err := sessions.SessionActive(r.Context(), claims.SessionID, authHeader)
+			if err != nil {
+				log.Error("Failed to check session",
+					slog.String("op", op),
+					slog.Int64("user_id", claims.UserID),
+					slog.String("error", err.Error()),
+				)
+
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("Internal error"))
+
+				return
+			}
+
+			if !active {
+				log.Warn("Session revoked",
+					slog.String("op", op),
+					slog.Int64("user_id", claims.UserID),
+					slog.String("path", r.URL.Path),
+				)
+
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("Session revoked"))
+
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, EmailKey, claims.Email)
+			ctx = context.WithValue(ctx, PrincipalKey, Principal{
+				UserID: claims.UserID,
+				Email:  claims.Email,
+				Role:   claims.Role,
+			})
 
 			log.Debug("User authenticated",
 				slog.String("op", op),
-				slog.Int64("user_id", userID),
+				slog.Int64("user_id", claims.UserID),
+				slog.String("role", claims.Role),
 				slog.String("path", r.URL.Path),
 			)
 
@@ -73,3 +136,42 @@ func New(log *slog.Logger, jwtParser *jwt.JWTParser) func(next http.Handler) htt
 		})
 	}
 }
+
+// * RequireRole пропускает только запросы, роль Principal которых входит в roles.
+// * Должен стоять после New
+func RequireRole(log *slog.Logger, roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "middleware.auth.RequireRole"
+
+			principal, ok := PrincipalFrom(r.Context())
+			if !ok {
+				log.Error("Principal not found in context",
+					slog.String("op", op),
+					slog.String("path", r.URL.Path),
+				)
+
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("Unauthorized"))
+
+				return
+			}
+
+			if !slices.Contains(roles, principal.Role) {
+				log.Warn("Insufficient role",
+					slog.String("op", op),
+					slog.Int64("user_id", principal.UserID),
+					slog.String("role", principal.Role),
+					slog.String("path", r.URL.Path),
+				)
+
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error("Forbidden"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package authMiddlware_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"main_service/internal/lib/authclient"
+	"main_service/internal/lib/jwt"
+	"main_service/internal/lib/sessions"
+	authMiddlware "main_service/internal/middleware/auth"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+)
+
+// * fakeAuthService отдаёт JWKS и отвечает на /sessions/current так же,
+// * как auth_service: 401 для токена завершённой сессии
+type fakeAuthService struct {
+	private ed25519.PrivateKey
+
+	mu       sync.Mutex
+	revoked  map[string]bool
+	checks   int
+	failures bool
+}
+
+func newFakeAuthService(t *testing.T) (*fakeAuthService, *httptest.Server) {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	a := &fakeAuthService{private: private, revoked: make(map[string]bool)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/jwks.json", a.serveJWKS)
+	mux.HandleFunc("/sessions/current", a.serveSession)
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return a, srv
+}
+
+func (a *fakeAuthService) serveJWKS(w http.ResponseWriter, _ *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(a.private.Public().(ed25519.PublicKey)),
+			"kid": "test",
+		}},
+	})
+}
+
+func (a *fakeAuthService) serveSession(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.checks++
+
+	if a.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	claims := jwtlib.MapClaims{}
+	_, err := jwtlib.ParseWithClaims(r.Header.Get("Authorization")[len("Bearer "):], claims,
+		func(*jwtlib.Token) (any, error) { return a.private.Public(), nil },
+	)
+	if err != nil || a.revoked[claims["sid"].(string)] {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]any{"status": "OK", "session_id": claims["sid"]})
+}
+
+func (a *fakeAuthService) set(f func(a *fakeAuthService)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f(a)
+}
+
+func (a *fakeAuthService) token(t *testing.T, sid string) string {
+	t.Helper()
+
+	token := jwtlib.NewWithClaims(jwtlib.SigningMethodEdDSA, jwtlib.MapClaims{
+		"uid":   7,
+		"email": "user@example.com",
+		"role":  "user",
+		"sid":   sid,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "test"
+
+	signed, err := token.SignedString(a.private)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	return "Bearer " + signed
+}
+
+func TestRevokedSessionRejected(t *testing.T) {
+	const ttl = 100 * time.Millisecond
+
+	auth, srv := newFakeAuthService(t)
+
+	parser := jwt.New(jwt.NewJWKSCache(srv.URL+"/.well-known/jwks.json", srv.Client(), time.Hour))
+	cache := sessions.New(authclient.New(srv.URL, srv.Client()), ttl)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := authMiddlware.New(log, parser, cache)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	do := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/products", nil)
+		req.Header.Set("Authorization", token)
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	token := auth.token(t, "session")
+
+	if code := do(token); code != http.StatusOK {
+		t.Fatalf("active session: status = %d, want 200", code)
+	}
+
+	// * пользователь вышел: до истечения ttl ответ берётся из кеша
+	auth.set(func(a *fakeAuthService) { a.revoked["session"] = true })
+
+	if code := do(token); code != http.StatusOK {
+		t.Fatalf("cached session: status = %d, want 200", code)
+	}
+
+	time.Sleep(ttl)
+
+	if code := do(token); code != http.StatusUnauthorized {
+		t.Fatalf("revoked session: status = %d, want 401", code)
+	}
+
+	// * другие сессии пользователя не затронуты
+	if code := do(auth.token(t, "other")); code != http.StatusOK {
+		t.Errorf("other session: status = %d, want 200", code)
+	}
+
+	auth.set(func(a *fakeAuthService) {
+		if a.checks != 3 {
+			t.Errorf("auth_service checked sessions %d times, want 3", a.checks)
+		}
+	})
+}
+
+func TestSessionCheckFailure(t *testing.T) {
+	auth, srv := newFakeAuthService(t)
+	auth.set(func(a *fakeAuthService) { a.failures = true })
+
+	parser := jwt.New(jwt.NewJWKSCache(srv.URL+"/.well-known/jwks.json", srv.Client(), time.Hour))
+	cache := sessions.New(authclient.New(srv.URL, srv.Client()), time.Minute)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := authMiddlware.New(log, parser, cache)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/products", nil)
+	req.Header.Set("Authorization", auth.token(t, "session"))
+	rec := httptest.NewRecorder()
+
+	h.ServeHTTP(rec, req)
+
+	// * недоступный auth_service не должен ни пропускать, ни разлогинивать
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+}
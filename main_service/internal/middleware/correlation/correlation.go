@@ -0,0 +1,21 @@
+package correlation
+
+import (
+	"net/http"
+
+	"messaging/envelope"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+// * New передаёт request id запроса в контекст как correlation_id: сообщения,
+// * опубликованные при обработке запроса, можно связать с ним в логах.
+// * Подключается после middleware.RequestID
+func New() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := envelope.WithCorrelationID(r.Context(), middleware.GetReqID(r.Context()))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
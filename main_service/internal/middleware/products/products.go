@@ -2,9 +2,7 @@ package products
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"time"
 
 	"main_service/internal/models"
@@ -17,30 +15,44 @@ type RedisStorage interface {
 }
 
 type PostgresStorage interface {
-	SaveProduct(ctx context.Context, userID int64, productURL, title string, marketplace models.Marketplace) (int64, error)
+	SaveProduct(
+		ctx context.Context,
+		userID int64,
+		productURL, title string,
+		marketplace models.Marketplace,
+	) (int64, error)
 	ProductByID(ctx context.Context, productID int64) (models.Product, error)
 }
 
-type RabbitMQProducer interface {
-	PublishJSON(ctx context.Context, msg any) error
+// * OutboxTrigger будит relay outbox после записи задания
+type OutboxTrigger interface {
+	Trigger()
 }
 
 type ProductOperator struct {
-	CheckInterval    time.Duration
-	Redis            RedisStorage
-	Postgres         PostgresStorage
-	RabbitMQProducer RabbitMQProducer
+	CheckInterval time.Duration
+	Redis         RedisStorage
+	Postgres      PostgresStorage
+	Outbox        OutboxTrigger
 }
 
-func New(p PostgresStorage, r RedisStorage, rabbit RabbitMQProducer, checkInterval time.Duration) *ProductOperator {
+func New(
+	p PostgresStorage,
+	r RedisStorage,
+	outbox OutboxTrigger,
+	checkInterval time.Duration,
+) *ProductOperator {
 	return &ProductOperator{
-		CheckInterval:    checkInterval,
-		Redis:            r,
-		Postgres:         p,
-		RabbitMQProducer: rabbit,
+		CheckInterval: checkInterval,
+		Redis:         r,
+		Postgres:      p,
+		Outbox:        outbox,
 	}
 }
 
+// * SaveProduct сохраняет продукт вместе с заданием на парсинг в outbox.
+// * Задание публикует relay, поэтому недоступный брокер не приводит к ошибке:
+// * продукт будет спарсен, когда брокер вернётся
 func (p *ProductOperator) SaveProduct(
 	ctx context.Context,
 	url, title string,
@@ -52,21 +64,7 @@ func (p *ProductOperator) SaveProduct(
 		return 0, err
 	}
 
-	product := models.ProductForProducer{
-		ID:          productID,
-		URL:         url,
-		Marketplace: marketplace,
-	}
-
-	data, err := json.Marshal(product)
-	if err != nil {
-		return 0, fmt.Errorf("failed to serialize product: %w", err)
-	}
-
-	err = p.RabbitMQProducer.PublishJSON(ctx, data)
-	if err != nil {
-		return 0, err
-	}
+	p.Outbox.Trigger()
 
 	return productID, nil
 }
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Marketplace string
 
@@ -12,11 +15,15 @@ const (
 
 type Product struct {
 	ID           int64       `json:"id"`
+	UserID       int64       `json:"-"`
+	URL          string      `json:"url"`
 	Title        string      `json:"title"`
 	Marketplace  Marketplace `json:"marketplace"`
 	Price        int         `json:"price"`
+	Currency     string      `json:"currency"`
 	In_stock     bool        `json:"in_stock"`
-	Last_checked time.Time   `json:"last_checked"`
+	NotifyStock  bool        `json:"notify_back_in_stock"`
+	Last_checked *time.Time  `json:"last_checked"` // * nil, пока продукт ни разу не проверен
 	Created_at   time.Time   `json:"created_at"`
 	Updated_at   time.Time   `json:"updated_at"`
 }
@@ -28,7 +35,208 @@ type ProductForProducer struct {
 }
 
 type ParsedProduct struct {
-	ID       int64 `json:"id"`
-	Price    int   `json:"price"`
-	In_stock bool  `json:"in_stock"`
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Price    int    `json:"price"`
+	Currency string `json:"currency"`
+	In_stock bool   `json:"in_stock"`
+	Error    string `json:"error,omitempty"` // * непусто, если страницу не удалось распарсить
+}
+
+type PricePoint struct {
+	Price      int       `json:"price"`
+	Currency   string    `json:"currency"`
+	InStock    bool      `json:"in_stock"`
+	ObservedAt time.Time `json:"observed_at"`
+}
+
+type AlertType string
+
+const (
+	AlertPriceBelow  AlertType = "price_below"   // * цена не выше порога
+	AlertPercentDrop AlertType = "percent_drop"  // * падение на threshold% с прошлой проверки
+	AlertBelow30dMin AlertType = "below_30d_min" // * цена ниже минимума за 30 дней
+)
+
+type AlertRule struct {
+	ID              int64      `json:"id"`
+	UserID          int64      `json:"-"`
+	ProductID       int64      `json:"product_id"`
+	Type            AlertType  `json:"type"`
+	Threshold       int        `json:"threshold"`
+	Triggered       bool       `json:"triggered"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// * PriceUpdate описывает применённый ParsedProduct: состояние продукта до и после
+type PriceUpdate struct {
+	ProductID   int64
+	UserID      int64
+	Title       string
+	URL         string
+	WasChecked  bool // * продукт уже проверялся, Old* содержат реальные значения
+	OldPrice    int
+	OldInStock  bool
+	NewPrice    int
+	NewInStock  bool
+	Currency    string
+	MinPrice30d *int // * минимальная цена за 30 дней до этого наблюдения
+	BackInStock bool // * товар появился в наличии после проверки, где его не было
+	NotifyStock bool // * пользователь подписан на уведомление о появлении в наличии
+	CheckedAt   time.Time
+
+	StockNotified  bool // * письмо о появлении в наличии поставлено в outbox
+	AlertsNotified bool // * письма о сработавших правилах поставлены в outbox
+}
+
+// * UserContact — адрес и настройки локализации уведомлений пользователя
+type UserContact struct {
+	UserID   int64
+	Email    string
+	Locale   string
+	Timezone string
+	Currency string // * предпочитаемая валюта, пустая — не задана
+}
+
+// * Notification публикуется в очередь email_sender
+type Notification struct {
+	Type     string `json:"type"`
+	To       string `json:"to"`
+	Locale   string `json:"locale,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	Data     any    `json:"data"`
+}
+
+// * Notification собирает письмо для контакта. Если парсер не определил
+// * валюту цены, подставляется предпочитаемая валюта пользователя. Цены не
+// * пересчитываются: курс сервису неизвестен
+func (c UserContact) Notification(notificationType string, data any) Notification {
+	if c.Currency != "" {
+		switch d := data.(type) {
+		case PriceAlertData:
+			if d.Currency == "" {
+				d.Currency = c.Currency
+			}
+			data = d
+		case BackInStockData:
+			if d.Currency == "" {
+				d.Currency = c.Currency
+			}
+			data = d
+		}
+	}
+
+	return Notification{
+		Type:     notificationType,
+		To:       c.Email,
+		Locale:   c.Locale,
+		Timezone: c.Timezone,
+		Data:     data,
+	}
+}
+
+const (
+	NotificationPriceAlert  = "price_alert"
+	NotificationBackInStock = "back_in_stock"
+)
+
+type PriceAlertData struct {
+	ProductID int64     `json:"product_id"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	AlertType AlertType `json:"alert_type"`
+	Threshold int       `json:"threshold"`
+	OldPrice  int       `json:"old_price"`
+	NewPrice  int       `json:"new_price"`
+	Currency  string    `json:"currency"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type BackInStockData struct {
+	ProductID int64     `json:"product_id"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	Price     int       `json:"price"`
+	Currency  string    `json:"currency"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// * MarketplaceParseStats — состояние парсинга продуктов одного маркетплейса
+type MarketplaceParseStats struct {
+	Marketplace   Marketplace `json:"marketplace"`
+	Products      int64       `json:"products"`
+	Failing       int64       `json:"failing"`
+	FailedLast24h int64       `json:"failed_last_24h"`
+	InFlight      int64       `json:"in_flight"`
+}
+
+// * FailingProduct — продукт, последние проверки которого завершились ошибкой
+type FailingProduct struct {
+	ID           int64       `json:"id"`
+	UserID       int64       `json:"user_id"`
+	URL          string      `json:"url"`
+	Marketplace  Marketplace `json:"marketplace"`
+	Failures     int         `json:"failures"`
+	LastError    string      `json:"last_error"`
+	LastFailedAt time.Time   `json:"last_failed_at"`
+}
+
+type ParserStats struct {
+	Marketplaces    []MarketplaceParseStats `json:"marketplaces"`
+	FailingProducts []FailingProduct        `json:"failing_products"`
+}
+
+const (
+	EventUserDeleted = "user.deleted"
+	EventUserUpdated = "user.updated"
+)
+
+// * UserEvent — событие об аккаунте, публикуемое auth_service
+type UserEvent struct {
+	Type       string            `json:"type"`
+	UserID     int64             `json:"user_id"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Profile    *UserEventProfile `json:"profile,omitempty"` // * только для user.updated
+}
+
+// * UserEventProfile — профиль пользователя на момент события user.updated
+type UserEventProfile struct {
+	Email       string `json:"email"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Locale      string `json:"locale"`
+	Timezone    string `json:"timezone"`
+	Currency    string `json:"currency"`
+}
+
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportRunning ExportStatus = "running"
+	ExportDone    ExportStatus = "done"
+	ExportFailed  ExportStatus = "failed"
+)
+
+// * ExportJob — фоновая выгрузка персональных данных пользователя
+type ExportJob struct {
+	ID         string       `json:"id"`
+	UserID     int64        `json:"-"`
+	Status     ExportStatus `json:"status"`
+	Error      string       `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time   `json:"expires_at,omitempty"`
+}
+
+// * NotificationRecord — запись журнала отправленных уведомлений
+type NotificationRecord struct {
+	ID          int64           `json:"id"`
+	ProductID   *int64          `json:"product_id"`
+	AlertRuleID *int64          `json:"alert_rule_id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
 }
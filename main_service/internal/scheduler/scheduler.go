@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	sl "main_service/internal/lib/logger"
+	"main_service/internal/models"
+)
+
+type Storage interface {
+	ClaimDueProducts(ctx context.Context, checkInterval, inFlightTimeout time.Duration, limit int) ([]models.ProductForProducer, error)
+	ReleaseProducts(ctx context.Context, productIDs []int64) error
+}
+
+type Producer interface {
+	PublishJSON(ctx context.Context, msg any) error
+}
+
+// * Scheduler периодически ставит в очередь на парсинг продукты,
+// * которые не проверялись дольше CheckInterval
+type Scheduler struct {
+	log             *slog.Logger
+	storage         Storage
+	producer        Producer
+	checkInterval   time.Duration
+	pollInterval    time.Duration
+	inFlightTimeout time.Duration
+	batchSize       int
+}
+
+func New(
+	log *slog.Logger,
+	storage Storage,
+	producer Producer,
+	checkInterval, pollInterval, inFlightTimeout time.Duration,
+	batchSize int,
+) *Scheduler {
+	return &Scheduler{
+		log:             log,
+		storage:         storage,
+		producer:        producer,
+		checkInterval:   checkInterval,
+		pollInterval:    pollInterval,
+		inFlightTimeout: inFlightTimeout,
+		batchSize:       batchSize,
+	}
+}
+
+// * Run блокируется до отмены контекста
+func (s *Scheduler) Run(ctx context.Context) {
+	const op = "scheduler.Run"
+
+	log := s.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.enqueueDue(ctx, log)
+
+		select {
+		case <-ctx.Done():
+			log.Info("scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// * enqueueDue публикует пачки продуктов, пока очередь просроченных не опустеет
+func (s *Scheduler) enqueueDue(ctx context.Context, log *slog.Logger) {
+	for ctx.Err() == nil {
+		products, err := s.storage.ClaimDueProducts(ctx, s.checkInterval, s.inFlightTimeout, s.batchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error("failed to claim due products", sl.Err(err))
+			}
+			return
+		}
+
+		if len(products) == 0 {
+			return
+		}
+
+		published, err := s.publish(ctx, products)
+		if err != nil {
+			log.Error("failed to publish products",
+				sl.Err(err),
+				slog.Int("published", published),
+				slog.Int("claimed", len(products)),
+			)
+
+			s.release(log, products[published:])
+
+			return
+		}
+
+		log.Info("products queued for re-check", slog.Int("count", published))
+
+		if len(products) < s.batchSize {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) publish(ctx context.Context, products []models.ProductForProducer) (int, error) {
+	for i, product := range products {
+		if err := s.producer.PublishJSON(ctx, product); err != nil {
+			return i, err
+		}
+	}
+
+	return len(products), nil
+}
+
+// * release снимает отметку с неопубликованных продуктов, чтобы они попали
+// * в следующий проход, а не ждали истечения inFlightTimeout. Контекст отдельный:
+// * основной может быть уже отменён при остановке сервиса
+func (s *Scheduler) release(log *slog.Logger, products []models.ProductForProducer) {
+	if len(products) == 0 {
+		return
+	}
+
+	ids := make([]int64, 0, len(products))
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.storage.ReleaseProducts(ctx, ids); err != nil {
+		log.Error("failed to release products", sl.Err(err), slog.Int("count", len(ids)))
+	}
+}
@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"main_service/internal/models"
+)
+
+// * fakeStorage повторяет семантику ClaimDueProducts: взятый продукт не выдаётся
+// * повторно, пока его не освободят
+type fakeStorage struct {
+	mu       sync.Mutex
+	due      []int64
+	claimed  map[int64]bool
+	claims   []int
+	released []int64
+}
+
+func newFakeStorage(ids ...int64) *fakeStorage {
+	return &fakeStorage{due: ids, claimed: make(map[int64]bool)}
+}
+
+func (s *fakeStorage) ClaimDueProducts(_ context.Context, _, _ time.Duration, limit int) ([]models.ProductForProducer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var products []models.ProductForProducer
+	for _, id := range s.due {
+		if len(products) == limit {
+			break
+		}
+
+		if s.claimed[id] {
+			continue
+		}
+
+		s.claimed[id] = true
+		products = append(products, models.ProductForProducer{ID: id, URL: "https://www.etsy.com/listing/1", Marketplace: models.Etsy})
+	}
+
+	s.claims = append(s.claims, len(products))
+
+	return products, nil
+}
+
+func (s *fakeStorage) ReleaseProducts(_ context.Context, productIDs []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range productIDs {
+		delete(s.claimed, id)
+	}
+
+	s.released = append(s.released, productIDs...)
+
+	return nil
+}
+
+// * fakeProducer отказывает, начиная с публикации номер failAt (с единицы)
+type fakeProducer struct {
+	mu        sync.Mutex
+	published []int64
+	calls     int
+	failAt    int
+}
+
+var errBroker = errors.New("broker unavailable")
+
+func (p *fakeProducer) PublishJSON(_ context.Context, msg any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls++
+	if p.failAt > 0 && p.calls >= p.failAt {
+		return errBroker
+	}
+
+	p.published = append(p.published, msg.(models.ProductForProducer).ID)
+
+	return nil
+}
+
+func newScheduler(storage Storage, producer Producer, batchSize int) *Scheduler {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return New(log, storage, producer, 30*time.Minute, time.Hour, 15*time.Minute, batchSize)
+}
+
+func TestEnqueueDueClaimsInBatches(t *testing.T) {
+	storage := newFakeStorage(1, 2, 3, 4, 5)
+	producer := &fakeProducer{}
+
+	s := newScheduler(storage, producer, 2)
+	s.enqueueDue(context.Background(), s.log)
+
+	if want := []int64{1, 2, 3, 4, 5}; !slices.Equal(producer.published, want) {
+		t.Errorf("published = %v, want %v", producer.published, want)
+	}
+
+	// * неполная пачка означает, что просроченных больше нет
+	if want := []int{2, 2, 1}; !slices.Equal(storage.claims, want) {
+		t.Errorf("claim batch sizes = %v, want %v", storage.claims, want)
+	}
+
+	// * повторный проход не ставит в очередь уже взятые продукты
+	s.enqueueDue(context.Background(), s.log)
+
+	if len(producer.published) != 5 {
+		t.Errorf("second pass published %d more products", len(producer.published)-5)
+	}
+}
+
+func TestEnqueueDueReleasesUnpublished(t *testing.T) {
+	storage := newFakeStorage(1, 2, 3, 4, 5)
+	producer := &fakeProducer{failAt: 4}
+
+	s := newScheduler(storage, producer, 3)
+	s.enqueueDue(context.Background(), s.log)
+
+	if want := []int64{1, 2, 3}; !slices.Equal(producer.published, want) {
+		t.Errorf("published = %v, want %v", producer.published, want)
+	}
+
+	// * сбой на первом продукте второй пачки освобождает всю её неопубликованную часть
+	if want := []int64{4, 5}; !slices.Equal(storage.released, want) {
+		t.Errorf("released = %v, want %v", storage.released, want)
+	}
+
+	// * после восстановления брокера освобождённые продукты уходят в следующем проходе
+	producer.failAt = 0
+	s.enqueueDue(context.Background(), s.log)
+
+	if want := []int64{1, 2, 3, 4, 5}; !slices.Equal(producer.published, want) {
+		t.Errorf("published after recovery = %v, want %v", producer.published, want)
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	storage := newFakeStorage(1)
+	producer := &fakeProducer{}
+
+	s := newScheduler(storage, producer, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
@@ -0,0 +1,342 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"main_service/internal/models"
+	"main_service/internal/storage"
+	"messaging/envelope"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// * SaveAlertRule добавляет правило оповещения к продукту пользователя
+func (r *PostgresRepo) SaveAlertRule(
+	ctx context.Context,
+	userID, productID int64,
+	alertType models.AlertType,
+	threshold int,
+) (int64, error) {
+	const op = "storage.postgres.SaveAlertRule"
+
+	const query = `
+		INSERT INTO alert_rules (user_id, product_id, type, threshold)
+		SELECT $1, $2, $3, $4
+		WHERE EXISTS (SELECT 1 FROM products WHERE id = $2 AND user_id = $1)
+		RETURNING id
+	`
+
+	var id int64
+
+	err := r.pool.QueryRow(ctx, query, userID, productID, alertType, threshold).Scan(&id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, storage.ErrProductsNotFound
+		}
+
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// * AlertRules возвращает правила пользователя. productID == 0 — правила всех продуктов
+func (r *PostgresRepo) AlertRules(ctx context.Context, userID, productID int64) ([]models.AlertRule, error) {
+	const op = "storage.postgres.AlertRules"
+
+	const query = `
+		SELECT id, user_id, product_id, type, threshold, triggered, last_triggered_at, created_at, updated_at
+		FROM alert_rules
+		WHERE user_id = $1
+			AND ($2 = 0 OR product_id = $2)
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID, productID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: query: %w", op, err)
+	}
+
+	rules, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.AlertRule])
+	if err != nil {
+		return nil, fmt.Errorf("%s: collect: %w", op, err)
+	}
+
+	return rules, nil
+}
+
+func (r *PostgresRepo) AlertRule(ctx context.Context, ruleID, userID int64) (models.AlertRule, error) {
+	const op = "storage.postgres.AlertRule"
+
+	const query = `
+		SELECT id, user_id, product_id, type, threshold, triggered, last_triggered_at, created_at, updated_at
+		FROM alert_rules
+		WHERE id = $1 AND user_id = $2
+	`
+
+	rows, err := r.pool.Query(ctx, query, ruleID, userID)
+	if err != nil {
+		return models.AlertRule{}, fmt.Errorf("%s: query: %w", op, err)
+	}
+
+	rule, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[models.AlertRule])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.AlertRule{}, storage.ErrAlertRuleNotFound
+		}
+
+		return models.AlertRule{}, fmt.Errorf("%s: collect: %w", op, err)
+	}
+
+	return rule, nil
+}
+
+// * UpdateAlertRule меняет порог правила и сбрасывает его состояние срабатывания
+func (r *PostgresRepo) UpdateAlertRule(ctx context.Context, ruleID, userID int64, threshold int) error {
+	const op = "storage.postgres.UpdateAlertRule"
+
+	const query = `
+		UPDATE alert_rules
+		SET threshold = $1,
+			triggered = FALSE,
+			updated_at = now()
+		WHERE id = $2 AND user_id = $3
+	`
+
+	cmd, err := r.pool.Exec(ctx, query, threshold, ruleID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return storage.ErrAlertRuleNotFound
+	}
+
+	return nil
+}
+
+// * DeleteAlertRule удаляет правило пользователя
+func (r *PostgresRepo) DeleteAlertRule(ctx context.Context, ruleID, userID int64) error {
+	const op = "storage.postgres.DeleteAlertRule"
+
+	const query = `
+		DELETE FROM alert_rules
+		WHERE id = $1 AND user_id = $2
+	`
+
+	cmd, err := r.pool.Exec(ctx, query, ruleID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return storage.ErrAlertRuleNotFound
+	}
+
+	return nil
+}
+
+// * alertRules выполняет операции с правилами в транзакции UpdateParsedData:
+// * срабатывание, письмо и результат парсинга сохраняются вместе
+type alertRules struct {
+	tx pgx.Tx
+}
+
+// * ProductAlertRules возвращает все правила продукта для проверки после парсинга
+func (a alertRules) ProductAlertRules(ctx context.Context, productID int64) ([]models.AlertRule, error) {
+	const op = "storage.postgres.ProductAlertRules"
+
+	const query = `
+		SELECT id, user_id, product_id, type, threshold, triggered, last_triggered_at, created_at, updated_at
+		FROM alert_rules
+		WHERE product_id = $1
+	`
+
+	rows, err := a.tx.Query(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: query: %w", op, err)
+	}
+
+	rules, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.AlertRule])
+	if err != nil {
+		return nil, fmt.Errorf("%s: collect: %w", op, err)
+	}
+
+	return rules, nil
+}
+
+// * TriggerAlertRule отмечает срабатывание правила и ставит письмо в outbox.
+// * Возвращает false, если правило уже сработало (флаг изменил другой
+// * обработчик): письмо ставит только он
+func (a alertRules) TriggerAlertRule(ctx context.Context, rule models.AlertRule, data models.PriceAlertData) (bool, error) {
+	const op = "storage.postgres.TriggerAlertRule"
+
+	const query = `
+		UPDATE alert_rules
+		SET triggered = TRUE,
+			last_triggered_at = now(),
+			updated_at = now()
+		WHERE id = $1 AND NOT triggered
+	`
+
+	cmd, err := a.tx.Exec(ctx, query, rule.ID)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := enqueueNotification(ctx, a.tx, rule.UserID, rule.ProductID, rule.ID, models.NotificationPriceAlert, data); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return true, nil
+}
+
+// * ResetAlertTriggered снимает отметку срабатывания, когда условие правила
+// * перестало выполняться. Возвращает false, если её уже снял другой обработчик
+func (a alertRules) ResetAlertTriggered(ctx context.Context, ruleID int64) (bool, error) {
+	const op = "storage.postgres.ResetAlertTriggered"
+
+	const query = `
+		UPDATE alert_rules
+		SET triggered = FALSE,
+			updated_at = now()
+		WHERE id = $1 AND triggered
+	`
+
+	cmd, err := a.tx.Exec(ctx, query, ruleID)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cmd.RowsAffected() == 1, nil
+}
+
+// * SaveUserContact сохраняет email пользователя из access токена. После первого
+// * события user.updated адрес ведёт auth_service: токен, выданный до смены email,
+// * не должен возвращать старый адрес. Контакт удалённого пользователя не сохраняется
+func (r *PostgresRepo) SaveUserContact(ctx context.Context, userID int64, email string) error {
+	const op = "storage.postgres.SaveUserContact"
+
+	const query = `
+		INSERT INTO user_contacts (user_id, email)
+		SELECT $1, $2
+		WHERE NOT EXISTS (SELECT 1 FROM deleted_users WHERE user_id = $1)
+		ON CONFLICT (user_id) DO UPDATE
+		SET email = EXCLUDED.email,
+			updated_at = now()
+		WHERE user_contacts.profile_updated_at IS NULL
+	`
+
+	if _, err := r.pool.Exec(ctx, query, userID, email); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * SaveUserProfile применяет профиль из события user.updated. События могут
+// * прийти не по порядку, поэтому профиль старше уже применённого отбрасывается,
+// * а запоздавший профиль удалённого пользователя не применяется
+func (r *PostgresRepo) SaveUserProfile(
+	ctx context.Context,
+	userID int64,
+	profile models.UserEventProfile,
+	occurredAt time.Time,
+) (bool, error) {
+	const op = "storage.postgres.SaveUserProfile"
+
+	const query = `
+		INSERT INTO user_contacts (user_id, email, locale, timezone, currency, profile_updated_at)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE NOT EXISTS (SELECT 1 FROM deleted_users WHERE user_id = $1)
+		ON CONFLICT (user_id) DO UPDATE
+		SET email = EXCLUDED.email,
+			locale = EXCLUDED.locale,
+			timezone = EXCLUDED.timezone,
+			currency = EXCLUDED.currency,
+			profile_updated_at = EXCLUDED.profile_updated_at,
+			updated_at = now()
+		WHERE user_contacts.profile_updated_at IS NULL
+			OR user_contacts.profile_updated_at < EXCLUDED.profile_updated_at
+	`
+
+	tag, err := r.pool.Exec(ctx, query,
+		userID, profile.Email, profile.Locale, profile.Timezone, profile.Currency, occurredAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// * enqueueNotification в транзакции срабатывания кладёт письмо в outbox
+// * и запись в журнал уведомлений: письмо уходит, только если срабатывание
+// * сохранено, и не теряется, пока брокер недоступен. Пользователю без
+// * контакта писать некуда — возвращается false
+func enqueueNotification(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID, productID, alertRuleID int64,
+	notificationType string,
+	data any,
+) (bool, error) {
+	const op = "storage.postgres.enqueueNotification"
+
+	const contactQuery = `
+		SELECT user_id, email, locale, timezone, currency
+		FROM user_contacts
+		WHERE user_id = $1
+	`
+
+	var c models.UserContact
+
+	err := tx.QueryRow(ctx, contactQuery, userID).Scan(&c.UserID, &c.Email, &c.Locale, &c.Timezone, &c.Currency)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("%s: contact: %w", op, err)
+	}
+
+	notification := c.Notification(notificationType, data)
+
+	payload, err := envelope.Marshal(envelope.TypeEmailRequested, notification, envelope.CorrelationID(ctx))
+	if err != nil {
+		return false, fmt.Errorf("%s: marshal email: %w", op, err)
+	}
+
+	const outboxQuery = `
+		INSERT INTO outbox (payload, product_id)
+		VALUES ($1, $2)
+	`
+
+	if _, err := tx.Exec(ctx, outboxQuery, payload, productID); err != nil {
+		return false, fmt.Errorf("%s: save outbox message: %w", op, err)
+	}
+
+	logPayload, err := json.Marshal(notification.Data)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	const logQuery = `
+		INSERT INTO notifications (user_id, product_id, alert_rule_id, type, payload)
+		VALUES ($1, $2, NULLIF($3::bigint, 0), $4, $5)
+	`
+
+	if _, err := tx.Exec(ctx, logQuery, userID, productID, alertRuleID, notification.Type, logPayload); err != nil {
+		return false, fmt.Errorf("%s: save notification: %w", op, err)
+	}
+
+	return true, nil
+}
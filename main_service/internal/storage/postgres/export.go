@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"main_service/internal/models"
+	"main_service/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// * Notifications возвращает журнал уведомлений пользователя
+func (r *PostgresRepo) Notifications(ctx context.Context, userID int64) ([]models.NotificationRecord, error) {
+	const op = "storage.postgres.Notifications"
+
+	const query = `
+		SELECT id, product_id, alert_rule_id, type, payload, created_at
+		FROM notifications
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: query: %w", op, err)
+	}
+
+	records, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.NotificationRecord])
+	if err != nil {
+		return nil, fmt.Errorf("%s: collect: %w", op, err)
+	}
+
+	return records, nil
+}
+
+// * CreateExportJob ставит выгрузку в очередь фонового обработчика
+func (r *PostgresRepo) CreateExportJob(ctx context.Context, userID int64, account []byte) (models.ExportJob, error) {
+	const op = "storage.postgres.CreateExportJob"
+
+	const query = `
+		INSERT INTO export_jobs (user_id, account)
+		VALUES ($1, $2)
+		RETURNING id::text, user_id, status, created_at
+	`
+
+	var job models.ExportJob
+
+	err := r.pool.QueryRow(ctx, query, userID, account).Scan(&job.ID, &job.UserID, &job.Status, &job.CreatedAt)
+	if err != nil {
+		return models.ExportJob{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return job, nil
+}
+
+// * ClaimExportJob забирает самое старое ожидающее задание. Задание в статусе
+// * running дольше staleAfter считается брошенным упавшим обработчиком
+// * и забирается снова. Если заданий нет, возвращает storage.ErrExportJobNotFound
+func (r *PostgresRepo) ClaimExportJob(ctx context.Context, staleAfter time.Duration) (models.ExportJob, []byte, error) {
+	const op = "storage.postgres.ClaimExportJob"
+
+	const query = `
+		UPDATE export_jobs j
+		SET status = 'running',
+			started_at = now()
+		FROM (
+			SELECT id
+			FROM export_jobs
+			WHERE status = 'pending'
+				OR (status = 'running' AND started_at < now() - make_interval(secs => $1))
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		) next
+		WHERE j.id = next.id
+		RETURNING j.id::text, j.user_id, j.status, j.created_at, j.account
+	`
+
+	var (
+		job     models.ExportJob
+		account []byte
+	)
+
+	err := r.pool.QueryRow(ctx, query, staleAfter.Seconds()).Scan(&job.ID, &job.UserID, &job.Status, &job.CreatedAt, &account)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ExportJob{}, nil, storage.ErrExportJobNotFound
+		}
+
+		return models.ExportJob{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return job, account, nil
+}
+
+// * CompleteExportJob сохраняет готовый архив, доступный до expiresAt
+func (r *PostgresRepo) CompleteExportJob(ctx context.Context, jobID string, archive []byte, expiresAt time.Time) error {
+	const op = "storage.postgres.CompleteExportJob"
+
+	const query = `
+		UPDATE export_jobs
+		SET status = 'done',
+			archive = $2,
+			account = '{}'::jsonb,
+			finished_at = now(),
+			expires_at = $3
+		WHERE id = $1
+	`
+
+	if _, err := r.pool.Exec(ctx, query, jobID, archive, expiresAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * FailExportJob отмечает задание неудачным
+func (r *PostgresRepo) FailExportJob(ctx context.Context, jobID, reason string) error {
+	const op = "storage.postgres.FailExportJob"
+
+	const query = `
+		UPDATE export_jobs
+		SET status = 'failed',
+			error = $2,
+			account = '{}'::jsonb,
+			finished_at = now()
+		WHERE id = $1
+	`
+
+	if _, err := r.pool.Exec(ctx, query, jobID, reason); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * ExportJob возвращает задание пользователя без архива
+func (r *PostgresRepo) ExportJob(ctx context.Context, jobID string, userID int64) (models.ExportJob, error) {
+	const op = "storage.postgres.ExportJob"
+
+	const query = `
+		SELECT id::text, user_id, status, COALESCE(error, ''), created_at, finished_at, expires_at
+		FROM export_jobs
+		WHERE id = $1 AND user_id = $2
+			AND (expires_at IS NULL OR expires_at > now())
+	`
+
+	var job models.ExportJob
+
+	err := r.pool.QueryRow(ctx, query, jobID, userID).Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Status,
+		&job.Error,
+		&job.CreatedAt,
+		&job.FinishedAt,
+		&job.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ExportJob{}, storage.ErrExportJobNotFound
+		}
+
+		return models.ExportJob{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return job, nil
+}
+
+// * ExportArchive возвращает готовый архив задания пользователя. Для незавершённого
+// * задания возвращает storage.ErrExportNotReady
+func (r *PostgresRepo) ExportArchive(ctx context.Context, jobID string, userID int64) ([]byte, error) {
+	const op = "storage.postgres.ExportArchive"
+
+	const query = `
+		SELECT status, archive
+		FROM export_jobs
+		WHERE id = $1 AND user_id = $2
+			AND (expires_at IS NULL OR expires_at > now())
+	`
+
+	var (
+		status  models.ExportStatus
+		archive []byte
+	)
+
+	err := r.pool.QueryRow(ctx, query, jobID, userID).Scan(&status, &archive)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, storage.ErrExportJobNotFound
+		}
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if status != models.ExportDone {
+		return nil, storage.ErrExportNotReady
+	}
+
+	return archive, nil
+}
+
+// * DeleteExpiredExportJobs удаляет задания с истёкшим сроком хранения архива
+func (r *PostgresRepo) DeleteExpiredExportJobs(ctx context.Context) (int64, error) {
+	const op = "storage.postgres.DeleteExpiredExportJobs"
+
+	const query = `
+		DELETE FROM export_jobs
+		WHERE expires_at < now()
+			OR (status = 'failed' AND finished_at < now() - interval '7 days')
+	`
+
+	cmd, err := r.pool.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cmd.RowsAffected(), nil
+}
@@ -9,9 +9,10 @@ import (
 	"main_service/internal/config"
 	"main_service/internal/models"
 	"main_service/internal/storage"
+	"messaging/envelope"
 
-	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -46,7 +47,10 @@ func New(ctx context.Context, cfg *config.Config) (*PostgresRepo, error) {
 	return &PostgresRepo{pool: pool}, nil
 }
 
-// * SaveProduct добавляет продукт в базу данных
+// * SaveProduct добавляет продукт в базу данных и в той же транзакции кладёт
+// * задание на первый парсинг в outbox. Продукт сразу помечается поставленным
+// * в очередь, чтобы планировщик не отправил его второй раз. Удалённому
+// * пользователю, чей токен ещё действует, возвращается ErrUserDeleted
 func (r *PostgresRepo) SaveProduct(
 	ctx context.Context,
 	userID int64,
@@ -55,26 +59,72 @@ func (r *PostgresRepo) SaveProduct(
 ) (int64, error) {
 	const op = "storage.postgres.SaveProduct"
 
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin tx: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
 	const query = `
-		INSERT INTO products (user_id, url, title, marketplace)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO products (user_id, url, title, marketplace, queued_at)
+		SELECT $1, $2, $3, $4, now()
+		WHERE NOT EXISTS (SELECT 1 FROM deleted_users WHERE user_id = $1)
 		RETURNING id
 	`
 
 	var id int64
 
-	err := r.pool.QueryRow(ctx, query, userID, productURL, title, marketplace).Scan(&id)
+	err = tx.QueryRow(ctx, query, userID, productURL, title, marketplace).Scan(&id)
 	if err != nil {
-		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == storage.UniqueViolation {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, storage.ErrUserDeleted
+		}
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == storage.UniqueViolation {
 			return 0, storage.ErrUserAlreadyTracksProduct
 		}
 
 		return 0, fmt.Errorf("%s: failed to save product: %w", op, err)
 	}
 
+	task := models.ProductForProducer{ID: id, URL: productURL, Marketplace: marketplace}
+
+	if err := enqueueParseTask(ctx, tx, task); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: commit: %w", op, err)
+	}
+
 	return id, nil
 }
 
+// * enqueueParseTask кладёт задание на парсинг в outbox в транзакции,
+// * пометившей продукт поставленным в очередь
+func enqueueParseTask(ctx context.Context, tx pgx.Tx, task models.ProductForProducer) error {
+	const op = "storage.postgres.enqueueParseTask"
+
+	// * в outbox хранится готовое тело сообщения: id конверта не меняется
+	// * при повторной публикации, и получатель может отбросить дубликат
+	payload, err := envelope.Marshal(envelope.TypeParseRequested, task, envelope.CorrelationID(ctx))
+	if err != nil {
+		return fmt.Errorf("%s: marshal task: %w", op, err)
+	}
+
+	const query = `
+		INSERT INTO outbox (payload, product_id)
+		VALUES ($1, $2)
+	`
+
+	if _, err := tx.Exec(ctx, query, payload, task.ID); err != nil {
+		return fmt.Errorf("%s: save outbox message: %w", op, err)
+	}
+
+	return nil
+}
+
 // * Products возвращает слайс продуктов для вывода пользователю
 func (r *PostgresRepo) Products(ctx context.Context, userID, limit, offset int64) ([]models.Product, int64, error) {
 	const op = "storage.Postgres.Products"
@@ -95,7 +145,7 @@ func (r *PostgresRepo) Products(ctx context.Context, userID, limit, offset int64
 
 	// * Получаем продукты
 	query := `
-    SELECT id, url, title, marketplace, price, in_stock, user_id, last_checked, created_at, updated_at
+    SELECT id, url, title, marketplace, price, currency, in_stock, notify_back_in_stock AS notify_stock, user_id, last_checked, created_at, updated_at
       FROM products
       WHERE user_id = $1
       ORDER BY created_at DESC
@@ -133,7 +183,7 @@ func (r *PostgresRepo) ProductByID(ctx context.Context, productID int64) (models
 	const op = "storage.postgres.ProductByID"
 
 	const query = `
-		SELECT id, url, title, marketplace, price, in_stock, user_id, last_checked, created_at, updated_at
+		SELECT id, url, title, marketplace, price, currency, in_stock, notify_back_in_stock AS notify_stock, user_id, last_checked, created_at, updated_at
 		FROM products
 		WHERE id = $1
 	`
@@ -144,10 +194,14 @@ func (r *PostgresRepo) ProductByID(ctx context.Context, productID int64) (models
 
 	err := row.Scan(
 		&p.ID,
+		&p.URL,
 		&p.Title,
-		&p.Price,
 		&p.Marketplace,
+		&p.Price,
+		&p.Currency,
 		&p.In_stock,
+		&p.NotifyStock,
+		&p.UserID,
 		&p.Last_checked,
 		&p.Created_at,
 		&p.Updated_at,
@@ -164,19 +218,237 @@ func (r *PostgresRepo) ProductByID(ctx context.Context, productID int64) (models
 }
 
 // * UpdateParsedData добавляет информацию о цене и наличии продукта
-func (r *PostgresRepo) UpdateParsedData(ctx context.Context, productID int64, price int, inStock bool) error {
+// * и одним запросом сохраняет наблюдение в price_history. Строка продукта
+// * блокируется до обновления, поэтому возвращаемое прежнее состояние
+// * (и переход in_stock false -> true) согласовано с новым даже при
+// * параллельных обработчиках: второй увидит уже обновлённое значение.
+// * messageID — id конверта результата: повторная доставка того же результата
+// * возвращает ErrMessageAlreadyProcessed и ничего не меняет. Письмо о появлении
+// * в наличии и правила оповещений (evaluate, nil — не проверять) обрабатываются
+// * в той же транзакции: если проверка не удалась, результат не сохраняется
+// * и вернётся на повтор целиком
+func (r *PostgresRepo) UpdateParsedData(
+	ctx context.Context,
+	messageID string,
+	productID int64,
+	price int,
+	currency string,
+	inStock bool,
+	evaluate storage.EvaluateAlerts,
+) (models.PriceUpdate, error) {
 	const op = "storage.postgres.UpdateParsedData"
 
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return models.PriceUpdate{}, fmt.Errorf("%s: begin tx: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := markMessageProcessed(ctx, tx, messageID, productID); err != nil {
+		return models.PriceUpdate{}, err
+	}
+
+	const query = `
+		WITH old AS (
+			SELECT id, price, in_stock, last_checked
+			FROM products
+			WHERE id = $4
+			FOR UPDATE
+		),
+		min_30d AS (
+			SELECT MIN(price) AS price
+			FROM price_history
+			WHERE product_id = $4
+				AND price >= 0
+				AND observed_at > now() - interval '30 days'
+		),
+		updated AS (
+			UPDATE products p
+			SET price = $1,
+				currency = $2,
+				in_stock = $3,
+				last_checked = now(),
+				queued_at = NULL,
+				parse_failures = 0,
+				last_parse_error = NULL,
+				updated_at = now()
+			FROM old
+			WHERE p.id = old.id
+			RETURNING p.id, p.user_id, p.title, p.url, p.price, p.currency, p.in_stock,
+				p.last_checked, p.notify_back_in_stock
+		),
+		history AS (
+			INSERT INTO price_history (product_id, price, currency, in_stock, observed_at)
+			SELECT id, price, currency, in_stock, last_checked
+			FROM updated
+		)
+		SELECT u.id, u.user_id, u.title, u.url,
+			old.last_checked IS NOT NULL, old.price, old.in_stock,
+			u.price, u.in_stock, u.currency, min_30d.price,
+			old.last_checked IS NOT NULL AND NOT COALESCE(old.in_stock, FALSE) AND u.in_stock,
+			u.notify_back_in_stock, u.last_checked
+		FROM updated u
+		JOIN old ON old.id = u.id
+		CROSS JOIN min_30d
+	`
+
+	var u models.PriceUpdate
+
+	err = tx.QueryRow(ctx, query, price, currency, inStock, productID).Scan(
+		&u.ProductID,
+		&u.UserID,
+		&u.Title,
+		&u.URL,
+		&u.WasChecked,
+		&u.OldPrice,
+		&u.OldInStock,
+		&u.NewPrice,
+		&u.NewInStock,
+		&u.Currency,
+		&u.MinPrice30d,
+		&u.BackInStock,
+		&u.NotifyStock,
+		&u.CheckedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.PriceUpdate{}, storage.ErrProductsNotFound
+		}
+
+		return models.PriceUpdate{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// * письмо о появлении в наличии ставится в очередь вместе с переходом:
+	// * либо сохраняются оба, либо результат вернётся на повтор целиком
+	if u.BackInStock && u.NotifyStock {
+		u.StockNotified, err = enqueueNotification(ctx, tx, u.UserID, u.ProductID, 0, models.NotificationBackInStock, models.BackInStockData{
+			ProductID: u.ProductID,
+			Title:     u.Title,
+			URL:       u.URL,
+			Price:     u.NewPrice,
+			Currency:  u.Currency,
+			CheckedAt: u.CheckedAt,
+		})
+		if err != nil {
+			return models.PriceUpdate{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if evaluate != nil {
+		u.AlertsNotified, err = evaluate(ctx, alertRules{tx: tx}, u)
+		if err != nil {
+			return models.PriceUpdate{}, fmt.Errorf("%s: evaluate alerts: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return models.PriceUpdate{}, fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return u, nil
+}
+
+// * markMessageProcessed запоминает id применённого сообщения о продукте.
+// * Параллельный обработчик дубликата ждёт коммита первого и получает
+// * ErrMessageAlreadyProcessed, а сообщение об удалённом продукте — ErrProductsNotFound
+func markMessageProcessed(ctx context.Context, tx pgx.Tx, messageID string, productID int64) error {
+	const op = "storage.postgres.markMessageProcessed"
+
+	const query = `
+		INSERT INTO processed_messages (message_id, product_id)
+		VALUES ($1, $2)
+		ON CONFLICT (message_id) DO NOTHING
+	`
+
+	cmd, err := tx.Exec(ctx, query, messageID, productID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == storage.ForeignKeyViolation {
+			return storage.ErrProductsNotFound
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cmd.RowsAffected() == 0 {
+		return storage.ErrMessageAlreadyProcessed
+	}
+
+	return nil
+}
+
+// * PriceHistory возвращает наблюдения цены и наличия за период [from, to)
+// * для продукта, принадлежащего пользователю
+func (r *PostgresRepo) PriceHistory(
+	ctx context.Context,
+	productID, userID int64,
+	from, to time.Time,
+) ([]models.PricePoint, error) {
+	const op = "storage.postgres.PriceHistory"
+
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel:   pgx.ReadCommitted,
+		AccessMode: pgx.ReadOnly,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: begin tx: %w", op, err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			fmt.Printf("failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	// * Проверяем владельца, чтобы отличить чужой продукт от пустой истории
+	var owned bool
+	ownerQuery := `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1 AND user_id = $2)`
+	if err := tx.QueryRow(ctx, ownerQuery, productID, userID).Scan(&owned); err != nil {
+		return nil, fmt.Errorf("%s: owner: %w", op, err)
+	}
+
+	if !owned {
+		return nil, storage.ErrProductsNotFound
+	}
+
+	query := `
+		SELECT price, currency, in_stock, observed_at
+		FROM price_history
+		WHERE product_id = $1
+			AND observed_at >= $2
+			AND observed_at < $3
+		ORDER BY observed_at
+	`
+
+	rows, err := tx.Query(ctx, query, productID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("%s: query: %w", op, err)
+	}
+
+	points, err := pgx.CollectRows(rows, pgx.RowToStructByName[models.PricePoint])
+	if err != nil {
+		return nil, fmt.Errorf("%s: collect: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return points, nil
+}
+
+// * SetBackInStockNotification включает или выключает уведомление
+// * о появлении продукта в наличии
+func (r *PostgresRepo) SetBackInStockNotification(ctx context.Context, productID, userID int64, enabled bool) error {
+	const op = "storage.postgres.SetBackInStockNotification"
+
 	const query = `
 		UPDATE products
-		SET price = $1,
-			in_stock = $2,
-			last_checked = now(),
+		SET notify_back_in_stock = $1,
 			updated_at = now()
-		WHERE id = $3
+		WHERE id = $2 AND user_id = $3
 	`
 
-	cmd, err := r.pool.Exec(ctx, query, price, inStock, productID)
+	cmd, err := r.pool.Exec(ctx, query, enabled, productID, userID)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -188,6 +460,203 @@ func (r *PostgresRepo) UpdateParsedData(ctx context.Context, productID int64, pr
 	return nil
 }
 
+// * ClaimDueProducts выбирает продукты, которые не проверялись дольше checkInterval,
+// * и помечает их как поставленные в очередь (queued_at). Продукт без результата
+// * парсинга дольше inFlightTimeout снова считается доступным для постановки,
+// * если его задание не ждёт публикации в outbox. Письма о продукте и неудавшиеся
+// * задания в outbox постановке не мешают
+func (r *PostgresRepo) ClaimDueProducts(
+	ctx context.Context,
+	checkInterval, inFlightTimeout time.Duration,
+	limit int,
+) ([]models.ProductForProducer, error) {
+	const op = "storage.postgres.ClaimDueProducts"
+
+	const query = `
+		UPDATE products p
+		SET queued_at = now()
+		FROM (
+			SELECT id
+			FROM products
+			WHERE (last_checked IS NULL OR last_checked < now() - make_interval(secs => $1))
+				AND (queued_at IS NULL OR queued_at < now() - make_interval(secs => $2))
+				AND NOT EXISTS (
+					SELECT 1
+					FROM outbox o
+					WHERE o.product_id = products.id
+						AND o.published_at IS NULL
+						AND o.failed_at IS NULL
+						AND o.payload ->> 'type' = $4
+				)
+			ORDER BY last_checked NULLS FIRST
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		) due
+		WHERE p.id = due.id
+		RETURNING p.id, p.url, p.marketplace
+	`
+
+	rows, err := r.pool.Query(ctx, query,
+		checkInterval.Seconds(), inFlightTimeout.Seconds(), limit, envelope.TypeParseRequested,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: query: %w", op, err)
+	}
+
+	products, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.ProductForProducer])
+	if err != nil {
+		return nil, fmt.Errorf("%s: collect: %w", op, err)
+	}
+
+	return products, nil
+}
+
+// * ReleaseProducts снимает отметку о постановке в очередь,
+// * если сообщения не удалось опубликовать
+func (r *PostgresRepo) ReleaseProducts(ctx context.Context, productIDs []int64) error {
+	const op = "storage.postgres.ReleaseProducts"
+
+	const query = `
+		UPDATE products
+		SET queued_at = NULL
+		WHERE id = ANY($1)
+	`
+
+	if _, err := r.pool.Exec(ctx, query, productIDs); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * RecordParseFailure отмечает неудачную проверку продукта. last_checked
+// * сдвигается, чтобы планировщик не переотправлял страницу в каждом проходе.
+// * Повторная доставка того же результата возвращает ErrMessageAlreadyProcessed
+func (r *PostgresRepo) RecordParseFailure(ctx context.Context, messageID string, productID int64, reason string) error {
+	const op = "storage.postgres.RecordParseFailure"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := markMessageProcessed(ctx, tx, messageID, productID); err != nil {
+		return err
+	}
+
+	const query = `
+		UPDATE products
+		SET parse_failures = parse_failures + 1,
+			last_parse_error = $2,
+			last_parse_failed_at = now(),
+			last_checked = now(),
+			queued_at = NULL
+		WHERE id = $1
+	`
+
+	if _, err := tx.Exec(ctx, query, productID, reason); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// * QueueProduct помечает продукт поставленным в очередь вне расписания и в той
+// * же транзакции кладёт задание для парсера в outbox: отметка не остаётся
+// * без задания, если публикация не удалась
+func (r *PostgresRepo) QueueProduct(ctx context.Context, productID int64) error {
+	const op = "storage.postgres.QueueProduct"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: begin tx: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	const query = `
+		UPDATE products
+		SET queued_at = now()
+		WHERE id = $1
+		RETURNING id, url, marketplace
+	`
+
+	var task models.ProductForProducer
+
+	err = tx.QueryRow(ctx, query, productID).Scan(&task.ID, &task.URL, &task.Marketplace)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.ErrProductsNotFound
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := enqueueParseTask(ctx, tx, task); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return nil
+}
+
+// * ParserStats собирает статистику ошибок парсинга по маркетплейсам
+// * и список продуктов с наибольшим числом неудач подряд
+func (r *PostgresRepo) ParserStats(ctx context.Context, failingLimit int) (models.ParserStats, error) {
+	const op = "storage.postgres.ParserStats"
+
+	const marketplacesQuery = `
+		SELECT marketplace,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE parse_failures > 0),
+			COUNT(*) FILTER (WHERE last_parse_failed_at > now() - interval '24 hours'),
+			COUNT(*) FILTER (WHERE queued_at IS NOT NULL)
+		FROM products
+		GROUP BY marketplace
+		ORDER BY marketplace
+	`
+
+	rows, err := r.pool.Query(ctx, marketplacesQuery)
+	if err != nil {
+		return models.ParserStats{}, fmt.Errorf("%s: marketplaces: %w", op, err)
+	}
+
+	marketplaces, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.MarketplaceParseStats])
+	if err != nil {
+		return models.ParserStats{}, fmt.Errorf("%s: collect marketplaces: %w", op, err)
+	}
+
+	const failingQuery = `
+		SELECT id, user_id, url, marketplace, parse_failures, last_parse_error, last_parse_failed_at
+		FROM products
+		WHERE parse_failures > 0
+		ORDER BY parse_failures DESC, last_parse_failed_at DESC
+		LIMIT $1
+	`
+
+	rows, err = r.pool.Query(ctx, failingQuery, failingLimit)
+	if err != nil {
+		return models.ParserStats{}, fmt.Errorf("%s: failing products: %w", op, err)
+	}
+
+	failing, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.FailingProduct])
+	if err != nil {
+		return models.ParserStats{}, fmt.Errorf("%s: collect failing products: %w", op, err)
+	}
+
+	return models.ParserStats{
+		Marketplaces:    marketplaces,
+		FailingProducts: failing,
+	}, nil
+}
+
 // * DeleteProduct удаляет продукт по productID и userID
 func (r *PostgresRepo) DeleteProduct(ctx context.Context, productID, userID int64) error {
 	const op = "storage.postgres.DeleteProduct"
@@ -209,6 +678,71 @@ func (r *PostgresRepo) DeleteProduct(ctx context.Context, productID, userID int6
 	return nil
 }
 
+// * DeleteUserData удаляет все данные пользователя: продукты (история цен
+// * удаляется каскадом), правила алертов, журнал уведомлений, контакт и выгрузки.
+// * Пользователь отмечается в deleted_users: после этого SaveProduct отказывает,
+// * а контакт и профиль не сохраняются. Повторный вызов ничего не удаляет
+// * и не считается ошибкой. purge вызывается с ID удалённых продуктов в той же
+// * транзакции, перед фиксацией (nil — кеш не очищается). Возвращает ID удалённых продуктов
+func (r *PostgresRepo) DeleteUserData(ctx context.Context, userID int64, purge storage.PurgeProducts) ([]int64, error) {
+	const op = "storage.postgres.DeleteUserData"
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: begin tx: %w", op, err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			fmt.Printf("failed to rollback transaction: %v\n", err)
+		}
+	}()
+
+	// * отметка удаления не даёт запоздавшим событиям и запросам
+	// * создать данные пользователя заново
+	const tombstoneQuery = `
+		INSERT INTO deleted_users (user_id)
+		VALUES ($1)
+		ON CONFLICT (user_id) DO NOTHING
+	`
+
+	if _, err := tx.Exec(ctx, tombstoneQuery, userID); err != nil {
+		return nil, fmt.Errorf("%s: tombstone: %w", op, err)
+	}
+
+	for _, query := range []string{
+		`DELETE FROM notifications WHERE user_id = $1`,
+		`DELETE FROM alert_rules WHERE user_id = $1`,
+		`DELETE FROM user_contacts WHERE user_id = $1`,
+		`DELETE FROM export_jobs WHERE user_id = $1`,
+	} {
+		if _, err := tx.Exec(ctx, query, userID); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	rows, err := tx.Query(ctx, `DELETE FROM products WHERE user_id = $1 RETURNING id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: products: %w", op, err)
+	}
+
+	productIDs, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("%s: collect products: %w", op, err)
+	}
+
+	if purge != nil {
+		if err := purge(ctx, productIDs); err != nil {
+			return nil, fmt.Errorf("%s: purge cache: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return productIDs, nil
+}
+
 // * Close закрывает соединение с базой данных.
 func (r *PostgresRepo) Close() {
 	r.pool.Close()
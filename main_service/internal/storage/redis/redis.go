@@ -80,6 +80,26 @@ func (r *RedisRepo) Product(ctx context.Context, productID int64) (models.Produc
 	return product, nil
 }
 
+// * DeleteProducts удаляет продукты из кеша; отсутствующие ключи пропускаются
+func (r *RedisRepo) DeleteProducts(ctx context.Context, productIDs []int64) error {
+	const op = "storage.redis.DeleteProducts"
+
+	if len(productIDs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(productIDs))
+	for _, id := range productIDs {
+		keys = append(keys, fmt.Sprintf("product:%d", id))
+	}
+
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 // Close закрывает соединение с базой данных.
 func (r *RedisRepo) Close() {
 	r.client.Close()
@@ -1,12 +1,39 @@
 package storage
 
-import "errors"
+import (
+	"context"
+	"errors"
+
+	"main_service/internal/models"
+)
 
 const (
-	UniqueViolation = "23505"
+	UniqueViolation     = "23505"
+	ForeignKeyViolation = "23503"
 )
 
 var (
 	ErrUserAlreadyTracksProduct = errors.New("This product is already tracking")
 	ErrProductsNotFound         = errors.New("products not found")
+	ErrAlertRuleNotFound        = errors.New("alert rule not found")
+	ErrExportJobNotFound        = errors.New("export job not found")
+	ErrExportNotReady           = errors.New("export is not ready")
+	ErrMessageAlreadyProcessed  = errors.New("message already processed")
+	ErrUserDeleted              = errors.New("user deleted")
 )
+
+// * AlertRules — правила оповещений продукта внутри транзакции, сохраняющей
+// * результат парсинга
+type AlertRules interface {
+	ProductAlertRules(ctx context.Context, productID int64) ([]models.AlertRule, error)
+	TriggerAlertRule(ctx context.Context, rule models.AlertRule, data models.PriceAlertData) (bool, error)
+	ResetAlertTriggered(ctx context.Context, ruleID int64) (bool, error)
+}
+
+// * EvaluateAlerts проверяет правила продукта после обновления цены и сообщает,
+// * поставлены ли письма в outbox
+type EvaluateAlerts func(ctx context.Context, rules AlertRules, update models.PriceUpdate) (bool, error)
+
+// * PurgeProducts убирает удалённые продукты из кеша до фиксации удаления:
+// * при ошибке удаление откатывается, и повторная доставка снова найдёт продукты
+type PurgeProducts func(ctx context.Context, productIDs []int64) error
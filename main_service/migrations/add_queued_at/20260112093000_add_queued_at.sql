@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE products
+	ADD COLUMN queued_at TIMESTAMPTZ;
+
+CREATE INDEX idx_products_last_checked
+	ON products (last_checked NULLS FIRST);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_products_last_checked;
+
+ALTER TABLE products
+	DROP COLUMN IF EXISTS queued_at;
+-- +goose StatementEnd
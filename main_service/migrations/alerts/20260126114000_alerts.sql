@@ -0,0 +1,65 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * Контакты пользователей для уведомлений (пользователи хранятся в auth_service)
+CREATE TABLE user_contacts (
+	user_id BIGINT PRIMARY KEY,
+	email TEXT NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE alert_rules (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	product_id BIGINT NOT NULL,
+	type TEXT NOT NULL,
+	threshold INTEGER NOT NULL DEFAULT 0,
+	triggered BOOLEAN NOT NULL DEFAULT FALSE,
+	last_triggered_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+	CONSTRAINT chk_alert_rules_type
+		CHECK (type IN ('price_below', 'percent_drop', 'below_30d_min')),
+
+	CONSTRAINT fk_alert_rules_product
+		FOREIGN KEY (product_id)
+		REFERENCES products(id)
+		ON DELETE CASCADE
+);
+
+CREATE INDEX idx_alert_rules_product_id
+	ON alert_rules (product_id);
+
+CREATE INDEX idx_alert_rules_user_id
+	ON alert_rules (user_id);
+
+CREATE TABLE notifications (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	product_id BIGINT,
+	alert_rule_id BIGINT,
+	type TEXT NOT NULL,
+	payload JSONB NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+	CONSTRAINT fk_notifications_product
+		FOREIGN KEY (product_id)
+		REFERENCES products(id)
+		ON DELETE SET NULL,
+
+	CONSTRAINT fk_notifications_alert_rule
+		FOREIGN KEY (alert_rule_id)
+		REFERENCES alert_rules(id)
+		ON DELETE SET NULL
+);
+
+CREATE INDEX idx_notifications_user_id
+	ON notifications (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS alert_rules;
+DROP TABLE IF EXISTS user_contacts;
+-- +goose StatementEnd
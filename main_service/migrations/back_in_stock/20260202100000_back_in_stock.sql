@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE products
+	ADD COLUMN notify_back_in_stock BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE products
+	DROP COLUMN IF EXISTS notify_back_in_stock;
+-- +goose StatementEnd
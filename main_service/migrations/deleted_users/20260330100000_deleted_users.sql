@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * удалённые пользователи: строка пишется в транзакции удаления данных.
+-- * Запоздавший user.updated или запрос с ещё действующим токеном
+-- * не должны заново создать контакт или продукт удалённого пользователя
+CREATE TABLE deleted_users (
+	user_id BIGINT PRIMARY KEY,
+	deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS deleted_users;
+-- +goose StatementEnd
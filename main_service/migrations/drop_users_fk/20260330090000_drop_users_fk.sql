@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * пользователи хранятся в базе auth_service: данные удалённого пользователя
+-- * удаляются по событию user.deleted, а не каскадом
+ALTER TABLE products
+	DROP CONSTRAINT IF EXISTS fk_products_user;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- * внешний ключ не восстанавливается: таблицы users в этой базе нет
+SELECT 1;
+-- +goose StatementEnd
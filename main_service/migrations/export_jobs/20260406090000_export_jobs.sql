@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * фоновые выгрузки персональных данных; account — ответ auth_service,
+-- * полученный при создании задания, archive — готовый ZIP
+CREATE TABLE export_jobs (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id BIGINT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	account JSONB NOT NULL,
+	archive BYTEA,
+	error TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	started_at TIMESTAMPTZ,
+	finished_at TIMESTAMPTZ,
+	expires_at TIMESTAMPTZ,
+
+	CONSTRAINT chk_export_jobs_status
+		CHECK (status IN ('pending', 'running', 'done', 'failed'))
+);
+
+CREATE INDEX idx_export_jobs_user_id
+	ON export_jobs (user_id);
+
+CREATE INDEX idx_export_jobs_pending
+	ON export_jobs (created_at)
+	WHERE status IN ('pending', 'running');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS export_jobs;
+-- +goose StatementEnd
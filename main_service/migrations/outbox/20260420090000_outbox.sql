@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * сообщения для брокера, записанные в одной транзакции с изменением данных.
+-- * claimed_at — отметка relay, взявшего строку; published_at — подтверждение брокера
+CREATE TABLE outbox (
+	id BIGSERIAL PRIMARY KEY,
+	queue TEXT NOT NULL,
+	payload JSONB NOT NULL,
+	product_id BIGINT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	claimed_at TIMESTAMPTZ,
+	published_at TIMESTAMPTZ,
+
+	CONSTRAINT fk_outbox_product
+		FOREIGN KEY (product_id)
+		REFERENCES products(id)
+		ON DELETE CASCADE
+);
+
+CREATE INDEX idx_outbox_pending
+	ON outbox (id)
+	WHERE published_at IS NULL;
+
+CREATE INDEX idx_outbox_pending_product_id
+	ON outbox (product_id)
+	WHERE published_at IS NULL;
+
+CREATE INDEX idx_outbox_published_at
+	ON outbox (published_at)
+	WHERE published_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS outbox;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * attempts — неудачные публикации строки; до available_at строка отложена
+-- * после неудачи и не задерживает следующие. Исчерпавшая попытки строка
+-- * получает failed_at и больше не публикуется, last_error — причина
+ALTER TABLE outbox
+	ADD COLUMN attempts INT NOT NULL DEFAULT 0,
+	ADD COLUMN available_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	ADD COLUMN failed_at TIMESTAMPTZ,
+	ADD COLUMN last_error TEXT;
+
+DROP INDEX IF EXISTS idx_outbox_pending;
+
+CREATE INDEX idx_outbox_pending
+	ON outbox (id)
+	WHERE published_at IS NULL AND failed_at IS NULL;
+
+CREATE INDEX idx_outbox_failed_at
+	ON outbox (failed_at)
+	WHERE failed_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_outbox_failed_at;
+DROP INDEX IF EXISTS idx_outbox_pending;
+
+CREATE INDEX idx_outbox_pending
+	ON outbox (id)
+	WHERE published_at IS NULL;
+
+ALTER TABLE outbox
+	DROP COLUMN IF EXISTS attempts,
+	DROP COLUMN IF EXISTS available_at,
+	DROP COLUMN IF EXISTS failed_at,
+	DROP COLUMN IF EXISTS last_error;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * сообщения маршрутизирует exchange по типу конверта: имя очереди не хранится
+ALTER TABLE outbox
+	DROP COLUMN queue;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- * очередь восстанавливается по типу конверта из имён по умолчанию
+ALTER TABLE outbox
+	ADD COLUMN queue TEXT;
+
+UPDATE outbox
+SET queue = CASE payload ->> 'type'
+	WHEN 'email.requested' THEN 'msgQueue'
+	ELSE 'parsing_queue'
+END;
+
+ALTER TABLE outbox
+	ALTER COLUMN queue SET NOT NULL;
+-- +goose StatementEnd
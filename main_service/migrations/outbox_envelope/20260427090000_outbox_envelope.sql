@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * неопубликованные задания, записанные до перехода на конверты,
+-- * оборачиваются в конверт product.parse_requested версии 1
+UPDATE outbox
+SET payload = jsonb_build_object(
+	'type', 'product.parse_requested',
+	'version', 1,
+	'id', gen_random_uuid()::text,
+	'occurred_at', created_at,
+	'payload', payload
+)
+WHERE published_at IS NULL
+	AND NOT payload ? 'version';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+UPDATE outbox
+SET payload = payload -> 'payload'
+WHERE published_at IS NULL
+	AND payload ? 'version';
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * parse_failures — неудачные проверки подряд, сбрасывается успешным парсингом
+ALTER TABLE products
+	ADD COLUMN parse_failures INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN last_parse_error TEXT,
+	ADD COLUMN last_parse_failed_at TIMESTAMPTZ;
+
+CREATE INDEX idx_products_parse_failures
+	ON products (parse_failures) WHERE parse_failures > 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_products_parse_failures;
+
+ALTER TABLE products
+	DROP COLUMN IF EXISTS last_parse_failed_at,
+	DROP COLUMN IF EXISTS last_parse_error,
+	DROP COLUMN IF EXISTS parse_failures;
+-- +goose StatementEnd
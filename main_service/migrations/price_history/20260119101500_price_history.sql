@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE products
+	ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE price_history (
+	id BIGSERIAL PRIMARY KEY,
+	product_id BIGINT NOT NULL,
+	price INTEGER NOT NULL,
+	currency TEXT NOT NULL DEFAULT '',
+	in_stock BOOLEAN NOT NULL,
+	observed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+	CONSTRAINT fk_price_history_product
+		FOREIGN KEY (product_id)
+		REFERENCES products(id)
+		ON DELETE CASCADE
+);
+
+CREATE INDEX idx_price_history_product_observed
+	ON price_history (product_id, observed_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS price_history;
+
+ALTER TABLE products
+	DROP COLUMN IF EXISTS currency;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * id конвертов применённых результатов парсинга: брокер и outbox доставляют
+-- * at-least-once, и повторная доставка не должна второй раз писать историю цен.
+-- * Строки удаляются вместе с продуктом
+CREATE TABLE processed_messages (
+	message_id TEXT PRIMARY KEY,
+	product_id BIGINT NOT NULL,
+	processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+	CONSTRAINT fk_processed_messages_product
+		FOREIGN KEY (product_id)
+		REFERENCES products(id)
+		ON DELETE CASCADE
+);
+
+CREATE INDEX idx_processed_messages_product_id
+	ON processed_messages (product_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS processed_messages;
+-- +goose StatementEnd
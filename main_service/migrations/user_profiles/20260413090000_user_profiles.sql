@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- * настройки профиля приходят из auth_service событием user.updated;
+-- * profile_updated_at — время последнего применённого события, более старые отбрасываются
+ALTER TABLE user_contacts
+	ADD COLUMN locale TEXT NOT NULL DEFAULT 'ru',
+	ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC',
+	ADD COLUMN currency TEXT NOT NULL DEFAULT '',
+	ADD COLUMN profile_updated_at TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user_contacts
+	DROP COLUMN IF EXISTS profile_updated_at,
+	DROP COLUMN IF EXISTS currency,
+	DROP COLUMN IF EXISTS timezone,
+	DROP COLUMN IF EXISTS locale;
+-- +goose StatementEnd
@@ -0,0 +1,262 @@
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"messaging/broker"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// * memBroker — брокер в памяти с семантикой очередей из Queue и
+// * RetryPolicy.Topology: очереди адресуются по имени через default exchange,
+// * отклонённое без повтора сообщение уходит в <queue>.dlq, expire имитирует
+// * истечение TTL очередей ожидания. Он же подтверждает выданные сообщения
+// * (amqp.Acknowledger) и выдаёт их подписчику (broker.DeliverySource)
+type memBroker struct {
+	mu      sync.Mutex
+	queues  map[string][]amqp.Delivery
+	unacked map[uint64]string // * тег -> очередь, из которой выдано
+	pending map[uint64]amqp.Delivery
+	nextTag uint64
+	changed chan struct{} // * будит подписку после публикации или подтверждения
+
+	failPublish error
+	acks        int
+	nacks       int
+}
+
+var errBroker = errors.New("broker unavailable")
+
+func newMemBroker() *memBroker {
+	return &memBroker{
+		queues:  make(map[string][]amqp.Delivery),
+		unacked: make(map[uint64]string),
+		pending: make(map[uint64]amqp.Delivery),
+		changed: make(chan struct{}, 1),
+	}
+}
+
+func (b *memBroker) notify() {
+	select {
+	case b.changed <- struct{}{}:
+	default:
+	}
+}
+
+// * Publish реализует broker.Republisher
+func (b *memBroker) Publish(_ context.Context, routingKey string, msg amqp.Publishing) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failPublish != nil {
+		return b.failPublish
+	}
+
+	b.queues[routingKey] = append(b.queues[routingKey], amqp.Delivery{
+		Headers:       msg.Headers,
+		ContentType:   msg.ContentType,
+		Type:          msg.Type,
+		MessageId:     msg.MessageId,
+		CorrelationId: msg.CorrelationId,
+		Timestamp:     msg.Timestamp,
+		Body:          msg.Body,
+	})
+	b.notify()
+
+	return nil
+}
+
+func (b *memBroker) push(queue, body string) {
+	_ = b.Publish(context.Background(), queue, amqp.Publishing{MessageId: body, Body: []byte(body)})
+}
+
+// * get выдаёт первое сообщение очереди как неподтверждённое
+func (b *memBroker) get(queue string) (amqp.Delivery, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.getLocked(queue)
+}
+
+func (b *memBroker) getLocked(queue string) (amqp.Delivery, bool) {
+	msgs := b.queues[queue]
+	if len(msgs) == 0 {
+		return amqp.Delivery{}, false
+	}
+
+	d := msgs[0]
+	b.queues[queue] = msgs[1:]
+
+	b.nextTag++
+	d.DeliveryTag = b.nextTag
+	d.Acknowledger = b
+
+	b.unacked[d.DeliveryTag] = queue
+	b.pending[d.DeliveryTag] = d
+
+	return d, true
+}
+
+func (b *memBroker) Ack(tag uint64, _ bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.unacked[tag]; !ok {
+		return errors.New("unknown delivery tag")
+	}
+
+	delete(b.unacked, tag)
+	delete(b.pending, tag)
+	b.acks++
+	b.notify()
+
+	return nil
+}
+
+func (b *memBroker) Nack(tag uint64, _ bool, requeue bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	queue, ok := b.unacked[tag]
+	if !ok {
+		return errors.New("unknown delivery tag")
+	}
+
+	b.nacks++
+	b.returnLocked(tag, queue, requeue)
+	b.notify()
+
+	return nil
+}
+
+// * returnLocked снимает сообщение с подтверждения и возвращает его в начало
+// * очереди либо отправляет в DLQ
+func (b *memBroker) returnLocked(tag uint64, queue string, requeue bool) {
+	d := b.pending[tag]
+	delete(b.unacked, tag)
+	delete(b.pending, tag)
+
+	d.Acknowledger, d.DeliveryTag = nil, 0
+
+	if requeue {
+		d.Redelivered = true
+		b.queues[queue] = append([]amqp.Delivery{d}, b.queues[queue]...)
+		return
+	}
+
+	dlq := broker.DeadLetterQueueName(queue)
+	b.queues[dlq] = append(b.queues[dlq], d)
+}
+
+func (b *memBroker) Reject(tag uint64, requeue bool) error {
+	return b.Nack(tag, false, requeue)
+}
+
+// * Deliveries реализует broker.DeliverySource: подписка выдаёт сообщения,
+// * пока неподтверждённых из queue меньше prefetch. Закрытие подписки
+// * возвращает неподтверждённые сообщения в очередь, как закрытие канала
+func (b *memBroker) Deliveries(_ context.Context, queue string, prefetch int) (<-chan amqp.Delivery, func() error, error) {
+	msgs := make(chan amqp.Delivery)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer close(msgs)
+
+		for {
+			d, ok := b.next(queue, prefetch)
+			if !ok {
+				select {
+				case <-stop:
+					return
+				case <-b.changed:
+					continue
+				}
+			}
+
+			select {
+			case <-stop:
+				return
+			case msgs <- d:
+			}
+		}
+	}()
+
+	closeFn := func() error {
+		close(stop)
+		<-done
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for tag, q := range b.unacked {
+			if q == queue {
+				b.returnLocked(tag, queue, true)
+			}
+		}
+
+		return nil
+	}
+
+	return msgs, closeFn, nil
+}
+
+func (b *memBroker) next(queue string, prefetch int) (amqp.Delivery, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	inFlight := 0
+	for _, q := range b.unacked {
+		if q == queue {
+			inFlight++
+		}
+	}
+
+	if inFlight >= prefetch {
+		return amqp.Delivery{}, false
+	}
+
+	return b.getLocked(queue)
+}
+
+// * expire возвращает сообщения из всех очередей ожидания queue в саму queue,
+// * как это делает брокер по истечении TTL
+func (b *memBroker) expire(queue string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for name, msgs := range b.queues {
+		if strings.HasPrefix(name, queue+".retry.") {
+			b.queues[queue] = append(b.queues[queue], msgs...)
+			delete(b.queues, name)
+		}
+	}
+
+	b.notify()
+}
+
+func (b *memBroker) len(queue string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.queues[queue])
+}
+
+func (b *memBroker) peek(queue string) amqp.Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.queues[queue][0]
+}
+
+func (b *memBroker) unackedCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.unacked)
+}
@@ -0,0 +1,336 @@
+// * Package broker — общий клиент RabbitMQ для всех сервисов: соединение с
+// * автоматическим переподключением, публикация с подтверждениями брокера и
+// * consumer с ограниченным пулом обработчиков.
+// *
+// * Топология (очереди, exchange, привязки) регистрируется через
+// * Connection.Declare и объявляется заново после каждого переподключения,
+// * до того как publisher и consumer откроют новые каналы
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+var ErrClosed = errors.New("broker connection closed")
+
+// * Topology объявляет на канале очереди, exchange и привязки.
+// * Объявления должны быть идемпотентными: они повторяются при каждом подключении
+type Topology func(ch Channel) error
+
+// * Channel — операции канала AMQP, которыми пользуются топология, Publisher
+// * и подписка. Реализуется каналом amqp091 (amqpChannel)
+type Channel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Confirm(noWait bool) error
+	PublishConfirmed(ctx context.Context, exchange, key string, msg amqp.Publishing) (Confirmation, error)
+	IsClosed() bool
+	Close() error
+}
+
+// * Confirmation — ожидание подтверждения публикации брокером
+type Confirmation interface {
+	WaitContext(ctx context.Context) (bool, error)
+}
+
+// * conn — открытое соединение с брокером. NotifyClose сообщает о его потере
+type conn interface {
+	Channel() (Channel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	Close() error
+}
+
+// * dialer открывает соединение по адресу брокера
+type dialer func(url string) (conn, error)
+
+// * Connection держит одно соединение с брокером и восстанавливает его
+// * с экспоненциальной задержкой, если брокер закрыл соединение
+type Connection struct {
+	url  string
+	log  *slog.Logger
+	dial dialer
+
+	mu       sync.Mutex
+	conn     conn
+	ready    chan struct{} // * закрыт, пока соединение открыто
+	topology []Topology
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// * Connect открывает соединение. Первая попытка не повторяется, чтобы
+// * сервис с неверным адресом брокера падал сразу при старте
+func Connect(url string, log *slog.Logger) (*Connection, error) {
+	return connect(url, log, dialAMQP)
+}
+
+func connect(url string, log *slog.Logger, dial dialer) (*Connection, error) {
+	const op = "broker.Connect"
+
+	conn, err := dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	c := &Connection{
+		url:    url,
+		log:    log.With(slog.String("component", "broker")),
+		dial:   dial,
+		conn:   conn,
+		ready:  make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	close(c.ready)
+
+	go c.watch(conn)
+
+	return c, nil
+}
+
+// * Declare объявляет топологию сразу и запоминает её для переподключений
+func (c *Connection) Declare(t Topology) error {
+	const op = "broker.Connection.Declare"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != nil {
+		if err := declare(c.conn, t); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	c.topology = append(c.topology, t)
+
+	return nil
+}
+
+// * Channel открывает новый канал. Если соединение сейчас восстанавливается,
+// * ждёт его, пока не отменён ctx
+func (c *Connection) Channel(ctx context.Context) (Channel, error) {
+	const op = "broker.Connection.Channel"
+
+	for {
+		c.mu.Lock()
+		conn, ready := c.conn, c.ready
+		c.mu.Unlock()
+
+		if conn != nil {
+			ch, err := conn.Channel()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+
+			return ch, nil
+		}
+
+		select {
+		case <-ready:
+		case <-c.closed:
+			return nil, fmt.Errorf("%s: %w", op, ErrClosed)
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s: %w", op, ctx.Err())
+		}
+	}
+}
+
+// * Deliveries подписывается на queue на новом канале с prefetch = prefetch.
+// * Закрытие канала возвращает неподтверждённые сообщения в очередь
+func (c *Connection) Deliveries(ctx context.Context, queue string, prefetch int) (<-chan amqp.Delivery, func() error, error) {
+	const op = "broker.Connection.Deliveries"
+
+	ch, err := c.Channel(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := ch.Qos(prefetch, 0, false); err != nil {
+		_ = ch.Close()
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	msgs, err := ch.Consume(
+		queue,
+		"",    // consumer name
+		false, // auto-ack выключен (чтобы подтверждать вручную)
+		false, // exclusive
+		false, // no-local
+		false, // no-wait
+		nil,
+	)
+	if err != nil {
+		_ = ch.Close()
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return msgs, ch.Close, nil
+}
+
+// * Close останавливает переподключение и закрывает соединение
+func (c *Connection) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+
+	return c.conn.Close()
+}
+
+// * watch ждёт закрытия соединения и запускает переподключение,
+// * если соединение закрыл не сам сервис
+func (c *Connection) watch(conn conn) {
+	notify := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	select {
+	case <-c.closed:
+		return
+	case amqpErr := <-notify:
+		select {
+		case <-c.closed:
+			return
+		default:
+		}
+
+		c.log.Warn("connection lost, reconnecting", slog.Any("error", amqpErr))
+	}
+
+	c.mu.Lock()
+	c.conn = nil
+	c.ready = make(chan struct{})
+	c.mu.Unlock()
+
+	c.reconnect()
+}
+
+func (c *Connection) reconnect() {
+	delay := minReconnectDelay
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.closed:
+			return
+		case <-time.After(delay):
+		}
+
+		conn, err := c.redial()
+		if err == nil {
+			c.log.Info("connection restored", slog.Int("attempt", attempt))
+			go c.watch(conn)
+			return
+		}
+
+		// * расхождение аргументов очереди не исправится повтором,
+		// * пока её не удалят вручную
+		level := slog.LevelWarn
+		if errors.Is(err, ErrTopologyMismatch) {
+			level = slog.LevelError
+		}
+
+		c.log.Log(context.Background(), level, "reconnect failed",
+			slog.Int("attempt", attempt),
+			slog.Duration("next_delay", delay),
+			slog.Any("error", err),
+		)
+
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// * redial открывает соединение и объявляет на нём всю топологию.
+// * Каналы открываются только после объявления
+func (c *Connection) redial() (conn, error) {
+	conn, err := c.dial(c.url)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, t := range c.topology {
+		if err := declare(conn, t); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	select {
+	case <-c.closed:
+		_ = conn.Close()
+		return nil, ErrClosed
+	default:
+	}
+
+	c.conn = conn
+	close(c.ready)
+
+	return conn, nil
+}
+
+// * declare объявляет топологию на отдельном канале: ошибка объявления
+// * закрывает канал, а не соединение
+func declare(conn conn, t Topology) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	return t(ch)
+}
+
+func dialAMQP(url string) (conn, error) {
+	c, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return amqpConn{c}, nil
+}
+
+// * amqpConn и amqpChannel приводят соединение и канал amqp091 к conn и Channel
+type amqpConn struct {
+	*amqp.Connection
+}
+
+func (c amqpConn) Channel() (Channel, error) {
+	ch, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	return amqpChannel{ch}, nil
+}
+
+type amqpChannel struct {
+	*amqp.Channel
+}
+
+func (ch amqpChannel) PublishConfirmed(ctx context.Context, exchange, key string, msg amqp.Publishing) (Confirmation, error) {
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return confirm, nil
+}
@@ -0,0 +1,320 @@
+package broker_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"messaging/broker"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// * fakeDialer открывает соединения с memBroker и запоминает их по порядку
+type fakeDialer struct {
+	broker *memBroker
+
+	mu    sync.Mutex
+	conns []*fakeConn
+}
+
+func (d *fakeDialer) dial(string) (broker.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	c := &fakeConn{broker: d.broker}
+	d.conns = append(d.conns, c)
+
+	return c, nil
+}
+
+func (d *fakeDialer) conn(i int) *fakeConn {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if i >= len(d.conns) {
+		return nil
+	}
+
+	return d.conns[i]
+}
+
+// * fakeConn записывает объявления топологии на всех своих каналах.
+// * drop имитирует потерю соединения брокером
+type fakeConn struct {
+	broker *memBroker
+
+	mu       sync.Mutex
+	declared []string
+	channels []*fakeChannel
+	notify   []chan *amqp.Error
+	closed   bool
+}
+
+func (c *fakeConn) Channel() (broker.Channel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, amqp.ErrClosed
+	}
+
+	ch := &fakeChannel{conn: c}
+	c.channels = append(c.channels, ch)
+
+	return ch, nil
+}
+
+func (c *fakeConn) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.notify = append(c.notify, receiver)
+
+	return receiver
+}
+
+func (c *fakeConn) Close() error {
+	c.shutdown(nil)
+	return nil
+}
+
+func (c *fakeConn) drop() {
+	c.shutdown(&amqp.Error{Code: amqp.ConnectionForced, Reason: "CONNECTION_FORCED"})
+}
+
+// * shutdown закрывает каналы (их подписки возвращают сообщения в очередь)
+// * и сообщает подписчикам NotifyClose, как amqp091
+func (c *fakeConn) shutdown(reason *amqp.Error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+
+	c.closed = true
+	channels, notify := c.channels, c.notify
+	c.mu.Unlock()
+
+	for _, ch := range channels {
+		_ = ch.Close()
+	}
+
+	for _, n := range notify {
+		if reason != nil {
+			n <- reason
+		}
+		close(n)
+	}
+}
+
+func (c *fakeConn) record(format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.declared = append(c.declared, fmt.Sprintf(format, args...))
+}
+
+func (c *fakeConn) declarations() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.declared)
+}
+
+// * fakeChannel публикует в memBroker и подписывается на него
+type fakeChannel struct {
+	conn *fakeConn
+
+	mu       sync.Mutex
+	prefetch int
+	closeFn  func() error
+	closed   bool
+}
+
+func (ch *fakeChannel) ExchangeDeclare(name, kind string, _, _, _, _ bool, _ amqp.Table) error {
+	ch.conn.record("exchange %s %s", name, kind)
+	return nil
+}
+
+func (ch *fakeChannel) QueueDeclare(name string, _, _, _, _ bool, args amqp.Table) (amqp.Queue, error) {
+	ch.conn.record("queue %s %v", name, args)
+	return amqp.Queue{Name: name}, nil
+}
+
+func (ch *fakeChannel) QueueBind(name, key, exchange string, _ bool, _ amqp.Table) error {
+	ch.conn.record("bind %s %s %s", name, key, exchange)
+	return nil
+}
+
+func (ch *fakeChannel) Qos(prefetchCount, _ int, _ bool) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.prefetch = prefetchCount
+
+	return nil
+}
+
+func (ch *fakeChannel) Consume(queue, _ string, _, _, _, _ bool, _ amqp.Table) (<-chan amqp.Delivery, error) {
+	msgs, closeFn, err := ch.conn.broker.Deliveries(context.Background(), queue, ch.prefetch)
+	if err != nil {
+		return nil, err
+	}
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.closeFn = closeFn
+
+	return msgs, nil
+}
+
+func (ch *fakeChannel) Confirm(bool) error {
+	return nil
+}
+
+func (ch *fakeChannel) PublishConfirmed(ctx context.Context, _, key string, msg amqp.Publishing) (broker.Confirmation, error) {
+	if ch.IsClosed() {
+		return nil, amqp.ErrClosed
+	}
+
+	if err := ch.conn.broker.Publish(ctx, key, msg); err != nil {
+		return nil, err
+	}
+
+	return ackedConfirmation{}, nil
+}
+
+func (ch *fakeChannel) IsClosed() bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	return ch.closed
+}
+
+func (ch *fakeChannel) Close() error {
+	ch.mu.Lock()
+	if ch.closed {
+		ch.mu.Unlock()
+		return nil
+	}
+
+	ch.closed = true
+	closeFn := ch.closeFn
+	ch.mu.Unlock()
+
+	if closeFn != nil {
+		return closeFn()
+	}
+
+	return nil
+}
+
+type ackedConfirmation struct{}
+
+func (ackedConfirmation) WaitContext(context.Context) (bool, error) {
+	return true, nil
+}
+
+// * После потери соединения топология объявляется заново на новом
+// * соединении, а публикация и подписка продолжают работать
+func TestConnectionReconnect(t *testing.T) {
+	b := newMemBroker()
+	dialer := &fakeDialer{broker: b}
+
+	conn, err := broker.ConnectWith("amqp://fake", slog.New(slog.DiscardHandler), dialer.dial)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	retry := broker.NewRetryPolicy(b, 3, time.Second)
+
+	for _, topology := range []broker.Topology{
+		broker.Queue("events", "results", "product.parsed"),
+		retry.Topology("results"),
+	} {
+		if err := conn.Declare(topology); err != nil {
+			t.Fatalf("Declare: %v", err)
+		}
+	}
+
+	pub := broker.NewPublisher(conn, "")
+	t.Cleanup(func() { _ = pub.Close() })
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+
+	c := broker.NewConsumer(conn, slog.New(slog.DiscardHandler), "results", 1, broker.Requeue, time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	handler := func(_ context.Context, body []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		received = append(received, string(body))
+
+		return nil
+	}
+
+	go func() { done <- c.Consume(ctx, handler) }()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	publishAndWait := func(body string) {
+		t.Helper()
+
+		pubCtx, pubCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer pubCancel()
+
+		if err := pub.Publish(pubCtx, "results", amqp.Publishing{Body: []byte(body)}); err != nil {
+			t.Fatalf("Publish %s: %v", body, err)
+		}
+
+		waitFor(t, "delivery of "+body, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return slices.Contains(received, body)
+		})
+	}
+
+	publishAndWait("before")
+
+	first := dialer.conn(0)
+	declared := first.declarations()
+
+	first.drop()
+
+	waitFor(t, "reconnect", func() bool { return dialer.conn(1) != nil })
+
+	publishAndWait("after")
+
+	// * вся топология, включая DLX, DLQ и очереди ожидания, объявлена заново
+	if got := dialer.conn(1).declarations(); !slices.Equal(got, declared) {
+		t.Errorf("declared after reconnect:\n%v\nwant:\n%v", got, declared)
+	}
+
+	for _, want := range []string{"exchange events.dlx direct", "bind results.dlq results events.dlx"} {
+		if !slices.Contains(declared, want) {
+			t.Errorf("topology %v does not contain %q", declared, want)
+		}
+	}
+
+	if !slices.ContainsFunc(declared, func(d string) bool { return strings.HasPrefix(d, "queue results.retry.") }) {
+		t.Errorf("topology %v does not declare retry queues", declared)
+	}
+}
@@ -0,0 +1,221 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// * HandlerFunc обрабатывает тело сообщения; результат передаётся Policy.
+// * Это псевдоним, чтобы сервисы могли описывать Consumer интерфейсом без импорта broker
+type HandlerFunc = func(ctx context.Context, body []byte) error
+
+// * DeliverySource подписывается на очередь: выдаёт не больше prefetch
+// * неподтверждённых сообщений. closeFn завершает подписку, выданные и не
+// * подтверждённые к этому моменту сообщения возвращаются в очередь.
+// * Реализуется *Connection
+type DeliverySource interface {
+	Deliveries(ctx context.Context, queue string, prefetch int) (msgs <-chan amqp.Delivery, closeFn func() error, err error)
+}
+
+// * Consumer читает очередь фиксированным пулом из workers обработчиков.
+// * Prefetch равен размеру пула, поэтому брокер не выдаёт больше сообщений,
+// * чем обрабатывается
+type Consumer struct {
+	source       DeliverySource
+	log          *slog.Logger
+	queue        string
+	workers      int
+	policy       Policy
+	drainTimeout time.Duration
+	metrics      *metrics
+}
+
+// * NewConsumer создаёт consumer. drainTimeout — сколько после отмены
+// * контекста ждать текущие обработки, прежде чем отменить и их
+func NewConsumer(
+	source DeliverySource,
+	log *slog.Logger,
+	queue string,
+	workers int,
+	policy Policy,
+	drainTimeout time.Duration,
+) *Consumer {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &Consumer{
+		source:       source,
+		log:          log,
+		queue:        queue,
+		workers:      workers,
+		policy:       policy,
+		drainTimeout: drainTimeout,
+		metrics:      newMetrics(),
+	}
+}
+
+// * Stats возвращает снимок метрик consumer
+func (c *Consumer) Stats() Stats {
+	s := c.metrics.snapshot()
+	s.Queue = c.queue
+	s.Workers = c.workers
+
+	return s
+}
+
+// * Consume блокируется до отмены ctx. После отмены новые сообщения не
+// * берутся, а текущие обработки получают drainTimeout на завершение: их
+// * контекст отменяется только по истечении этого срока. Если канал закрылся
+// * (перезапуск брокера, ошибка канала), подписка восстанавливается на новом канале
+func (c *Consumer) Consume(ctx context.Context, handler HandlerFunc) error {
+	const op = "broker.Consumer.Consume"
+
+	log := c.log.With(slog.String("op", op), slog.String("queue", c.queue))
+
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	go c.drain(ctx, handlerCtx, cancelHandlers, log)
+
+	delay := minReconnectDelay
+
+	for {
+		err := c.session(ctx, handlerCtx, handler)
+		if ctx.Err() != nil {
+			log.Info("consumer drained", slog.Int64("in_flight", c.metrics.inFlight.Load()))
+			return nil
+		}
+
+		if errors.Is(err, ErrClosed) {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err != nil {
+			log.Warn("failed to subscribe, retrying", slog.Duration("delay", delay), slog.Any("error", err))
+			delay = min(delay*2, maxReconnectDelay)
+		} else {
+			log.Warn("delivery channel closed, resubscribing")
+			delay = minReconnectDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// * drain отменяет контекст обработчиков, если после отмены ctx они
+// * не завершились за drainTimeout
+func (c *Consumer) drain(ctx, handlerCtx context.Context, cancelHandlers context.CancelFunc, log *slog.Logger) {
+	select {
+	case <-ctx.Done():
+	case <-handlerCtx.Done():
+		return
+	}
+
+	log.Info("draining consumer",
+		slog.Int64("in_flight", c.metrics.inFlight.Load()),
+		slog.Duration("deadline", c.drainTimeout),
+	)
+
+	timer := time.NewTimer(c.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		log.Warn("drain deadline exceeded, cancelling in-flight handlers",
+			slog.Int64("in_flight", c.metrics.inFlight.Load()),
+		)
+		cancelHandlers()
+	case <-handlerCtx.Done():
+	}
+}
+
+// * session подписывается на очередь и раздаёт сообщения пулу обработчиков,
+// * пока подписка не закроется или не будет отменён ctx. Выданные, но не
+// * взятые в работу сообщения возвращаются в очередь при закрытии подписки
+func (c *Consumer) session(ctx, handlerCtx context.Context, handler HandlerFunc) error {
+	msgs, closeFn, err := c.source.Deliveries(ctx, c.queue, c.workers)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case d, ok := <-msgs:
+					if !ok {
+						return
+					}
+
+					// * select мог выбрать сообщение уже после отмены
+					if ctx.Err() != nil {
+						_ = d.Nack(false, true)
+						return
+					}
+
+					c.handle(handlerCtx, d, handler)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+func (c *Consumer) handle(ctx context.Context, d amqp.Delivery, handler HandlerFunc) {
+	const op = "broker.Consumer.handle"
+
+	c.metrics.inFlight.Add(1)
+	defer c.metrics.inFlight.Add(-1)
+
+	start := time.Now()
+	handleErr := handler(ctx, d.Body)
+	c.metrics.observeLatency(time.Since(start))
+
+	outcome, err := c.policy.Settle(ctx, c.queue, d, handleErr)
+	c.metrics.observeOutcome(outcome)
+
+	if err != nil {
+		c.metrics.settleErrors.Add(1)
+
+		c.log.Error("failed to settle message",
+			slog.String("op", op),
+			slog.String("queue", c.queue),
+			slog.String("message_id", d.MessageId),
+			slog.Any("error", err),
+		)
+	}
+
+	if handleErr != nil {
+		c.log.Warn("failed to handle message",
+			slog.String("op", op),
+			slog.String("queue", c.queue),
+			slog.String("message_id", d.MessageId),
+			slog.String("outcome", string(outcome)),
+			slog.Int("attempt", RetryCount(d.Headers)+1),
+			slog.Any("error", handleErr),
+		)
+	}
+}
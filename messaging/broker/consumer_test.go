@@ -0,0 +1,362 @@
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"messaging/broker"
+)
+
+// * consumerEnv запускает Consume в отдельной горутине поверх memBroker
+type consumerEnv struct {
+	broker   *memBroker
+	consumer *broker.Consumer
+	cancel   context.CancelFunc
+	done     chan struct{} // * закрыт после возврата Consume
+	err      error
+}
+
+func startConsumer(
+	t *testing.T,
+	b *memBroker,
+	workers int,
+	policy broker.Policy,
+	drainTimeout time.Duration,
+	handler broker.HandlerFunc,
+) *consumerEnv {
+	t.Helper()
+
+	c := broker.NewConsumer(b, slog.New(slog.DiscardHandler), "results", workers, policy, drainTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	env := &consumerEnv{broker: b, consumer: c, cancel: cancel, done: make(chan struct{})}
+
+	go func() {
+		defer close(env.done)
+		env.err = c.Consume(ctx, handler)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-env.done
+	})
+
+	return env
+}
+
+// * stop отменяет контекст и ждёт возврата Consume
+func (e *consumerEnv) stop(t *testing.T) time.Duration {
+	t.Helper()
+
+	start := time.Now()
+	e.cancel()
+
+	select {
+	case <-e.done:
+		if e.err != nil {
+			t.Fatalf("Consume: %v", e.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Consume did not return after cancel")
+	}
+
+	return time.Since(start)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// * blockingHandler держит обработку до release и считает одновременные вызовы
+type blockingHandler struct {
+	release chan struct{}
+
+	mu      sync.Mutex
+	active  int
+	peak    int
+	started int
+	ctxErrs []error
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{release: make(chan struct{})}
+}
+
+// * handle ждёт release; ignoreRelease оставляет только отмену контекста
+func (h *blockingHandler) handle(ignoreRelease bool) broker.HandlerFunc {
+	release := h.release
+	if ignoreRelease {
+		release = nil
+	}
+
+	return func(ctx context.Context, _ []byte) error {
+		h.mu.Lock()
+		h.active++
+		h.started++
+		h.peak = max(h.peak, h.active)
+		h.mu.Unlock()
+
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+
+		h.mu.Lock()
+		h.active--
+		h.ctxErrs = append(h.ctxErrs, ctx.Err())
+		h.mu.Unlock()
+
+		return ctx.Err()
+	}
+}
+
+func (h *blockingHandler) state() (active, peak, started int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.active, h.peak, h.started
+}
+
+func (h *blockingHandler) errs() []error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]error(nil), h.ctxErrs...)
+}
+
+func (b *memBroker) counts() (acks, nacks int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.acks, b.nacks
+}
+
+// * Пул ограничен workers: больше сообщений не обрабатывается и не выдаётся
+func TestConsumerWorkerPoolBound(t *testing.T) {
+	const workers, messages = 3, 10
+
+	b := newMemBroker()
+	for range messages {
+		b.push("results", "msg")
+	}
+
+	h := newBlockingHandler()
+	env := startConsumer(t, b, workers, broker.Requeue, time.Second, h.handle(false))
+
+	waitFor(t, "pool to fill", func() bool {
+		active, _, _ := h.state()
+		return active == workers
+	})
+
+	// * даём consumer время взять лишнее, если ограничение не работает
+	time.Sleep(20 * time.Millisecond)
+
+	if active, _, _ := h.state(); active != workers {
+		t.Errorf("active handlers = %d, want %d", active, workers)
+	}
+
+	if got := b.unackedCount(); got != workers {
+		t.Errorf("unacked = %d, want prefetch %d", got, workers)
+	}
+
+	if got := env.consumer.Stats().InFlight; got != workers {
+		t.Errorf("Stats.InFlight = %d, want %d", got, workers)
+	}
+
+	close(h.release)
+
+	waitFor(t, "all messages acked", func() bool { return env.consumer.Stats().Acked == messages })
+
+	if _, peak, _ := h.state(); peak != workers {
+		t.Errorf("peak concurrency = %d, want %d", peak, workers)
+	}
+
+	env.stop(t)
+}
+
+// * После отмены новые сообщения не берутся, текущие дорабатывают с живым
+// * контекстом и подтверждаются, невзятые возвращаются в очередь
+func TestConsumerDrain(t *testing.T) {
+	const workers, messages = 2, 5
+
+	b := newMemBroker()
+	for range messages {
+		b.push("results", "msg")
+	}
+
+	h := newBlockingHandler()
+	env := startConsumer(t, b, workers, broker.Requeue, 5*time.Second, h.handle(false))
+
+	waitFor(t, "pool to fill", func() bool {
+		active, _, _ := h.state()
+		return active == workers
+	})
+
+	env.cancel()
+
+	// * обработчики завершаются сами, задолго до drainTimeout
+	time.Sleep(20 * time.Millisecond)
+	close(h.release)
+
+	env.stop(t)
+
+	if _, _, started := h.state(); started != workers {
+		t.Errorf("handlers started = %d, want %d", started, workers)
+	}
+
+	for _, err := range h.errs() {
+		if err != nil {
+			t.Errorf("handler context cancelled during drain: %v", err)
+		}
+	}
+
+	stats := env.consumer.Stats()
+	if stats.Acked != workers || stats.Nacked != 0 || stats.InFlight != 0 {
+		t.Errorf("Stats = acked %d, nacked %d, in flight %d; want %d, 0, 0",
+			stats.Acked, stats.Nacked, stats.InFlight, workers)
+	}
+
+	if got := b.len("results"); got != messages-workers {
+		t.Errorf("results has %d messages, want %d", got, messages-workers)
+	}
+
+	if got := b.unackedCount(); got != 0 {
+		t.Errorf("%d messages left unacked", got)
+	}
+}
+
+// * Обработчики, не уложившиеся в drainTimeout, получают отмену контекста,
+// * а их сообщения возвращаются в очередь политикой
+func TestConsumerDrainTimeout(t *testing.T) {
+	const workers, drainTimeout = 2, 50 * time.Millisecond
+
+	b := newMemBroker()
+	for range workers {
+		b.push("results", "msg")
+	}
+
+	h := newBlockingHandler()
+	env := startConsumer(t, b, workers, broker.Requeue, drainTimeout, h.handle(true))
+
+	waitFor(t, "pool to fill", func() bool {
+		active, _, _ := h.state()
+		return active == workers
+	})
+
+	if elapsed := env.stop(t); elapsed < drainTimeout {
+		t.Errorf("Consume returned after %s, before drain deadline %s", elapsed, drainTimeout)
+	}
+
+	errs := h.errs()
+	if len(errs) != workers {
+		t.Fatalf("%d handlers finished, want %d", len(errs), workers)
+	}
+
+	for _, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("handler context error = %v, want %v", err, context.Canceled)
+		}
+	}
+
+	stats := env.consumer.Stats()
+	if stats.Nacked != workers || stats.Outcomes[broker.OutcomeRequeued] != workers {
+		t.Errorf("Stats = nacked %d, requeued %d; want %d", stats.Nacked, stats.Outcomes[broker.OutcomeRequeued], workers)
+	}
+
+	if got := b.len("results"); got != workers {
+		t.Errorf("results has %d messages, want %d", got, workers)
+	}
+}
+
+// * Nacked считает только вызовы Nack; перекладывание в очередь ожидания
+// * и DLQ политикой повторов подтверждает исходное сообщение и считается отдельно
+func TestConsumerStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		bodies []string
+		policy func(b *memBroker) broker.Policy
+		want   broker.Stats
+	}{
+		{
+			name:   "requeue",
+			bodies: []string{"ok", "flaky"},
+			policy: func(*memBroker) broker.Policy { return broker.Requeue },
+			want:   broker.Stats{Acked: 2, Nacked: 1},
+		},
+		{
+			name:   "discard",
+			bodies: []string{"ok", "flaky"},
+			policy: func(*memBroker) broker.Policy { return broker.Discard },
+			want:   broker.Stats{Acked: 1, Nacked: 1},
+		},
+		{
+			name:   "retry",
+			bodies: []string{"ok", "flaky", "permanent"},
+			policy: func(b *memBroker) broker.Policy { return broker.NewRetryPolicy(b, 3, time.Second) },
+			want:   broker.Stats{Acked: 2, Retried: 1, DeadLettered: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newMemBroker()
+			for _, body := range tt.bodies {
+				b.push("results", body)
+			}
+
+			var flakyFailed atomic.Bool
+
+			handler := func(_ context.Context, body []byte) error {
+				switch string(body) {
+				case "flaky":
+					if flakyFailed.CompareAndSwap(false, true) {
+						return errTransient
+					}
+				case "permanent":
+					return broker.Permanent(errors.New("bad message"))
+				}
+
+				return nil
+			}
+
+			env := startConsumer(t, b, 2, tt.policy(b), time.Second, handler)
+
+			waitFor(t, "all messages settled", func() bool {
+				// * сообщение из очереди ожидания возвращается по TTL
+				b.expire("results")
+
+				s := env.consumer.Stats()
+				return s.Acked+s.Nacked+s.Retried+s.DeadLettered == tt.want.Acked+tt.want.Nacked+tt.want.Retried+tt.want.DeadLettered &&
+					b.len("results") == 0 && b.unackedCount() == 0
+			})
+
+			env.stop(t)
+
+			got := env.consumer.Stats()
+			if got.Acked != tt.want.Acked || got.Nacked != tt.want.Nacked ||
+				got.Retried != tt.want.Retried || got.DeadLettered != tt.want.DeadLettered {
+				t.Errorf("Stats = acked %d, nacked %d, retried %d, dead lettered %d; want %d, %d, %d, %d",
+					got.Acked, got.Nacked, got.Retried, got.DeadLettered,
+					tt.want.Acked, tt.want.Nacked, tt.want.Retried, tt.want.DeadLettered)
+			}
+
+			if _, nacks := b.counts(); got.Nacked != int64(nacks) {
+				t.Errorf("Stats.Nacked = %d, broker saw %d Nack calls", got.Nacked, nacks)
+			}
+		})
+	}
+}
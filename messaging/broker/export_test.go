@@ -0,0 +1,11 @@
+package broker
+
+import "log/slog"
+
+// * Conn открывает тестам соединение, которое подставляет ConnectWith
+type Conn = conn
+
+// * ConnectWith — Connect с подменённым открытием соединения
+func ConnectWith(url string, log *slog.Logger, dial func(url string) (Conn, error)) (*Connection, error) {
+	return connect(url, log, dial)
+}
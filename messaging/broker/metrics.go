@@ -0,0 +1,145 @@
+package broker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// * latencyBuckets — верхние границы корзин гистограммы времени обработки
+var latencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	30 * time.Second,
+}
+
+var outcomes = []Outcome{OutcomeAcked, OutcomeRequeued, OutcomeDiscarded, OutcomeRetried, OutcomeDeadLettered}
+
+// * Stats — снимок метрик consumer с момента запуска сервиса
+type Stats struct {
+	Queue        string            `json:"queue"`
+	Workers      int               `json:"workers"`
+	InFlight     int64             `json:"in_flight"`
+	Acked        int64             `json:"acked"`
+	Nacked       int64             `json:"nacked"`        // * Nack: возвращены в очередь или отброшены в DLQ
+	Retried      int64             `json:"retried"`       // * переложены в очередь ожидания
+	DeadLettered int64             `json:"dead_lettered"` // * переложены в DLQ политикой повторов
+	SettleErrors int64             `json:"settle_errors"`
+	Outcomes     map[Outcome]int64 `json:"outcomes"`
+	Latency      LatencyStats      `json:"latency"`
+}
+
+// * LatencyStats — время работы обработчика. Buckets накопительные:
+// * число сообщений, обработанных не дольше LE
+type LatencyStats struct {
+	Count   int64           `json:"count"`
+	AvgMs   float64         `json:"avg_ms"`
+	MaxMs   float64         `json:"max_ms"`
+	Buckets []LatencyBucket `json:"buckets"`
+}
+
+type LatencyBucket struct {
+	LE    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+type metrics struct {
+	inFlight     atomic.Int64
+	settleErrors atomic.Int64
+	outcomes     map[Outcome]*atomic.Int64
+
+	latencyCount atomic.Int64
+	latencySum   atomic.Int64 // * наносекунды
+	latencyMax   atomic.Int64
+	buckets      []atomic.Int64 // * последняя корзина — +Inf
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		outcomes: make(map[Outcome]*atomic.Int64, len(outcomes)),
+		buckets:  make([]atomic.Int64, len(latencyBuckets)+1),
+	}
+
+	for _, o := range outcomes {
+		m.outcomes[o] = new(atomic.Int64)
+	}
+
+	return m
+}
+
+func (m *metrics) observeLatency(d time.Duration) {
+	m.latencyCount.Add(1)
+	m.latencySum.Add(int64(d))
+
+	for {
+		cur := m.latencyMax.Load()
+		if int64(d) <= cur || m.latencyMax.CompareAndSwap(cur, int64(d)) {
+			break
+		}
+	}
+
+	i := 0
+	for i < len(latencyBuckets) && d > latencyBuckets[i] {
+		i++
+	}
+
+	m.buckets[i].Add(1)
+}
+
+func (m *metrics) observeOutcome(o Outcome) {
+	if counter, ok := m.outcomes[o]; ok {
+		counter.Add(1)
+	}
+}
+
+func (m *metrics) snapshot() Stats {
+	s := Stats{
+		InFlight:     m.inFlight.Load(),
+		SettleErrors: m.settleErrors.Load(),
+		Outcomes:     make(map[Outcome]int64, len(m.outcomes)),
+	}
+
+	for o, counter := range m.outcomes {
+		n := counter.Load()
+		s.Outcomes[o] = n
+
+		switch o {
+		case OutcomeAcked:
+			s.Acked = n
+		case OutcomeRequeued, OutcomeDiscarded:
+			s.Nacked += n
+		case OutcomeRetried:
+			s.Retried = n
+		case OutcomeDeadLettered:
+			s.DeadLettered = n
+		}
+	}
+
+	s.Latency.Count = m.latencyCount.Load()
+	if s.Latency.Count > 0 {
+		s.Latency.AvgMs = milliseconds(time.Duration(m.latencySum.Load() / s.Latency.Count))
+	}
+	s.Latency.MaxMs = milliseconds(time.Duration(m.latencyMax.Load()))
+
+	var cumulative int64
+	for i := range m.buckets {
+		cumulative += m.buckets[i].Load()
+
+		le := "+Inf"
+		if i < len(latencyBuckets) {
+			le = latencyBuckets[i].String()
+		}
+
+		s.Latency.Buckets = append(s.Latency.Buckets, LatencyBucket{LE: le, Count: cumulative})
+	}
+
+	return s
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,211 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"messaging/envelope"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	HeaderRetryCount    = "x-retry-count"
+	HeaderFailureReason = "x-failure-reason"
+	HeaderOriginalQueue = "x-original-queue"
+)
+
+// * Outcome — что стало с сообщением после обработки
+type Outcome string
+
+const (
+	OutcomeAcked        Outcome = "acked"
+	OutcomeRequeued     Outcome = "requeued"
+	OutcomeDiscarded    Outcome = "discarded"
+	OutcomeRetried      Outcome = "retried"
+	OutcomeDeadLettered Outcome = "dead_lettered"
+)
+
+// * Policy подтверждает или отклоняет сообщение по результату обработчика.
+// * handleErr == nil означает успешную обработку
+type Policy interface {
+	Settle(ctx context.Context, queue string, d amqp.Delivery, handleErr error) (Outcome, error)
+}
+
+// * PolicyFunc позволяет использовать функцию как Policy
+type PolicyFunc func(ctx context.Context, queue string, d amqp.Delivery, handleErr error) (Outcome, error)
+
+func (f PolicyFunc) Settle(ctx context.Context, queue string, d amqp.Delivery, handleErr error) (Outcome, error) {
+	return f(ctx, queue, d, handleErr)
+}
+
+// * Requeue возвращает неудачное сообщение в очередь для немедленного повтора
+var Requeue = PolicyFunc(func(_ context.Context, _ string, d amqp.Delivery, handleErr error) (Outcome, error) {
+	if handleErr == nil {
+		return OutcomeAcked, d.Ack(false)
+	}
+
+	return OutcomeRequeued, d.Nack(false, true)
+})
+
+// * Discard отклоняет неудачное сообщение без повтора: очередь, объявленная
+// * через Queue, отправит его через DLX в DLQ
+var Discard = PolicyFunc(func(_ context.Context, _ string, d amqp.Delivery, handleErr error) (Outcome, error) {
+	if handleErr == nil {
+		return OutcomeAcked, d.Ack(false)
+	}
+
+	return OutcomeDiscarded, d.Nack(false, false)
+})
+
+// * Republisher кладёт сообщение в очередь по имени; обычно это *Publisher
+// * default exchange
+type Republisher interface {
+	Publish(ctx context.Context, routingKey string, msg amqp.Publishing) error
+}
+
+// * RetryPolicy откладывает неудачные сообщения в очереди ожидания с растущим
+// * TTL и после MaxAttempts попыток переносит их в DLQ. Ошибки, обёрнутые
+// * в Permanent, и некорректные конверты (envelope.IsInvalid) уходят в DLQ
+// * сразу. Очереди ожидания объявляются через Topology
+type RetryPolicy struct {
+	pub         Republisher
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+// * NewRetryPolicy принимает publisher default exchange: сообщения
+// * перекладываются в очереди ожидания и DLQ по их именам
+func NewRetryPolicy(pub Republisher, maxAttempts int, baseDelay time.Duration) *RetryPolicy {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &RetryPolicy{
+		pub:         pub,
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+	}
+}
+
+// * Delay возвращает задержку перед повтором после attempt-й неудачной попытки:
+// * BaseDelay, 2*BaseDelay, 4*BaseDelay...
+func (p *RetryPolicy) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	return p.baseDelay << (attempt - 1)
+}
+
+// * Topology объявляет очереди ожидания для очереди, объявленной через Queue.
+// * Очередь ожидания держит сообщение TTL и через default exchange
+// * возвращает его в основную очередь (dead-lettering по истечении TTL)
+func (p *RetryPolicy) Topology(queueName string) Topology {
+	return func(ch Channel) error {
+		for attempt := 1; attempt < p.maxAttempts; attempt++ {
+			delay := p.Delay(attempt)
+			args := amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queueName,
+			}
+
+			if err := declareQueue(ch, RetryQueueName(queueName, delay), args); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// * Settle подтверждает сообщение только после того, как оно обработано
+// * либо переложено в очередь повтора или DLQ. В DLQ сообщение попадает
+// * с причиной отказа в заголовках. Если переложить не удалось, сообщение
+// * возвращается в основную очередь
+func (p *RetryPolicy) Settle(ctx context.Context, queue string, d amqp.Delivery, handleErr error) (Outcome, error) {
+	if handleErr == nil {
+		return OutcomeAcked, d.Ack(false)
+	}
+
+	attempt := RetryCount(d.Headers) + 1
+
+	target, outcome := RetryQueueName(queue, p.Delay(attempt)), OutcomeRetried
+	if IsPermanent(handleErr) || envelope.IsInvalid(handleErr) || attempt >= p.maxAttempts {
+		target, outcome = DeadLetterQueueName(queue), OutcomeDeadLettered
+	}
+
+	if err := p.republish(ctx, target, queue, d, attempt, handleErr); err != nil {
+		return OutcomeRequeued, errors.Join(
+			fmt.Errorf("republish to %s: %w", target, err),
+			d.Nack(false, true),
+		)
+	}
+
+	return outcome, d.Ack(false)
+}
+
+func (p *RetryPolicy) republish(
+	ctx context.Context,
+	target, queue string,
+	d amqp.Delivery,
+	attempt int,
+	reason error,
+) error {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+
+	headers[HeaderRetryCount] = int32(attempt)
+	headers[HeaderFailureReason] = reason.Error()
+	headers[HeaderOriginalQueue] = queue
+
+	return p.pub.Publish(ctx, target, amqp.Publishing{
+		Headers:       headers,
+		ContentType:   d.ContentType,
+		Type:          d.Type,
+		MessageId:     d.MessageId,
+		CorrelationId: d.CorrelationId,
+		Timestamp:     d.Timestamp,
+		Body:          d.Body,
+	})
+}
+
+// * RetryCount читает число уже сделанных неудачных попыток из заголовков
+func RetryCount(headers amqp.Table) int {
+	switch v := headers[HeaderRetryCount].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// * Permanent помечает ошибку как неисправимую: повторять такое сообщение бессмысленно
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
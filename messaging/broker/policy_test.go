@@ -0,0 +1,224 @@
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"messaging/broker"
+	"messaging/envelope"
+)
+
+var errTransient = errors.New("database unavailable")
+
+func settle(t *testing.T, b *memBroker, policy broker.Policy, queue string, handleErr error) (broker.Outcome, error) {
+	t.Helper()
+
+	d, ok := b.get(queue)
+	if !ok {
+		t.Fatalf("queue %s is empty", queue)
+	}
+
+	return policy.Settle(context.Background(), queue, d, handleErr)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := broker.NewRetryPolicy(newMemBroker(), 5, time.Second)
+
+	for attempt, want := range map[int]time.Duration{0: time.Second, 1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second} {
+		if got := p.Delay(attempt); got != want {
+			t.Errorf("Delay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+// * Смена задержек в конфиге даёт новые имена очередей ожидания, а не
+// * повторное объявление существующих с другим TTL
+func TestRetryQueueNameEncodesTTL(t *testing.T) {
+	if got := broker.RetryQueueName("results", 10*time.Second); got != "results.retry.10s" {
+		t.Errorf("RetryQueueName = %q, want results.retry.10s", got)
+	}
+
+	before := broker.NewRetryPolicy(newMemBroker(), 3, time.Second)
+	after := broker.NewRetryPolicy(newMemBroker(), 3, 5*time.Second)
+
+	for attempt := 1; attempt < 3; attempt++ {
+		old := broker.RetryQueueName("results", before.Delay(attempt))
+		updated := broker.RetryQueueName("results", after.Delay(attempt))
+
+		if old == updated {
+			t.Errorf("attempt %d: queue name %q did not change with delay", attempt, old)
+		}
+	}
+}
+
+// * Временная ошибка откладывает сообщение в очереди ожидания с растущей
+// * задержкой, после MaxAttempts попыток оно уходит в DLQ с причиной отказа
+func TestRetryPolicyRetriesThenDeadLetters(t *testing.T) {
+	const maxAttempts = 3
+
+	b := newMemBroker()
+	p := broker.NewRetryPolicy(b, maxAttempts, time.Second)
+
+	b.push("results", "msg")
+
+	for attempt := 1; attempt < maxAttempts; attempt++ {
+		outcome, err := settle(t, b, p, "results", errTransient)
+		if err != nil || outcome != broker.OutcomeRetried {
+			t.Fatalf("attempt %d: Settle = %s, %v; want %s", attempt, outcome, err, broker.OutcomeRetried)
+		}
+
+		retryQueue := broker.RetryQueueName("results", p.Delay(attempt))
+		if b.len(retryQueue) != 1 {
+			t.Fatalf("attempt %d: %s has %d messages, want 1", attempt, retryQueue, b.len(retryQueue))
+		}
+
+		if got := broker.RetryCount(b.peek(retryQueue).Headers); got != attempt {
+			t.Errorf("attempt %d: retry count header = %d", attempt, got)
+		}
+
+		b.expire("results")
+	}
+
+	outcome, err := settle(t, b, p, "results", errTransient)
+	if err != nil || outcome != broker.OutcomeDeadLettered {
+		t.Fatalf("last attempt: Settle = %s, %v; want %s", outcome, err, broker.OutcomeDeadLettered)
+	}
+
+	dlq := broker.DeadLetterQueueName("results")
+	if b.len(dlq) != 1 {
+		t.Fatalf("%s has %d messages, want 1", dlq, b.len(dlq))
+	}
+
+	dead := b.peek(dlq)
+	if string(dead.Body) != "msg" || dead.MessageId != "msg" {
+		t.Errorf("dead-lettered message = %q (id %q), want msg", dead.Body, dead.MessageId)
+	}
+
+	if got := dead.Headers[broker.HeaderFailureReason]; got != errTransient.Error() {
+		t.Errorf("failure reason = %v, want %q", got, errTransient)
+	}
+
+	if got := dead.Headers[broker.HeaderOriginalQueue]; got != "results" {
+		t.Errorf("original queue = %v, want results", got)
+	}
+
+	if got := broker.RetryCount(dead.Headers); got != maxAttempts {
+		t.Errorf("retry count = %d, want %d", got, maxAttempts)
+	}
+
+	if b.len("results") != 0 || b.unackedCount() != 0 {
+		t.Errorf("results has %d messages and %d unacked, want none", b.len("results"), b.unackedCount())
+	}
+}
+
+// * Неисправимые ошибки и некорректные конверты не повторяются
+func TestRetryPolicyDeadLettersImmediately(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "permanent", err: broker.Permanent(errors.New("product not found"))},
+		{name: "wrapped permanent", err: fmt.Errorf("handle: %w", broker.Permanent(errors.New("bad url")))},
+		{name: "malformed envelope", err: fmt.Errorf("decode: %w", envelope.ErrMalformed)},
+		{name: "unknown type", err: envelope.ErrUnknownType},
+		{name: "unsupported version", err: envelope.ErrUnsupportedVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newMemBroker()
+			p := broker.NewRetryPolicy(b, 5, time.Second)
+
+			b.push("results", "msg")
+
+			outcome, err := settle(t, b, p, "results", tt.err)
+			if err != nil || outcome != broker.OutcomeDeadLettered {
+				t.Fatalf("Settle = %s, %v; want %s", outcome, err, broker.OutcomeDeadLettered)
+			}
+
+			if b.len(broker.DeadLetterQueueName("results")) != 1 || b.len(broker.RetryQueueName("results", p.Delay(1))) != 0 {
+				t.Errorf("message not dead-lettered on first attempt")
+			}
+
+			if got := broker.RetryCount(b.peek(broker.DeadLetterQueueName("results")).Headers); got != 1 {
+				t.Errorf("retry count = %d, want 1", got)
+			}
+		})
+	}
+}
+
+// * Если переложить сообщение не удалось, оно не теряется: возвращается в очередь
+func TestRetryPolicyRequeuesWhenRepublishFails(t *testing.T) {
+	b := newMemBroker()
+	p := broker.NewRetryPolicy(b, 3, time.Second)
+
+	b.push("results", "msg")
+	b.failPublish = errBroker
+
+	outcome, err := settle(t, b, p, "results", errTransient)
+	if !errors.Is(err, errBroker) || outcome != broker.OutcomeRequeued {
+		t.Fatalf("Settle = %s, %v; want %s, %v", outcome, err, broker.OutcomeRequeued, errBroker)
+	}
+
+	if b.len("results") != 1 || b.unackedCount() != 0 {
+		t.Fatalf("results has %d messages and %d unacked, want 1 and 0", b.len("results"), b.unackedCount())
+	}
+
+	if !b.peek("results").Redelivered {
+		t.Error("requeued message is not marked redelivered")
+	}
+
+	// * после восстановления брокера сообщение проходит обычный путь повтора
+	b.failPublish = nil
+
+	outcome, err = settle(t, b, p, "results", errTransient)
+	if err != nil || outcome != broker.OutcomeRetried {
+		t.Fatalf("Settle after recovery = %s, %v; want %s", outcome, err, broker.OutcomeRetried)
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    broker.Policy
+		handleErr error
+		want      broker.Outcome
+		queue     string // * где сообщение окажется после Settle
+	}{
+		{name: "requeue success", policy: broker.Requeue, want: broker.OutcomeAcked},
+		{name: "requeue failure", policy: broker.Requeue, handleErr: errTransient, want: broker.OutcomeRequeued, queue: "results"},
+		{name: "discard success", policy: broker.Discard, want: broker.OutcomeAcked},
+		{name: "discard failure", policy: broker.Discard, handleErr: errTransient, want: broker.OutcomeDiscarded, queue: "results.dlq"},
+		{name: "retry success", policy: broker.NewRetryPolicy(newMemBroker(), 3, time.Second), want: broker.OutcomeAcked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newMemBroker()
+			b.push("results", "msg")
+
+			outcome, err := settle(t, b, tt.policy, "results", tt.handleErr)
+			if err != nil || outcome != tt.want {
+				t.Fatalf("Settle = %s, %v; want %s", outcome, err, tt.want)
+			}
+
+			if b.unackedCount() != 0 {
+				t.Errorf("%d messages left unacked", b.unackedCount())
+			}
+
+			for _, queue := range []string{"results", "results.dlq"} {
+				want := 0
+				if queue == tt.queue {
+					want = 1
+				}
+
+				if got := b.len(queue); got != want {
+					t.Errorf("%s has %d messages, want %d", queue, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"messaging/envelope"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var ErrNotConfirmed = errors.New("message not confirmed by broker")
+
+// * Publisher публикует сообщения в exchange через канал в режиме publisher
+// * confirms. Publisher default exchange ("") публикует прямо в очередь по её
+// * имени. Канал открывается заново, если брокер его закрыл или соединение
+// * было восстановлено
+type Publisher struct {
+	conn     *Connection
+	exchange string
+
+	mu sync.Mutex
+	ch Channel
+}
+
+func NewPublisher(conn *Connection, exchange string) *Publisher {
+	return &Publisher{
+		conn:     conn,
+		exchange: exchange,
+	}
+}
+
+// * Publish возвращает nil только после ack брокера: сообщение записано
+// * в очередь и переживёт перезапуск брокера
+func (p *Publisher) Publish(ctx context.Context, routingKey string, msg amqp.Publishing) error {
+	const op = "broker.Publisher.Publish"
+
+	if msg.DeliveryMode == 0 {
+		msg.DeliveryMode = amqp.Persistent
+	}
+
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+
+	confirm, err := p.publish(ctx, routingKey, msg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// * nack приходит и при закрытии канала до подтверждения
+	if !acked {
+		return fmt.Errorf("%s: %w", op, ErrNotConfirmed)
+	}
+
+	return nil
+}
+
+// * PublishEnvelope публикует конверт с ключом, равным его типу, дублируя
+// * type, id и correlation_id в свойствах AMQP сообщения
+func (p *Publisher) PublishEnvelope(ctx context.Context, env envelope.Envelope) error {
+	body, err := env.Encode()
+	if err != nil {
+		return err
+	}
+
+	return p.Publish(ctx, env.Type, publishing(env, body))
+}
+
+// * PublishEncoded публикует уже закодированный конверт, например из outbox.
+// * Тело проверяется, чтобы в очередь не попало сообщение, которое получатель отклонит
+func (p *Publisher) PublishEncoded(ctx context.Context, body []byte) error {
+	env, err := envelope.Decode(body)
+	if err != nil {
+		return err
+	}
+
+	return p.Publish(ctx, env.Type, publishing(env, body))
+}
+
+// * Close закрывает канал публикации; соединение остаётся открытым
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ch == nil {
+		return nil
+	}
+
+	err := p.ch.Close()
+	p.ch = nil
+
+	return err
+}
+
+// * publish отправляет сообщение под мьютексом: номера подтверждений
+// * выдаются каналом по порядку публикаций
+func (p *Publisher) publish(ctx context.Context, routingKey string, msg amqp.Publishing) (Confirmation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ch == nil || p.ch.IsClosed() {
+		ch, err := p.conn.Channel(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := ch.Confirm(false); err != nil {
+			_ = ch.Close()
+			return nil, err
+		}
+
+		p.ch = ch
+	}
+
+	confirm, err := p.ch.PublishConfirmed(ctx, p.exchange, routingKey, msg)
+	if err != nil {
+		// * следующая публикация откроет новый канал
+		_ = p.ch.Close()
+		p.ch = nil
+
+		return nil, err
+	}
+
+	return confirm, nil
+}
+
+func publishing(env envelope.Envelope, body []byte) amqp.Publishing {
+	return amqp.Publishing{
+		ContentType:   "application/json",
+		Type:          env.Type,
+		MessageId:     env.ID,
+		CorrelationId: env.CorrelationID,
+		Body:          body,
+	}
+}
+
+// * Producer публикует сообщения одного типа, оборачивая их в конверт
+type Producer struct {
+	pub     *Publisher
+	msgType string
+}
+
+func NewProducer(pub *Publisher, msgType string) *Producer {
+	return &Producer{
+		pub:     pub,
+		msgType: msgType,
+	}
+}
+
+// * PublishJSON публикует msg как payload конверта. correlation_id берётся из контекста
+func (p *Producer) PublishJSON(ctx context.Context, msg any) error {
+	env, err := envelope.New(p.msgType, msg)
+	if err != nil {
+		return err
+	}
+
+	env.CorrelationID = envelope.CorrelationID(ctx)
+
+	return p.pub.PublishEnvelope(ctx, env)
+}
+
+// * PublishDerived публикует msg как ответ на сообщение parentID. id конверта
+// * выводится из parentID: если исходное сообщение обработано дважды, второй
+// * ответ придёт с тем же id, и получатель его отбросит
+func (p *Producer) PublishDerived(ctx context.Context, parentID string, msg any) error {
+	env, err := envelope.New(p.msgType, msg)
+	if err != nil {
+		return err
+	}
+
+	env.ID = envelope.DerivedID(parentID, p.msgType)
+	env.CorrelationID = envelope.CorrelationID(ctx)
+
+	return p.pub.PublishEnvelope(ctx, env)
+}
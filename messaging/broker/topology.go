@@ -0,0 +1,114 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// * Схема очередей:
+// *
+// *	<exchange> (topic) --type--> <queue> --reject--> <exchange>.dlx --<queue>--> <queue>.dlq
+// *	                              |   ^
+// *	                      retry   v   | TTL
+// *	                       <queue>.retry.<TTL>
+// *
+// * Сообщения публикуются в topic exchange с ключом, равным типу конверта.
+// * Очередь, отклонившая сообщение без повторной постановки, отправляет его
+// * через DLX в свою DLQ. Очереди ожидания объявляются RetryPolicy получателя
+
+// * ErrTopologyMismatch — очередь уже существует с другими аргументами
+// * (PRECONDITION_FAILED). Брокер не меняет аргументы существующей очереди,
+// * её нужно удалить вручную или объявить под другим именем
+var ErrTopologyMismatch = errors.New("queue exists with different arguments")
+
+// * DeadLetterExchangeName — DLX для всех очередей exchange
+func DeadLetterExchangeName(exchange string) string {
+	return exchange + ".dlx"
+}
+
+// * RetryQueueName — очередь ожидания с TTL delay. TTL входит в имя: после
+// * смены задержек в конфиге объявляются новые очереди, а не старые с другими
+// * аргументами. Старые очереди сами возвращают сообщения в основную и
+// * после этого их можно удалить
+func RetryQueueName(queueName string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%s", queueName, delay)
+}
+
+func DeadLetterQueueName(queueName string) string {
+	return queueName + ".dlq"
+}
+
+// * Exchange объявляет topic exchange и его DLX. Достаточно для сервиса,
+// * который только публикует и не владеет очередями получателя
+func Exchange(name string) Topology {
+	return func(ch Channel) error {
+		if err := ch.ExchangeDeclare(name, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("declare exchange %s: %w", name, err)
+		}
+
+		dlx := DeadLetterExchangeName(name)
+		if err := ch.ExchangeDeclare(dlx, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("declare exchange %s: %w", dlx, err)
+		}
+
+		return nil
+	}
+}
+
+// * Queue объявляет exchange, очередь с привязками по routingKeys и её DLQ.
+// * Аргументы очереди зависят только от имён, поэтому её объявляют и
+// * отправитель, и получатель: сообщение не потеряется, если получатель
+// * ещё ни разу не запускался
+func Queue(exchange, name string, routingKeys ...string) Topology {
+	return func(ch Channel) error {
+		if err := Exchange(exchange)(ch); err != nil {
+			return err
+		}
+
+		dlx := DeadLetterExchangeName(exchange)
+		args := amqp.Table{
+			"x-dead-letter-exchange":    dlx,
+			"x-dead-letter-routing-key": name,
+		}
+
+		if err := declareQueue(ch, name, args); err != nil {
+			return err
+		}
+
+		for _, key := range routingKeys {
+			if err := ch.QueueBind(name, key, exchange, false, nil); err != nil {
+				return fmt.Errorf("bind queue %s to %s: %w", name, key, err)
+			}
+		}
+
+		dlq := DeadLetterQueueName(name)
+		if err := declareQueue(ch, dlq, nil); err != nil {
+			return err
+		}
+
+		if err := ch.QueueBind(dlq, name, dlx, false, nil); err != nil {
+			return fmt.Errorf("bind queue %s: %w", dlq, err)
+		}
+
+		return nil
+	}
+}
+
+// * declareQueue объявляет durable очередь и отличает расхождение аргументов
+// * с существующей очередью от остальных ошибок брокера
+func declareQueue(ch Channel, name string, args amqp.Table) error {
+	_, err := ch.QueueDeclare(name, true, false, false, false, args)
+	if err == nil {
+		return nil
+	}
+
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed {
+		return fmt.Errorf("declare queue %s: %w: %s", name, ErrTopologyMismatch, amqpErr.Reason)
+	}
+
+	return fmt.Errorf("declare queue %s: %w", name, err)
+}
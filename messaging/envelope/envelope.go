@@ -0,0 +1,229 @@
+// * Package envelope — общий формат сообщений между сервисами.
+// *
+// * Каждое сообщение в очереди — JSON объект:
+// *
+// *	{
+// *	  "type": "product.parse_requested",
+// *	  "version": 1,
+// *	  "id": "6f1c...",
+// *	  "occurred_at": "2026-04-20T09:00:00Z",
+// *	  "correlation_id": "...",
+// *	  "payload": { ... }
+// *	}
+// *
+// * version относится к схеме payload данного type. Получатель отклоняет
+// * неизвестные type и version вместо того, чтобы разбирать payload наугад
+package envelope
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// * Типы сообщений и очереди, по которым они ходят
+const (
+	// * main_service -> parsing_service: задание на парсинг страницы продукта
+	TypeParseRequested = "product.parse_requested"
+	// * parsing_service -> main_service: результат или ошибка парсинга
+	TypeParsed = "product.parsed"
+	// * auth_service, main_service -> email_sender: письмо по шаблону
+	TypeEmailRequested = "email.requested"
+	// * auth_service -> main_service: события об аккаунтах
+	TypeUserDeleted = "user.deleted"
+	TypeUserUpdated = "user.updated"
+)
+
+// * versions — поддерживаемые версии схемы payload для каждого типа
+var versions = map[string]int{
+	TypeParseRequested: 1,
+	TypeParsed:         1,
+	TypeEmailRequested: 1,
+	TypeUserDeleted:    1,
+	TypeUserUpdated:    1,
+}
+
+var (
+	ErrMalformed          = errors.New("malformed envelope")
+	ErrUnknownType        = errors.New("unknown message type")
+	ErrUnsupportedVersion = errors.New("unsupported message version")
+)
+
+type Envelope struct {
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	ID            string          `json:"id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// * New оборачивает payload в конверт текущей версии типа msgType
+// * с новым id и текущим временем
+func New(msgType string, payload any) (Envelope, error) {
+	const op = "envelope.New"
+
+	version, ok := versions[msgType]
+	if !ok {
+		return Envelope{}, fmt.Errorf("%s: %w: %q", op, ErrUnknownType, msgType)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("%s: marshal payload: %w", op, err)
+	}
+
+	return Envelope{
+		Type:       msgType,
+		Version:    version,
+		ID:         NewID(),
+		OccurredAt: time.Now().UTC(),
+		Payload:    body,
+	}, nil
+}
+
+// * Marshal собирает конверт и сразу кодирует его для публикации
+func Marshal(msgType string, payload any, correlationID string) ([]byte, error) {
+	env, err := New(msgType, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	env.CorrelationID = correlationID
+
+	return env.Encode()
+}
+
+// * Encode кодирует конверт в тело сообщения
+func (e Envelope) Encode() ([]byte, error) {
+	const op = "envelope.Encode"
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return body, nil
+}
+
+// * Decode разбирает тело сообщения и проверяет тип и версию. Payload не
+// * разбирается: для этого есть Unmarshal или DecodePayload
+func Decode(body []byte) (Envelope, error) {
+	const op = "envelope.Decode"
+
+	var env Envelope
+
+	if err := json.Unmarshal(body, &env); err != nil {
+		return Envelope{}, fmt.Errorf("%s: %w: %v", op, ErrMalformed, err)
+	}
+
+	if env.Type == "" || env.ID == "" || len(env.Payload) == 0 {
+		return Envelope{}, fmt.Errorf("%s: %w: missing type, id or payload", op, ErrMalformed)
+	}
+
+	version, ok := versions[env.Type]
+	if !ok {
+		return Envelope{}, fmt.Errorf("%s: %w: %q", op, ErrUnknownType, env.Type)
+	}
+
+	if env.Version != version {
+		return Envelope{}, fmt.Errorf("%s: %w: %s v%d", op, ErrUnsupportedVersion, env.Type, env.Version)
+	}
+
+	return env, nil
+}
+
+// * DecodePayload раскладывает payload в v
+func (e Envelope) DecodePayload(v any) error {
+	const op = "envelope.DecodePayload"
+
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("%s: %s: %w: %v", op, e.Type, ErrMalformed, err)
+	}
+
+	return nil
+}
+
+// * Unmarshal разбирает сообщение ожидаемого типа msgType и его payload в v
+func Unmarshal(body []byte, msgType string, v any) (Envelope, error) {
+	const op = "envelope.Unmarshal"
+
+	env, err := Decode(body)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	if env.Type != msgType {
+		return Envelope{}, fmt.Errorf("%s: %w: got %q, want %q", op, ErrUnknownType, env.Type, msgType)
+	}
+
+	if err := env.DecodePayload(v); err != nil {
+		return Envelope{}, err
+	}
+
+	return env, nil
+}
+
+// * Correlation возвращает correlation_id цепочки, начатой этим сообщением:
+// * унаследованный, а для первого сообщения цепочки — его собственный id
+func (e Envelope) Correlation() string {
+	if e.CorrelationID != "" {
+		return e.CorrelationID
+	}
+
+	return e.ID
+}
+
+type correlationKey struct{}
+
+// * WithCorrelationID сохраняет correlation_id в контексте: сообщения,
+// * опубликованные при обработке запроса или другого сообщения, наследуют его
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// * CorrelationID возвращает correlation_id из контекста или пустую строку
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// * IsInvalid сообщает, что сообщение нельзя обработать ни при какой повторной
+// * доставке: повреждённый конверт, неизвестный тип или версия
+func IsInvalid(err error) bool {
+	return errors.Is(err, ErrMalformed) ||
+		errors.Is(err, ErrUnknownType) ||
+		errors.Is(err, ErrUnsupportedVersion)
+}
+
+// * NewID возвращает случайный UUID v4
+func NewID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// * DerivedID возвращает UUID v5 от id исходного сообщения и типа ответа.
+// * Повторная обработка того же сообщения даёт ответ с тем же id,
+// * и получатель отбрасывает его как дубликат
+func DerivedID(parentID, msgType string) string {
+	sum := sha1.Sum([]byte(parentID + "/" + msgType))
+
+	b := sum[:16]
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,242 @@
+package envelope_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"messaging/envelope"
+)
+
+// * types — все типы, которыми обмениваются сервисы. Для каждого в testdata
+// * лежит пример сообщения текущей версии: изменение формата конверта или
+// * версии типа ломает этот тест раньше, чем получателей
+var types = []string{
+	envelope.TypeParseRequested,
+	envelope.TypeParsed,
+	envelope.TypeEmailRequested,
+	envelope.TypeUserDeleted,
+	envelope.TypeUserUpdated,
+}
+
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestGoldenMessages(t *testing.T) {
+	for _, msgType := range types {
+		t.Run(msgType, func(t *testing.T) {
+			golden, err := os.ReadFile(filepath.Join("testdata", msgType+".json"))
+			if err != nil {
+				t.Fatalf("read golden: %v", err)
+			}
+
+			env, err := envelope.Decode(golden)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+
+			if env.Type != msgType || env.Version != 1 {
+				t.Errorf("type, version = %s, %d; want %s, 1", env.Type, env.Version, msgType)
+			}
+
+			var payload map[string]any
+			if err := env.DecodePayload(&payload); err != nil {
+				t.Fatalf("DecodePayload: %v", err)
+			}
+
+			// * повторное кодирование даёт те же поля в том же виде
+			encoded, err := env.Encode()
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			var want bytes.Buffer
+			if err := json.Compact(&want, golden); err != nil {
+				t.Fatalf("compact golden: %v", err)
+			}
+
+			if !bytes.Equal(encoded, want.Bytes()) {
+				t.Errorf("Encode =\n%s\nwant\n%s", encoded, want.Bytes())
+			}
+		})
+	}
+}
+
+func TestNewEnvelope(t *testing.T) {
+	for _, msgType := range types {
+		t.Run(msgType, func(t *testing.T) {
+			before := time.Now().UTC()
+
+			env, err := envelope.New(msgType, map[string]int{"id": 1})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			if env.Version != 1 {
+				t.Errorf("version = %d, want 1", env.Version)
+			}
+
+			if !uuidV4.MatchString(env.ID) {
+				t.Errorf("id = %s, want UUID v4", env.ID)
+			}
+
+			if env.OccurredAt.Location() != time.UTC || env.OccurredAt.Before(before) {
+				t.Errorf("occurred_at = %s, want current UTC time", env.OccurredAt)
+			}
+
+			if string(env.Payload) != `{"id":1}` {
+				t.Errorf("payload = %s, want {\"id\":1}", env.Payload)
+			}
+		})
+	}
+
+	if _, err := envelope.New("product.unknown", nil); !errors.Is(err, envelope.ErrUnknownType) {
+		t.Errorf("New with unknown type: %v, want %v", err, envelope.ErrUnknownType)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	type payload struct {
+		ID    int64  `json:"id"`
+		Title string `json:"title"`
+	}
+
+	body, err := envelope.Marshal(envelope.TypeParsed, payload{ID: 42, Title: "mug"}, "corr")
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var got payload
+	env, err := envelope.Unmarshal(body, envelope.TypeParsed, &got)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if got != (payload{ID: 42, Title: "mug"}) {
+		t.Errorf("payload = %+v", got)
+	}
+
+	if env.CorrelationID != "corr" || env.Correlation() != "corr" {
+		t.Errorf("correlation = %q, %q; want corr", env.CorrelationID, env.Correlation())
+	}
+
+	// * без correlation_id поле не попадает в сообщение, а цепочку начинает id
+	body, err = envelope.Marshal(envelope.TypeParsed, payload{}, "")
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	if bytes.Contains(body, []byte("correlation_id")) {
+		t.Errorf("empty correlation_id encoded: %s", body)
+	}
+
+	env, err = envelope.Decode(body)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if env.Correlation() != env.ID {
+		t.Errorf("Correlation() = %q, want id %q", env.Correlation(), env.ID)
+	}
+}
+
+// * Получатель отклоняет сообщение, которое нельзя обработать ни при какой
+// * повторной доставке, и IsInvalid отправляет его в DLQ без повторов
+func TestDecodeRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		{name: "not json", body: `not json`, want: envelope.ErrMalformed},
+		{name: "array", body: `[]`, want: envelope.ErrMalformed},
+		{name: "missing type", body: `{"version":1,"id":"x","payload":{}}`, want: envelope.ErrMalformed},
+		{name: "missing id", body: `{"type":"product.parsed","version":1,"payload":{}}`, want: envelope.ErrMalformed},
+		{name: "missing payload", body: `{"type":"product.parsed","version":1,"id":"x"}`, want: envelope.ErrMalformed},
+		{name: "bare payload", body: `{"id":42,"title":"mug","price":100}`, want: envelope.ErrMalformed},
+		{name: "unknown type", body: `{"type":"product.deleted","version":1,"id":"x","payload":{}}`, want: envelope.ErrUnknownType},
+		{name: "missing version", body: `{"type":"product.parsed","id":"x","payload":{}}`, want: envelope.ErrUnsupportedVersion},
+		{name: "future version", body: `{"type":"product.parsed","version":2,"id":"x","payload":{}}`, want: envelope.ErrUnsupportedVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := envelope.Decode([]byte(tt.body))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Decode: %v, want %v", err, tt.want)
+			}
+
+			if !envelope.IsInvalid(err) {
+				t.Errorf("IsInvalid(%v) = false", err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalRejects(t *testing.T) {
+	body, err := envelope.Marshal(envelope.TypeParsed, map[string]any{"id": "not a number"}, "")
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var payload struct {
+		ID int64 `json:"id"`
+	}
+
+	// * сообщение другого типа в очереди не разбирается как ожидаемое
+	if _, err := envelope.Unmarshal(body, envelope.TypeParseRequested, &payload); !errors.Is(err, envelope.ErrUnknownType) {
+		t.Errorf("Unmarshal with wrong type: %v, want %v", err, envelope.ErrUnknownType)
+	}
+
+	_, err = envelope.Unmarshal(body, envelope.TypeParsed, &payload)
+	if !errors.Is(err, envelope.ErrMalformed) || !envelope.IsInvalid(err) {
+		t.Errorf("Unmarshal with bad payload: %v, want invalid %v", err, envelope.ErrMalformed)
+	}
+
+	if envelope.IsInvalid(context.DeadlineExceeded) {
+		t.Error("IsInvalid(transient error) = true")
+	}
+}
+
+func TestCorrelationIDContext(t *testing.T) {
+	ctx := context.Background()
+
+	if got := envelope.CorrelationID(ctx); got != "" {
+		t.Errorf("CorrelationID(empty ctx) = %q", got)
+	}
+
+	if got := envelope.CorrelationID(envelope.WithCorrelationID(ctx, "")); got != "" {
+		t.Errorf("CorrelationID after empty id = %q", got)
+	}
+
+	if got := envelope.CorrelationID(envelope.WithCorrelationID(ctx, "corr")); got != "corr" {
+		t.Errorf("CorrelationID = %q, want corr", got)
+	}
+}
+
+func TestDerivedID(t *testing.T) {
+	parent := envelope.NewID()
+
+	id := envelope.DerivedID(parent, envelope.TypeParsed)
+
+	if again := envelope.DerivedID(parent, envelope.TypeParsed); again != id {
+		t.Fatalf("DerivedID is not stable: %s != %s", again, id)
+	}
+
+	if other := envelope.DerivedID(envelope.NewID(), envelope.TypeParsed); other == id {
+		t.Fatalf("different parents share id %s", id)
+	}
+
+	if other := envelope.DerivedID(parent, envelope.TypeEmailRequested); other == id {
+		t.Fatalf("different types share id %s", id)
+	}
+
+	if len(id) != 36 || id[14] != '5' {
+		t.Fatalf("DerivedID = %s, want UUID version 5", id)
+	}
+}
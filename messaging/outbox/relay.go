@@ -0,0 +1,273 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"messaging/broker"
+	"messaging/envelope"
+)
+
+// * maxRetryDelay ограничивает задержку повтора неудачной публикации
+const maxRetryDelay = time.Hour
+
+// * Message — строка outbox: payload хранит закодированный конверт целиком,
+// * поэтому id конверта не меняется при повторной публикации.
+// * Attempts — число уже неудавшихся публикаций
+type Message struct {
+	ID       int64
+	Payload  []byte
+	Attempts int
+}
+
+// * Storage — таблица outbox сервиса. Строки пишутся в одной транзакции
+// * с изменением данных, Relay только забирает и отмечает их.
+// * ClaimOutbox не выдаёт отложенные (RetryOutbox) и неудавшиеся (FailOutbox) строки.
+// * RetryOutbox сохраняет переданное число попыток, FailOutbox добавляет одну
+type Storage interface {
+	ClaimOutbox(ctx context.Context, claimTimeout time.Duration, limit int) ([]Message, error)
+	MarkOutboxPublished(ctx context.Context, ids []int64) error
+	ReleaseOutbox(ctx context.Context, ids []int64) error
+	RetryOutbox(ctx context.Context, id int64, attempts int, delay time.Duration, reason string) error
+	FailOutbox(ctx context.Context, id int64, reason string) error
+	DeletePublishedOutbox(ctx context.Context, before time.Time) (int64, error)
+}
+
+// * Publisher публикует готовый конверт с ключом по его типу и ждёт подтверждения брокера
+type Publisher interface {
+	PublishEncoded(ctx context.Context, body []byte) error
+}
+
+// * Relay публикует сообщения из outbox с подтверждением брокера и отмечает
+// * их отправленными. Строка появляется в одной транзакции с изменением данных,
+// * поэтому недоступный брокер задерживает сообщение, но не теряет его.
+// * Доставка at-least-once: если relay упадёт между публикацией и отметкой,
+// * после claimTimeout сообщение уйдёт повторно с тем же id конверта.
+// * Получатель обязан отбрасывать дубликаты по id конверта.
+// * Сообщение, которое брокер не принял (nack), откладывается с растущей
+// * задержкой и не задерживает следующие; после maxAttempts отказов или сразу,
+// * если конверт повреждён, оно помечается неудавшимся и остаётся в таблице
+// * для разбора. Пока брокер недоступен, попытки не расходуются
+type Relay struct {
+	log          *slog.Logger
+	storage      Storage
+	publisher    Publisher
+	pollInterval time.Duration
+	claimTimeout time.Duration
+	retention    time.Duration
+	batchSize    int
+	maxAttempts  int
+	trigger      chan struct{}
+}
+
+func NewRelay(
+	log *slog.Logger,
+	storage Storage,
+	publisher Publisher,
+	pollInterval, claimTimeout, retention time.Duration,
+	batchSize, maxAttempts int,
+) *Relay {
+	return &Relay{
+		log:          log,
+		storage:      storage,
+		publisher:    publisher,
+		pollInterval: pollInterval,
+		claimTimeout: claimTimeout,
+		retention:    retention,
+		batchSize:    batchSize,
+		maxAttempts:  maxAttempts,
+		trigger:      make(chan struct{}, 1),
+	}
+}
+
+// * Trigger будит Relay после записи в outbox, чтобы не ждать pollInterval. Не блокируется
+func (r *Relay) Trigger() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// * Run блокируется до отмены контекста
+func (r *Relay) Run(ctx context.Context) {
+	const op = "outbox.Relay.Run"
+
+	log := r.log.With(slog.String("op", op))
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.publishPending(ctx, log)
+		r.deletePublished(ctx, log)
+
+		select {
+		case <-ctx.Done():
+			log.Info("outbox relay stopped")
+			return
+		case <-ticker.C:
+		case <-r.trigger:
+		}
+	}
+}
+
+// * publishPending публикует пачки сообщений, пока outbox не опустеет
+// * или брокер не откажет
+func (r *Relay) publishPending(ctx context.Context, log *slog.Logger) {
+	for ctx.Err() == nil {
+		messages, err := r.storage.ClaimOutbox(ctx, r.claimTimeout, r.batchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error("failed to claim outbox messages", slog.Any("error", err))
+			}
+			return
+		}
+
+		if len(messages) == 0 {
+			return
+		}
+
+		published, err := r.publish(ctx, log, messages)
+
+		if len(published) > 0 {
+			if markErr := r.storage.MarkOutboxPublished(context.WithoutCancel(ctx), published); markErr != nil {
+				log.Error("failed to mark outbox messages published", slog.Any("error", markErr), slog.Int("count", len(published)))
+			} else {
+				log.Info("outbox messages published", slog.Int("count", len(published)))
+			}
+		}
+
+		if err != nil {
+			log.Error("failed to publish outbox messages",
+				slog.Any("error", err),
+				slog.Int("published", len(published)),
+				slog.Int("claimed", len(messages)),
+			)
+
+			return
+		}
+
+		if len(messages) < r.batchSize {
+			return
+		}
+	}
+}
+
+// * publish отправляет сообщения по порядку и возвращает id опубликованных.
+// * Повреждённый конверт не уйдёт ни с какой попытки: он помечается неудавшимся,
+// * и публикация продолжается. Иная ошибка прерывает проход, освободив остаток
+// * пачки: брокер не принял сообщение или недоступен
+func (r *Relay) publish(ctx context.Context, log *slog.Logger, messages []Message) ([]int64, error) {
+	published := make([]int64, 0, len(messages))
+
+	for i, msg := range messages {
+		err := r.publisher.PublishEncoded(ctx, msg.Payload)
+		if err == nil {
+			published = append(published, msg.ID)
+			continue
+		}
+
+		r.fail(log, msg, err)
+
+		if envelope.IsInvalid(err) {
+			continue
+		}
+
+		r.release(log, messages[i+1:])
+
+		return published, err
+	}
+
+	return published, nil
+}
+
+// * fail записывает неудачную публикацию. Попыткой считается только отказ
+// * брокера принять сообщение (nack) или повреждённый конверт: такое сообщение
+// * откладывается на retryDelay, а исчерпав попытки, помечается неудавшимся.
+// * Недоступный брокер, разрыв соединения или остановка сервиса попыткой не
+// * считаются: сообщение освобождается без задержки и ждёт брокер сколько угодно
+func (r *Relay) fail(log *slog.Logger, msg Message, publishErr error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if !errors.Is(publishErr, broker.ErrNotConfirmed) && !envelope.IsInvalid(publishErr) {
+		if err := r.storage.RetryOutbox(ctx, msg.ID, msg.Attempts, 0, publishErr.Error()); err != nil {
+			log.Error("failed to release outbox message", slog.Any("error", err), slog.Int64("id", msg.ID))
+		}
+
+		return
+	}
+
+	attempts := msg.Attempts + 1
+
+	if envelope.IsInvalid(publishErr) || attempts >= r.maxAttempts {
+		log.Error("outbox message failed permanently",
+			slog.Int64("id", msg.ID),
+			slog.Int("attempts", attempts),
+			slog.Any("error", publishErr),
+		)
+
+		if err := r.storage.FailOutbox(ctx, msg.ID, publishErr.Error()); err != nil {
+			log.Error("failed to mark outbox message failed", slog.Any("error", err), slog.Int64("id", msg.ID))
+		}
+
+		return
+	}
+
+	if err := r.storage.RetryOutbox(ctx, msg.ID, attempts, r.retryDelay(msg.Attempts), publishErr.Error()); err != nil {
+		log.Error("failed to postpone outbox message", slog.Any("error", err), slog.Int64("id", msg.ID))
+	}
+}
+
+// * retryDelay удваивает задержку после каждой неудачи, начиная с pollInterval
+func (r *Relay) retryDelay(attempts int) time.Duration {
+	delay := r.pollInterval
+	for range attempts {
+		if delay >= maxRetryDelay {
+			break
+		}
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
+
+// * release возвращает неопубликованные сообщения следующему проходу. Контекст
+// * отдельный: основной может быть уже отменён при остановке сервиса
+func (r *Relay) release(log *slog.Logger, messages []Message) {
+	if len(messages) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := r.storage.ReleaseOutbox(ctx, messageIDs(messages)); err != nil {
+		log.Error("failed to release outbox messages", slog.Any("error", err), slog.Int("count", len(messages)))
+	}
+}
+
+func (r *Relay) deletePublished(ctx context.Context, log *slog.Logger) {
+	deleted, err := r.storage.DeletePublishedOutbox(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error("failed to delete published outbox messages", slog.Any("error", err))
+		}
+		return
+	}
+
+	if deleted > 0 {
+		log.Info("published outbox messages deleted", slog.Int64("count", deleted))
+	}
+}
+
+func messageIDs(messages []Message) []int64 {
+	ids := make([]int64, 0, len(messages))
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+
+	return ids
+}
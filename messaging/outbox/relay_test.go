@@ -0,0 +1,328 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"messaging/broker"
+	"messaging/envelope"
+)
+
+// * fakeStorage повторяет семантику таблицы outbox: взятая строка не выдаётся
+// * повторно, пока её не освободят или не истечёт claimTimeout (expireClaims);
+// * отложенная — пока не пройдёт задержка (expireDelays); неудавшаяся — никогда
+type fakeStorage struct {
+	mu        sync.Mutex
+	messages  []Message
+	claimed   map[int64]bool
+	published map[int64]bool
+	attempts  map[int64]int
+	delayed   map[int64]time.Duration
+	failed    map[int64]bool
+	markErr   error
+}
+
+func newFakeStorage(n int) *fakeStorage {
+	s := &fakeStorage{
+		claimed:   make(map[int64]bool),
+		published: make(map[int64]bool),
+		attempts:  make(map[int64]int),
+		delayed:   make(map[int64]time.Duration),
+		failed:    make(map[int64]bool),
+	}
+	for i := 1; i <= n; i++ {
+		s.messages = append(s.messages, Message{ID: int64(i), Payload: fmt.Appendf(nil, `{"id":"msg-%d"}`, i)})
+	}
+
+	return s
+}
+
+func (s *fakeStorage) ClaimOutbox(_ context.Context, _ time.Duration, limit int) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var claimed []Message
+	for _, msg := range s.messages {
+		if len(claimed) == limit {
+			break
+		}
+
+		if s.published[msg.ID] || s.claimed[msg.ID] || s.failed[msg.ID] {
+			continue
+		}
+
+		if _, ok := s.delayed[msg.ID]; ok {
+			continue
+		}
+
+		s.claimed[msg.ID] = true
+		msg.Attempts = s.attempts[msg.ID]
+		claimed = append(claimed, msg)
+	}
+
+	return claimed, nil
+}
+
+func (s *fakeStorage) MarkOutboxPublished(_ context.Context, ids []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.markErr != nil {
+		return s.markErr
+	}
+
+	for _, id := range ids {
+		s.published[id] = true
+	}
+
+	return nil
+}
+
+func (s *fakeStorage) ReleaseOutbox(_ context.Context, ids []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.claimed, id)
+	}
+
+	return nil
+}
+
+func (s *fakeStorage) RetryOutbox(_ context.Context, id int64, attempts int, delay time.Duration, _ string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.claimed, id)
+	s.attempts[id] = attempts
+	if delay > 0 {
+		s.delayed[id] = delay
+	}
+
+	return nil
+}
+
+func (s *fakeStorage) FailOutbox(_ context.Context, id int64, _ string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.claimed, id)
+	s.attempts[id]++
+	s.failed[id] = true
+
+	return nil
+}
+
+func (s *fakeStorage) DeletePublishedOutbox(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// * expireClaims имитирует истечение claimTimeout после падения relay
+func (s *fakeStorage) expireClaims() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.claimed)
+}
+
+// * expireDelays имитирует истечение задержек отложенных сообщений
+// * и возвращает задержки, которые были назначены
+func (s *fakeStorage) expireDelays() map[int64]time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delays := maps.Clone(s.delayed)
+	clear(s.delayed)
+
+	return delays
+}
+
+// * fakePublisher отказывает, начиная с публикации номер failAt (с единицы),
+// * а тела из rejected не принимает никогда
+type fakePublisher struct {
+	mu        sync.Mutex
+	published []string
+	calls     int
+	failAt    int
+	rejected  map[string]error
+}
+
+var (
+	errBroker   = errors.New("broker unavailable")
+	errRejected = fmt.Errorf("publish: %w", broker.ErrNotConfirmed)
+)
+
+func (p *fakePublisher) PublishEncoded(_ context.Context, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls++
+	if p.failAt > 0 && p.calls >= p.failAt {
+		return errBroker
+	}
+
+	if err, ok := p.rejected[string(body)]; ok {
+		return err
+	}
+
+	p.published = append(p.published, string(body))
+
+	return nil
+}
+
+func newRelay(storage Storage, publisher Publisher, batchSize int) *Relay {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewRelay(log, storage, publisher, time.Minute, time.Minute, time.Hour, batchSize, 3)
+}
+
+func payloads(ids ...int) []string {
+	var out []string
+	for _, id := range ids {
+		out = append(out, fmt.Sprintf(`{"id":"msg-%d"}`, id))
+	}
+
+	return out
+}
+
+func TestRelayRetriesAfterPublishFailure(t *testing.T) {
+	storage := newFakeStorage(5)
+	publisher := &fakePublisher{failAt: 3}
+
+	r := newRelay(storage, publisher, 10)
+	r.publishPending(context.Background(), r.log)
+
+	if want := payloads(1, 2); !slices.Equal(publisher.published, want) {
+		t.Fatalf("published = %v, want %v", publisher.published, want)
+	}
+
+	// * неопубликованный хвост освобождён и уходит после восстановления брокера,
+	// * подтверждённые сообщения не повторяются
+	publisher.failAt = 0
+	r.publishPending(context.Background(), r.log)
+
+	if want := payloads(1, 2, 3, 4, 5); !slices.Equal(publisher.published, want) {
+		t.Fatalf("published after recovery = %v, want %v", publisher.published, want)
+	}
+}
+
+func TestRelayBrokerOutageDoesNotUseAttempts(t *testing.T) {
+	storage := newFakeStorage(2)
+	publisher := &fakePublisher{failAt: 1}
+
+	r := newRelay(storage, publisher, 10)
+
+	// * брокер недоступен дольше, чем хватило бы maxAttempts отказов
+	for range 10 {
+		r.publishPending(context.Background(), r.log)
+	}
+
+	if storage.failed[1] || storage.attempts[1] != 0 || len(storage.delayed) != 0 {
+		t.Fatalf("message 1 after outage: failed = %v, attempts = %d, delays = %v; want it pending",
+			storage.failed[1], storage.attempts[1], storage.delayed)
+	}
+
+	publisher.failAt = 0
+	r.publishPending(context.Background(), r.log)
+
+	if want := payloads(1, 2); !slices.Equal(publisher.published, want) {
+		t.Fatalf("published after recovery = %v, want %v", publisher.published, want)
+	}
+}
+
+func TestRelayFailingMessageDoesNotBlockOthers(t *testing.T) {
+	storage := newFakeStorage(4)
+	publisher := &fakePublisher{rejected: map[string]error{
+		payloads(2)[0]: errRejected,
+	}}
+
+	r := newRelay(storage, publisher, 10)
+
+	// * неудавшееся сообщение отложено: следующий проход публикует остальные, не дожидаясь его
+	r.publishPending(context.Background(), r.log)
+	r.publishPending(context.Background(), r.log)
+
+	if want := payloads(1, 3, 4); !slices.Equal(publisher.published, want) {
+		t.Fatalf("published = %v, want %v", publisher.published, want)
+	}
+
+	// * каждая неудача удваивает задержку; после maxAttempts сообщение больше не выдаётся
+	var delays []time.Duration
+	for range 3 {
+		delays = append(delays, storage.expireDelays()[2])
+		r.publishPending(context.Background(), r.log)
+	}
+
+	if want := []time.Duration{time.Minute, 2 * time.Minute, 0}; !slices.Equal(delays, want) {
+		t.Errorf("retry delays = %v, want %v", delays, want)
+	}
+
+	if !storage.failed[2] || storage.attempts[2] != 3 {
+		t.Errorf("message 2 failed = %v after %d attempts, want failed after 3", storage.failed[2], storage.attempts[2])
+	}
+
+	if publisher.calls != 6 {
+		t.Errorf("publish calls = %d, want 6", publisher.calls)
+	}
+}
+
+func TestRelayFailsInvalidEnvelopeAtOnce(t *testing.T) {
+	storage := newFakeStorage(3)
+	publisher := &fakePublisher{rejected: map[string]error{
+		payloads(1)[0]: fmt.Errorf("decode: %w", envelope.ErrMalformed),
+	}}
+
+	r := newRelay(storage, publisher, 10)
+	r.publishPending(context.Background(), r.log)
+
+	// * повреждённый конверт не прерывает проход и не повторяется
+	if want := payloads(2, 3); !slices.Equal(publisher.published, want) {
+		t.Fatalf("published = %v, want %v", publisher.published, want)
+	}
+
+	if !storage.failed[1] || storage.attempts[1] != 1 {
+		t.Errorf("message 1 failed = %v after %d attempts, want failed after 1", storage.failed[1], storage.attempts[1])
+	}
+}
+
+func TestRelayRepublishesAfterCrash(t *testing.T) {
+	storage := newFakeStorage(3)
+	storage.markErr = errors.New("connection reset")
+	publisher := &fakePublisher{}
+
+	r := newRelay(storage, publisher, 10)
+
+	// * брокер подтвердил сообщения, но отметка не сохранилась: relay "упал"
+	r.publishPending(context.Background(), r.log)
+
+	// * до истечения claimTimeout строки остаются за упавшим relay
+	r.publishPending(context.Background(), r.log)
+
+	if want := payloads(1, 2, 3); !slices.Equal(publisher.published, want) {
+		t.Fatalf("published before claim timeout = %v, want %v", publisher.published, want)
+	}
+
+	storage.markErr = nil
+	storage.expireClaims()
+	r.publishPending(context.Background(), r.log)
+
+	// * сообщения ушли повторно тем же телом, а значит с тем же id конверта:
+	// * получатель отбросит дубликаты
+	if want := payloads(1, 2, 3, 1, 2, 3); !slices.Equal(publisher.published, want) {
+		t.Fatalf("published after claim timeout = %v, want %v", publisher.published, want)
+	}
+
+	storage.expireClaims()
+	r.publishPending(context.Background(), r.log)
+
+	if len(publisher.published) != 6 {
+		t.Fatalf("published %d messages after they were marked, want 6", len(publisher.published))
+	}
+}
@@ -1,8 +1,19 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+
+	"messaging/broker"
+	"messaging/envelope"
+	"parsing_service/internal/config"
+	"parsing_service/internal/extractor"
+	"parsing_service/internal/fetcher"
+	sl "parsing_service/internal/lib/logger"
+	"parsing_service/internal/scraper"
 )
 
 const (
@@ -12,6 +23,71 @@ const (
 )
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := config.MustLoad()
+
+	log := setupLogger(cfg.Env)
+
+	log.Info("starting parsing service", slog.String("env", cfg.Env))
+
+	brokerConn, err := broker.Connect(cfg.RabbitMQURL, log)
+	if err != nil {
+		log.Error("failed to connect rabbitMQ", sl.Err(err))
+		os.Exit(1)
+	}
+	defer brokerConn.Close()
+
+	// * publisher default exchange перекладывает задания в очереди повторов и DLQ
+	retryPublisher := broker.NewPublisher(brokerConn, "")
+	defer retryPublisher.Close()
+
+	retry := broker.NewRetryPolicy(retryPublisher, cfg.Retry.MaxAttempts, cfg.Retry.BaseDelay)
+
+	topology := []broker.Topology{
+		broker.Queue(cfg.Exchange, cfg.QueueName, envelope.TypeParseRequested),
+		broker.Queue(cfg.Exchange, cfg.ResultQueueName, envelope.TypeParsed),
+		retry.Topology(cfg.QueueName),
+	}
+
+	for _, t := range topology {
+		if err := brokerConn.Declare(t); err != nil {
+			log.Error("failed to declare rabbitmq topology", sl.Err(err))
+			os.Exit(1)
+		}
+	}
+
+	log.Info("rabbitmq connected successfully",
+		slog.String("queue", cfg.QueueName),
+		slog.String("result_queue", cfg.ResultQueueName),
+		slog.Int("workers", cfg.WorkerPoolSize),
+	)
+
+	publisher := broker.NewPublisher(brokerConn, cfg.Exchange)
+	defer publisher.Close()
+
+	producer := broker.NewProducer(publisher, envelope.TypeParsed)
+
+	// * ошибка страницы отправляется результатом, сюда доходят только сбои
+	// * публикации (повторяются) и некорректные задания (сразу в DLQ)
+	consumer := broker.NewConsumer(brokerConn, log, cfg.QueueName, cfg.WorkerPoolSize, retry, cfg.DrainTimeout)
+
+	s := scraper.New(
+		log,
+		fetcher.New(cfg.HTTPClient.Timeout, cfg.HTTPClient.UserAgent, cfg.HTTPClient.MaxBodySize),
+		extractor.Default(),
+		producer,
+	)
+
+	log.Info("consumer started")
+
+	if err := consumer.Consume(ctx, s.HandleMessage); err != nil {
+		log.Error("failed to consume messages", sl.Err(err))
+		os.Exit(1)
+	}
+
+	log.Info("service gracefully stopped")
 }
 
 func setupLogger(env string) *slog.Logger {
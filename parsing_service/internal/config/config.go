@@ -3,19 +3,38 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
-	Env      string `yaml:"env" env-default:"local"`
-	RabbitMQ `yaml:"rabbitmq"`
+	Env        string `yaml:"env" env-default:"local"`
+	RabbitMQ   `yaml:"rabbitmq"`
+	Retry      `yaml:"retry"`
+	HTTPClient `yaml:"http_client"`
 }
 
 type RabbitMQ struct {
-	RabbitMQURL    string `yaml:"url" env-required:"true"`
-	QueueName      string `yaml:"queue_name" env-required:"true"`
-	WorkerPoolSize int    `yaml:"worker_pool_size" env-default:"10"`
+	RabbitMQURL     string        `yaml:"url" env-required:"true"`
+	Exchange        string        `yaml:"exchange" env-default:"price_monitoring"`
+	QueueName       string        `yaml:"queue_name" env-required:"true"`
+	ResultQueueName string        `yaml:"result_queue_name" env-default:"parsed_queue"`
+	WorkerPoolSize  int           `yaml:"worker_pool_size" env-default:"10"`
+	DrainTimeout    time.Duration `yaml:"drain_timeout" env-default:"20s"`
+}
+
+// * Retry — повторная обработка задания при временных ошибках:
+// * задержка перед n-й повторной попыткой равна BaseDelay * 2^(n-1)
+type Retry struct {
+	MaxAttempts int           `yaml:"max_attempts" env-default:"3"`
+	BaseDelay   time.Duration `yaml:"base_delay" env-default:"10s"`
+}
+
+type HTTPClient struct {
+	Timeout     time.Duration `yaml:"timeout" env-default:"15s"`
+	UserAgent   string        `yaml:"user_agent" env-default:"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36"`
+	MaxBodySize int64         `yaml:"max_body_size" env-default:"5242880"`
 }
 
 func MustLoad() *Config {
@@ -0,0 +1,127 @@
+package extractor
+
+import (
+	"strings"
+)
+
+// * Aliexpress разбирает встроенное состояние страницы (window.runParams
+// * или _init_data_). Старая вёрстка хранит данные в priceModule/titleModule/
+// * quantityModule, новая — в PRICE/PRODUCT_TITLE/QUANTITY_PC
+type Aliexpress struct{}
+
+func (Aliexpress) Extract(page []byte) (Result, error) {
+	if res, ok := aliexpressLegacy(page); ok {
+		return res, nil
+	}
+
+	if res, ok := aliexpressModern(page); ok {
+		return res, nil
+	}
+
+	if res, ok := fromJSONLD(page); ok {
+		return res, nil
+	}
+
+	return Result{}, ErrPriceNotFound
+}
+
+func aliexpressLegacy(page []byte) (Result, bool) {
+	priceModule, ok := embeddedObject(page, "priceModule")
+	if !ok {
+		return Result{}, false
+	}
+
+	price, currency, ok := aliexpressAmount(priceModule,
+		"minActivityAmount", "minAmount",
+	)
+	if !ok {
+		text := stringValue(priceModule["formatedActivityPrice"])
+		if text == "" {
+			text = stringValue(priceModule["formatedPrice"])
+		}
+
+		p, err := ParsePrice(text)
+		if err != nil {
+			return Result{}, false
+		}
+		price, currency = p, currencyFromText(text)
+	}
+
+	res := Result{
+		Price:    price,
+		Currency: currency,
+		InStock:  true,
+	}
+
+	if titleModule, ok := embeddedObject(page, "titleModule"); ok {
+		res.Title = stringValue(titleModule["subject"])
+	}
+
+	if quantityModule, ok := embeddedObject(page, "quantityModule"); ok {
+		if qty, ok := numberValue(quantityModule["totalAvailQuantity"]); ok {
+			res.InStock = qty > 0
+		}
+	}
+
+	return res, true
+}
+
+func aliexpressModern(page []byte) (Result, bool) {
+	priceBlock, ok := embeddedObject(page, "PRICE")
+	if !ok {
+		return Result{}, false
+	}
+
+	info, ok := priceBlock["targetSkuPriceInfo"].(map[string]any)
+	if !ok {
+		return Result{}, false
+	}
+
+	price, currency, ok := aliexpressAmount(info, "salePrice", "originalPrice")
+	if !ok {
+		text := stringValue(info["salePriceString"])
+
+		p, err := ParsePrice(text)
+		if err != nil {
+			return Result{}, false
+		}
+		price, currency = p, currencyFromText(text)
+	}
+
+	res := Result{
+		Price:    price,
+		Currency: currency,
+		InStock:  true,
+	}
+
+	if titleBlock, ok := embeddedObject(page, "PRODUCT_TITLE"); ok {
+		res.Title = stringValue(titleBlock["text"])
+	}
+
+	if quantityBlock, ok := embeddedObject(page, "QUANTITY_PC"); ok {
+		if qty, ok := numberValue(quantityBlock["totalAvailableInventory"]); ok {
+			res.InStock = qty > 0
+		}
+	}
+
+	return res, true
+}
+
+// * aliexpressAmount читает первую найденную сумму вида {"value": 12.34, "currency": "USD"}
+func aliexpressAmount(module map[string]any, keys ...string) (int, string, bool) {
+	for _, key := range keys {
+		amount, ok := module[key].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		price, err := ParsePrice(stringValue(amount["value"]))
+		if err != nil {
+			continue
+		}
+
+		return price, strings.ToUpper(stringValue(amount["currency"])), true
+	}
+
+	return 0, "", false
+}
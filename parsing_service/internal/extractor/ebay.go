@@ -0,0 +1,64 @@
+package extractor
+
+import (
+	"bytes"
+	"regexp"
+)
+
+var (
+	ebayPriceRe        = regexp.MustCompile(`(?is)<div[^>]+class\s*=\s*["'][^"']*x-price-primary[^"']*["'][^>]*>(.*?)</div>`)
+	ebayTitleRe        = regexp.MustCompile(`(?is)<h1[^>]+class\s*=\s*["'][^"']*x-item-title__mainTitle[^"']*["'][^>]*>(.*?)</h1>`)
+	ebayAvailabilityRe = regexp.MustCompile(`(?is)<div[^>]+class\s*=\s*["'][^"']*(?:x|d)-quantity__availability[^"']*["'][^>]*>(.*?)</div>`)
+)
+
+// * Ebay читает JSON-LD, а при его отсутствии — разметку блока покупки
+// * (x-price-primary, x-item-title__mainTitle, x-quantity__availability)
+type Ebay struct{}
+
+func (Ebay) Extract(page []byte) (Result, error) {
+	if res, ok := fromJSONLD(page); ok {
+		if ebayOutOfStock(page) {
+			res.InStock = false
+		}
+		return res, nil
+	}
+
+	m := ebayPriceRe.FindSubmatch(page)
+	if m == nil {
+		return Result{}, ErrPriceNotFound
+	}
+
+	text := innerText(m[1])
+
+	price, err := ParsePrice(text)
+	if err != nil {
+		return Result{}, ErrPriceNotFound
+	}
+
+	var title string
+	if t := ebayTitleRe.FindSubmatch(page); t != nil {
+		title = innerText(t[1])
+	} else {
+		title = pageTitle(page)
+	}
+
+	return Result{
+		Title:    title,
+		Price:    price,
+		Currency: currencyFromText(text),
+		InStock:  !ebayOutOfStock(page),
+	}, nil
+}
+
+// * ebayOutOfStock проверяет блок наличия и баннер завершённого лота
+func ebayOutOfStock(page []byte) bool {
+	if m := ebayAvailabilityRe.FindSubmatch(page); m != nil {
+		text := bytes.ToLower([]byte(innerText(m[1])))
+		if bytes.Contains(text, []byte("out of stock")) || bytes.Contains(text, []byte("sold out")) {
+			return true
+		}
+	}
+
+	return bytes.Contains(page, []byte("This listing was ended")) ||
+		bytes.Contains(page, []byte("This listing has ended"))
+}
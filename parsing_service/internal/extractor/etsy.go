@@ -0,0 +1,47 @@
+package extractor
+
+import (
+	"regexp"
+)
+
+var etsyPriceRe = regexp.MustCompile(`(?is)<p[^>]+data-selector\s*=\s*["']price-only["'][^>]*>(.*?)</p>`)
+
+// * Etsy публикует schema.org Product в JSON-LD, Open Graph теги
+// * и блок цены в buy box как запасной вариант
+type Etsy struct{}
+
+func (Etsy) Extract(page []byte) (Result, error) {
+	if res, ok := fromJSONLD(page); ok {
+		return res, nil
+	}
+
+	if res, ok := fromMeta(page); ok {
+		return res, nil
+	}
+
+	m := etsyPriceRe.FindSubmatch(page)
+	if m == nil {
+		return Result{}, ErrPriceNotFound
+	}
+
+	text := innerText(m[1])
+
+	price, err := ParsePrice(text)
+	if err != nil {
+		return Result{}, ErrPriceNotFound
+	}
+
+	meta := metaTags(page)
+
+	title := meta["og:title"]
+	if title == "" {
+		title = pageTitle(page)
+	}
+
+	return Result{
+		Title:    title,
+		Price:    price,
+		Currency: currencyFromText(text),
+		InStock:  true,
+	}, nil
+}
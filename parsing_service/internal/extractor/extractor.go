@@ -0,0 +1,61 @@
+package extractor
+
+import (
+	"errors"
+	"fmt"
+
+	"parsing_service/internal/models"
+)
+
+var (
+	ErrPriceNotFound          = errors.New("price not found")
+	ErrInvalidPrice           = errors.New("invalid price")
+	ErrUnsupportedMarketplace = errors.New("unsupported marketplace")
+)
+
+type Result struct {
+	Title    string
+	Price    int // * в минимальных единицах валюты (центы, копейки)
+	Currency string
+	InStock  bool
+}
+
+// * Extractor извлекает данные о продукте из HTML страницы конкретного маркетплейса.
+// * Если цену найти не удалось, возвращается ErrPriceNotFound
+type Extractor interface {
+	Extract(page []byte) (Result, error)
+}
+
+type Registry struct {
+	extractors map[models.Marketplace]Extractor
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		extractors: make(map[models.Marketplace]Extractor),
+	}
+}
+
+// * Default возвращает реестр с экстракторами всех поддерживаемых маркетплейсов
+func Default() *Registry {
+	r := NewRegistry()
+
+	r.Register(models.Etsy, Etsy{})
+	r.Register(models.Ebay, Ebay{})
+	r.Register(models.Aliexpress, Aliexpress{})
+
+	return r
+}
+
+func (r *Registry) Register(marketplace models.Marketplace, e Extractor) {
+	r.extractors[marketplace] = e
+}
+
+func (r *Registry) Extractor(marketplace models.Marketplace) (Extractor, error) {
+	e, ok := r.extractors[marketplace]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedMarketplace, marketplace)
+	}
+
+	return e, nil
+}
@@ -0,0 +1,118 @@
+package extractor_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"parsing_service/internal/extractor"
+	"parsing_service/internal/models"
+)
+
+// * Фикстуры в testdata — сокращённые сохранённые страницы маркетплейсов.
+// * Если сайт поменял вёрстку, обновите фикстуру и ожидаемый результат:
+// * тест должен падать, а не пропускать цену -1 в базу
+func TestExtractFixtures(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		marketplace models.Marketplace
+		want        extractor.Result
+		wantErr     error
+	}{
+		{
+			fixture:     "etsy_jsonld.html",
+			marketplace: models.Etsy,
+			want:        extractor.Result{Title: "Linen Apron & Pockets", Price: 3800, Currency: "EUR", InStock: true},
+		},
+		{
+			fixture:     "etsy_meta.html",
+			marketplace: models.Etsy,
+			want:        extractor.Result{Title: "Knitted Wool Scarf", Price: 129950, Currency: "GBP", InStock: false},
+		},
+		{
+			fixture:     "etsy_buybox.html",
+			marketplace: models.Etsy,
+			want:        extractor.Result{Title: "Holzschild personalisiert", Price: 123456, Currency: "EUR", InStock: true},
+		},
+		{
+			fixture:     "etsy_no_price.html",
+			marketplace: models.Etsy,
+			wantErr:     extractor.ErrPriceNotFound,
+		},
+		{
+			fixture:     "ebay_jsonld.html",
+			marketplace: models.Ebay,
+			want:        extractor.Result{Title: "Mechanical Keyboard", Price: 8990, Currency: "USD", InStock: false},
+		},
+		{
+			fixture:     "ebay_markup.html",
+			marketplace: models.Ebay,
+			want:        extractor.Result{Title: "Vintage Kamera & Objektiv", Price: 104900, Currency: "EUR", InStock: true},
+		},
+		{
+			fixture:     "ebay_sold_out.html",
+			marketplace: models.Ebay,
+			want:        extractor.Result{Title: "Road Bike Helmet | eBay", Price: 4500, Currency: "CAD", InStock: false},
+		},
+		{
+			fixture:     "ebay_no_price.html",
+			marketplace: models.Ebay,
+			wantErr:     extractor.ErrPriceNotFound,
+		},
+		{
+			fixture:     "aliexpress_legacy.html",
+			marketplace: models.Aliexpress,
+			want:        extractor.Result{Title: "USB-C Cable 2m", Price: 299, Currency: "EUR", InStock: false},
+		},
+		{
+			fixture:     "aliexpress_modern.html",
+			marketplace: models.Aliexpress,
+			want:        extractor.Result{Title: "Смарт-часы", Price: 129900, Currency: "RUB", InStock: true},
+		},
+		{
+			fixture:     "aliexpress_no_price.html",
+			marketplace: models.Aliexpress,
+			wantErr:     extractor.ErrPriceNotFound,
+		},
+	}
+
+	registry := extractor.Default()
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			page, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+
+			e, err := registry.Extractor(tt.marketplace)
+			if err != nil {
+				t.Fatalf("Extractor(%q): %v", tt.marketplace, err)
+			}
+
+			got, err := e.Extract(page)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Extract error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Extract = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryUnsupportedMarketplace(t *testing.T) {
+	_, err := extractor.Default().Extractor(models.Marketplace("amazon"))
+	if !errors.Is(err, extractor.ErrUnsupportedMarketplace) {
+		t.Fatalf("Extractor error = %v, want %v", err, extractor.ErrUnsupportedMarketplace)
+	}
+}
@@ -0,0 +1,88 @@
+package extractor
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	tagRe          = regexp.MustCompile(`(?s)<[^>]*>`)
+	currencyCodeRe = regexp.MustCompile(`\b(USD|EUR|GBP|CAD|AUD|RUB|UAH|KZT|CNY|JPY|PLN|TRY)\b`)
+)
+
+// * currencySymbols проверяются по порядку: составные обозначения раньше одиночного "$"
+var currencySymbols = []struct {
+	symbol string
+	code   string
+}{
+	{"US $", "USD"},
+	{"C $", "CAD"},
+	{"AU $", "AUD"},
+	{"$", "USD"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+	{"₽", "RUB"},
+	{"руб", "RUB"},
+	{"₴", "UAH"},
+	{"₸", "KZT"},
+	{"zł", "PLN"},
+}
+
+// * innerText убирает теги и лишние пробелы из фрагмента HTML
+func innerText(fragment []byte) string {
+	text := tagRe.ReplaceAll(fragment, []byte(" "))
+
+	return strings.Join(strings.Fields(html.UnescapeString(string(text))), " ")
+}
+
+// * currencyFromText определяет ISO код валюты по строке цены ("US $12.99", "12,99 €")
+func currencyFromText(text string) string {
+	if m := currencyCodeRe.FindString(text); m != "" {
+		return m
+	}
+
+	for _, c := range currencySymbols {
+		if strings.Contains(text, c.symbol) {
+			return c.code
+		}
+	}
+
+	return ""
+}
+
+// * embeddedObject ищет во встроенном JSON состоянии страницы объект по ключу
+// * ("key": {...}) и декодирует его. Берётся первое вхождение, которое удалось разобрать
+func embeddedObject(page []byte, key string) (map[string]any, bool) {
+	needle := []byte(`"` + key + `":`)
+
+	for rest := page; ; {
+		i := bytes.Index(rest, needle)
+		if i < 0 {
+			return nil, false
+		}
+		rest = rest[i+len(needle):]
+
+		dec := json.NewDecoder(bytes.NewReader(rest))
+		dec.UseNumber()
+
+		var obj map[string]any
+		if err := dec.Decode(&obj); err == nil && obj != nil {
+			return obj, true
+		}
+	}
+}
+
+func numberValue(v any) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,149 @@
+package extractor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var jsonLDRe = regexp.MustCompile(`(?is)<script[^>]+type\s*=\s*["']application/ld\+json["'][^>]*>(.*?)</script>`)
+
+// * fromJSONLD ищет schema.org Product с предложением (Offer/AggregateOffer)
+func fromJSONLD(page []byte) (Result, bool) {
+	for _, product := range jsonLDProducts(page) {
+		res, ok := productResult(product)
+		if ok {
+			return res, true
+		}
+	}
+
+	return Result{}, false
+}
+
+func jsonLDProducts(page []byte) []map[string]any {
+	var products []map[string]any
+
+	for _, m := range jsonLDRe.FindAllSubmatch(page, -1) {
+		dec := json.NewDecoder(bytes.NewReader(bytes.TrimSpace(m[1])))
+		dec.UseNumber()
+
+		var doc any
+		if err := dec.Decode(&doc); err != nil {
+			continue
+		}
+
+		collectProducts(doc, &products)
+	}
+
+	return products
+}
+
+// * collectProducts обходит массивы и @graph, собирая объекты с @type Product
+func collectProducts(node any, out *[]map[string]any) {
+	switch v := node.(type) {
+	case []any:
+		for _, item := range v {
+			collectProducts(item, out)
+		}
+	case map[string]any:
+		if hasType(v, "Product") || hasType(v, "ProductGroup") {
+			*out = append(*out, v)
+		}
+
+		if graph, ok := v["@graph"]; ok {
+			collectProducts(graph, out)
+		}
+	}
+}
+
+func hasType(obj map[string]any, typ string) bool {
+	switch t := obj["@type"].(type) {
+	case string:
+		return strings.EqualFold(t, typ)
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); ok && strings.EqualFold(s, typ) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func productResult(product map[string]any) (Result, bool) {
+	offer, ok := firstOffer(product["offers"])
+	if !ok {
+		return Result{}, false
+	}
+
+	rawPrice := stringValue(offer["price"])
+	if rawPrice == "" {
+		rawPrice = stringValue(offer["lowPrice"])
+	}
+
+	if rawPrice == "" {
+		if spec, ok := offer["priceSpecification"].(map[string]any); ok {
+			rawPrice = stringValue(spec["price"])
+		}
+	}
+
+	price, err := ParsePrice(rawPrice)
+	if err != nil {
+		return Result{}, false
+	}
+
+	// * Если наличие не указано, но предложение с ценой есть — считаем товар доступным
+	inStock, known := parseAvailability(stringValue(offer["availability"]))
+	if !known {
+		inStock = true
+	}
+
+	return Result{
+		Title:    html.UnescapeString(stringValue(product["name"])),
+		Price:    price,
+		Currency: strings.ToUpper(stringValue(offer["priceCurrency"])),
+		InStock:  inStock,
+	}, true
+}
+
+func firstOffer(node any) (map[string]any, bool) {
+	switch v := node.(type) {
+	case map[string]any:
+		// * AggregateOffer может содержать вложенный список offers
+		if nested, ok := v["offers"]; ok && v["price"] == nil && v["lowPrice"] == nil {
+			return firstOffer(nested)
+		}
+		return v, true
+	case []any:
+		for _, item := range v {
+			if offer, ok := firstOffer(item); ok {
+				return offer, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func stringValue(v any) string {
+	switch s := v.(type) {
+	case string:
+		return strings.TrimSpace(s)
+	case json.Number:
+		// * Числовое значение всегда с точкой, приводим к двум знакам,
+		// * чтобы ParsePrice не принял "12.345" за разделитель тысяч
+		f, err := s.Float64()
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", f)
+	case float64:
+		return fmt.Sprintf("%.2f", s)
+	}
+
+	return ""
+}
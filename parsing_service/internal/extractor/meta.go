@@ -0,0 +1,97 @@
+package extractor
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	metaTagRe  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrRe     = regexp.MustCompile(`(?s)([a-zA-Z_:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	titleTagRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// * fromMeta использует Open Graph и microdata мета-теги
+// * (product:price:amount, og:price:amount, itemprop="price")
+func fromMeta(page []byte) (Result, bool) {
+	meta := metaTags(page)
+
+	rawPrice := firstNonEmpty(meta, "product:price:amount", "og:price:amount", "price")
+
+	price, err := ParsePrice(rawPrice)
+	if err != nil {
+		return Result{}, false
+	}
+
+	inStock, known := parseAvailability(firstNonEmpty(meta, "product:availability", "og:availability", "availability"))
+	if !known {
+		inStock = true
+	}
+
+	title := firstNonEmpty(meta, "og:title", "twitter:title")
+	if title == "" {
+		title = pageTitle(page)
+	}
+
+	return Result{
+		Title:    title,
+		Price:    price,
+		Currency: strings.ToUpper(firstNonEmpty(meta, "product:price:currency", "og:price:currency", "pricecurrency")),
+		InStock:  inStock,
+	}, true
+}
+
+// * metaTags возвращает content мета-тегов по ключу property/name/itemprop в нижнем регистре.
+// * При повторе ключа сохраняется первое значение
+func metaTags(page []byte) map[string]string {
+	tags := make(map[string]string)
+
+	for _, tag := range metaTagRe.FindAll(page, -1) {
+		attrs := make(map[string]string)
+
+		for _, m := range attrRe.FindAllSubmatch(tag, -1) {
+			value := string(m[2])
+			if value == "" {
+				value = string(m[3])
+			}
+			attrs[strings.ToLower(string(m[1]))] = html.UnescapeString(strings.TrimSpace(value))
+		}
+
+		content, ok := attrs["content"]
+		if !ok {
+			continue
+		}
+
+		for _, key := range []string{"property", "name", "itemprop"} {
+			name := strings.ToLower(attrs[key])
+			if name == "" {
+				continue
+			}
+			if _, exists := tags[name]; !exists {
+				tags[name] = content
+			}
+		}
+	}
+
+	return tags
+}
+
+func pageTitle(page []byte) string {
+	m := titleTagRe.FindSubmatch(page)
+	if m == nil {
+		return ""
+	}
+
+	return html.UnescapeString(strings.TrimSpace(string(m[1])))
+}
+
+func firstNonEmpty(values map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if v := values[key]; v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,81 @@
+package extractor
+
+import (
+	"strconv"
+	"strings"
+)
+
+// * ParsePrice переводит строку цены ("US $1,234.56", "1 234,56 ₽", "12.5")
+// * в минимальные единицы валюты. Для диапазона цен берётся нижняя граница
+func ParsePrice(raw string) (int, error) {
+	if i := strings.IndexAny(raw, "-–"); i > 0 {
+		raw = raw[:i]
+	}
+
+	var b strings.Builder
+	for _, r := range raw {
+		if (r >= '0' && r <= '9') || r == '.' || r == ',' {
+			b.WriteRune(r)
+		}
+	}
+
+	s := strings.Trim(b.String(), ".,")
+	if s == "" {
+		return 0, ErrInvalidPrice
+	}
+
+	lastDot := strings.LastIndexByte(s, '.')
+	lastComma := strings.LastIndexByte(s, ',')
+	sep := max(lastDot, lastComma)
+
+	intPart, fracPart := s, ""
+	if sep >= 0 {
+		// * Разделитель дробной части: последний из двух разных, либо
+		// * единственный, после которого не больше двух цифр
+		tail := s[sep+1:]
+		if (lastDot >= 0 && lastComma >= 0) || len(tail) <= 2 {
+			intPart, fracPart = s[:sep], tail
+		}
+	}
+
+	intPart = strings.NewReplacer(".", "", ",", "").Replace(intPart)
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	switch len(fracPart) {
+	case 0:
+		fracPart = "00"
+	case 1:
+		fracPart += "0"
+	}
+
+	units, err := strconv.Atoi(intPart)
+	if err != nil {
+		return 0, ErrInvalidPrice
+	}
+
+	cents, err := strconv.Atoi(fracPart[:2])
+	if err != nil {
+		return 0, ErrInvalidPrice
+	}
+
+	return units*100 + cents, nil
+}
+
+// * parseAvailability разбирает значение schema.org/ItemAvailability
+func parseAvailability(raw string) (inStock bool, known bool) {
+	v := strings.ToLower(strings.TrimSpace(raw))
+	if i := strings.LastIndexByte(v, '/'); i >= 0 {
+		v = v[i+1:]
+	}
+
+	switch v {
+	case "instock", "in stock", "limitedavailability", "onlineonly", "instoreonly":
+		return true, true
+	case "outofstock", "out of stock", "soldout", "discontinued":
+		return false, true
+	}
+
+	return false, false
+}
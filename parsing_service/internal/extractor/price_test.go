@@ -0,0 +1,56 @@
+package extractor_test
+
+import (
+	"errors"
+	"testing"
+
+	"parsing_service/internal/extractor"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{name: "plain", raw: "12.5", want: 1250},
+		{name: "integer", raw: "€ 5", want: 500},
+		{name: "en thousands", raw: "US $1,234.56", want: 123456},
+		{name: "en millions", raw: "1,234,567.89", want: 123456789},
+		{name: "de thousands", raw: "1.234,56 €", want: 123456},
+		{name: "ru space thousands", raw: "1 234,56 ₽", want: 123456},
+		{name: "nbsp thousands", raw: "1 299,00 zł", want: 129900},
+		{name: "ch apostrophe thousands", raw: "CHF 1'234.50", want: 123450},
+		{name: "comma decimals", raw: "12,99 €", want: 1299},
+		{name: "single decimal digit", raw: "0,5", want: 50},
+		{name: "comma thousands without decimals", raw: "$1,234", want: 123400},
+		{name: "dot thousands without decimals", raw: "1.234.567 ₸", want: 123456700},
+		{name: "range", raw: "$10.00 - $20.00", want: 1000},
+		{name: "en dash range", raw: "10–20 €", want: 1000},
+		{name: "empty", raw: "", wantErr: true},
+		{name: "no digits", raw: "Sold out", wantErr: true},
+		{name: "currency only", raw: "US $", wantErr: true},
+		{name: "separators only", raw: "-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractor.ParsePrice(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, extractor.ErrInvalidPrice) {
+					t.Fatalf("ParsePrice(%q) = %d, %v; want %v", tt.raw, got, err, extractor.ErrInvalidPrice)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParsePrice(%q): %v", tt.raw, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParsePrice(%q) = %d, want %d", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrUnexpectedStatus = errors.New("unexpected response status")
+	ErrBodyTooLarge     = errors.New("response body too large")
+)
+
+type Fetcher struct {
+	client      *http.Client
+	userAgent   string
+	maxBodySize int64
+}
+
+func New(timeout time.Duration, userAgent string, maxBodySize int64) *Fetcher {
+	return &Fetcher{
+		client:      &http.Client{Timeout: timeout},
+		userAgent:   userAgent,
+		maxBodySize: maxBodySize,
+	}
+}
+
+// * Fetch загружает HTML страницы продукта
+func (f *Fetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	const op = "fetcher.Fetch"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %w: %d", op, ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	// * Читаем на байт больше лимита, чтобы отличить обрезанную страницу
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if int64(len(body)) > f.maxBodySize {
+		return nil, fmt.Errorf("%s: %w", op, ErrBodyTooLarge)
+	}
+
+	return body, nil
+}
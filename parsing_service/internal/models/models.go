@@ -15,7 +15,10 @@ type ProductForProducer struct {
 }
 
 type ParsedProduct struct {
-	ID       int64 `json:"id"`
-	Price    int   `json:"price"`
-	In_stock bool  `json:"in_stock"`
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Price    int    `json:"price"`
+	Currency string `json:"currency"`
+	In_stock bool   `json:"in_stock"`
+	Error    string `json:"error,omitempty"` // * причина, по которой страницу не удалось распарсить
 }
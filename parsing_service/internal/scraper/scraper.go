@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"messaging/envelope"
+	"parsing_service/internal/extractor"
+	"parsing_service/internal/models"
+)
+
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) ([]byte, error)
+}
+
+type Extractors interface {
+	Extractor(marketplace models.Marketplace) (extractor.Extractor, error)
+}
+
+type Producer interface {
+	PublishDerived(ctx context.Context, parentID string, msg any) error
+}
+
+type Scraper struct {
+	log        *slog.Logger
+	fetcher    Fetcher
+	extractors Extractors
+	producer   Producer
+}
+
+func New(log *slog.Logger, f Fetcher, e Extractors, p Producer) *Scraper {
+	return &Scraper{
+		log:        log,
+		fetcher:    f,
+		extractors: e,
+		producer:   p,
+	}
+}
+
+// * HandleMessage обрабатывает задание на парсинг и публикует результат
+func (s *Scraper) HandleMessage(ctx context.Context, body []byte) error {
+	const op = "scraper.HandleMessage"
+
+	var task models.ProductForProducer
+
+	env, err := envelope.Unmarshal(body, envelope.TypeParseRequested, &task)
+	if err != nil {
+		return fmt.Errorf("%s: invalid message format: %w", op, err)
+	}
+
+	// * результат продолжает цепочку задания
+	ctx = envelope.WithCorrelationID(ctx, env.Correlation())
+
+	// * ошибку парсинга отправляем результатом: main_service ведёт по ним статистику
+	parsed, err := s.Scrape(ctx, task)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s: product %d: %w", op, task.ID, err)
+		}
+
+		s.log.Warn("failed to parse product",
+			slog.String("op", op),
+			slog.Int64("product_id", task.ID),
+			slog.String("marketplace", string(task.Marketplace)),
+			slog.String("error", err.Error()),
+		)
+
+		parsed = models.ParsedProduct{ID: task.ID, Error: err.Error()}
+	}
+
+	// * id результата выводится из id задания: задание, выполненное повторно
+	// * (повтор relay или возврат в очередь), даёт дубликат, который main_service отбросит
+	if err := s.producer.PublishDerived(ctx, env.ID, parsed); err != nil {
+		return fmt.Errorf("%s: publish result: %w", op, err)
+	}
+
+	if parsed.Error != "" {
+		return nil
+	}
+
+	s.log.Debug("product parsed",
+		slog.String("op", op),
+		slog.Int64("product_id", parsed.ID),
+		slog.Int("price", parsed.Price),
+		slog.String("currency", parsed.Currency),
+		slog.Bool("in_stock", parsed.In_stock),
+	)
+
+	return nil
+}
+
+// * Scrape загружает страницу продукта и извлекает цену и наличие
+// * экстрактором маркетплейса
+func (s *Scraper) Scrape(ctx context.Context, task models.ProductForProducer) (models.ParsedProduct, error) {
+	e, err := s.extractors.Extractor(task.Marketplace)
+	if err != nil {
+		return models.ParsedProduct{}, err
+	}
+
+	page, err := s.fetcher.Fetch(ctx, task.URL)
+	if err != nil {
+		return models.ParsedProduct{}, err
+	}
+
+	res, err := e.Extract(page)
+	if err != nil {
+		return models.ParsedProduct{}, fmt.Errorf("%s: %w", task.Marketplace, err)
+	}
+
+	return models.ParsedProduct{
+		ID:       task.ID,
+		Title:    res.Title,
+		Price:    res.Price,
+		Currency: res.Currency,
+		In_stock: res.InStock,
+	}, nil
+}
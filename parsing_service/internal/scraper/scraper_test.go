@@ -0,0 +1,208 @@
+package scraper_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"messaging/envelope"
+	"parsing_service/internal/extractor"
+	"parsing_service/internal/fetcher"
+	"parsing_service/internal/models"
+	"parsing_service/internal/scraper"
+)
+
+// * fakeProducer запоминает опубликованные результаты, id исходных заданий
+// * и correlation_id контекста
+type fakeProducer struct {
+	mu           sync.Mutex
+	results      []models.ParsedProduct
+	parents      []string
+	correlations []string
+	err          error
+}
+
+func (p *fakeProducer) PublishDerived(ctx context.Context, parentID string, msg any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+
+	p.results = append(p.results, msg.(models.ParsedProduct))
+	p.parents = append(p.parents, parentID)
+	p.correlations = append(p.correlations, envelope.CorrelationID(ctx))
+
+	return nil
+}
+
+// * newScraper поднимает HTTP заглушку с сохранёнными страницами из testdata
+func newScraper(t *testing.T, producer scraper.Producer) (*scraper.Scraper, string) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	t.Cleanup(srv.Close)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	f := fetcher.New(5*time.Second, "scraper-test", 1<<20)
+
+	return scraper.New(log, f, extractor.Default(), producer), srv.URL
+}
+
+func taskMessage(t *testing.T, task models.ProductForProducer) (envelope.Envelope, []byte) {
+	t.Helper()
+
+	env, err := envelope.New(envelope.TypeParseRequested, task)
+	if err != nil {
+		t.Fatalf("envelope.New: %v", err)
+	}
+
+	body, err := env.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	return env, body
+}
+
+func TestHandleMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		marketplace models.Marketplace
+		want        models.ParsedProduct
+		wantError   string
+	}{
+		{
+			name:        "etsy json-ld",
+			path:        "/etsy.html",
+			marketplace: models.Etsy,
+			want:        models.ParsedProduct{Title: "Handmade Ceramic Mug", Price: 2499, Currency: "USD", In_stock: true},
+		},
+		{
+			name:        "ebay markup out of stock",
+			path:        "/ebay.html",
+			marketplace: models.Ebay,
+			want:        models.ParsedProduct{Title: "Vintage Film Camera", Price: 123456, Currency: "USD", In_stock: false},
+		},
+		{
+			name:        "aliexpress run params",
+			path:        "/aliexpress.html",
+			marketplace: models.Aliexpress,
+			want:        models.ParsedProduct{Title: "Wireless Earbuds", Price: 1234, Currency: "USD", In_stock: true},
+		},
+		{
+			name:        "page not found",
+			path:        "/missing.html",
+			marketplace: models.Etsy,
+			wantError:   fetcher.ErrUnexpectedStatus.Error(),
+		},
+		{
+			name:        "page without price",
+			path:        "/ebay.html",
+			marketplace: models.Etsy,
+			wantError:   extractor.ErrPriceNotFound.Error(),
+		},
+		{
+			name:        "unsupported marketplace",
+			path:        "/etsy.html",
+			marketplace: models.Marketplace("amazon"),
+			wantError:   extractor.ErrUnsupportedMarketplace.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			producer := &fakeProducer{}
+			s, baseURL := newScraper(t, producer)
+
+			task := models.ProductForProducer{ID: 42, URL: baseURL + tt.path, Marketplace: tt.marketplace}
+			env, body := taskMessage(t, task)
+
+			// * ошибка страницы — не ошибка обработки: она уходит результатом
+			if err := s.HandleMessage(context.Background(), body); err != nil {
+				t.Fatalf("HandleMessage: %v", err)
+			}
+
+			if len(producer.results) != 1 {
+				t.Fatalf("published %d results, want 1", len(producer.results))
+			}
+
+			got := producer.results[0]
+
+			if got.ID != task.ID {
+				t.Errorf("result id = %d, want %d", got.ID, task.ID)
+			}
+
+			if producer.correlations[0] != env.ID {
+				t.Errorf("result correlation_id = %q, want task id %q", producer.correlations[0], env.ID)
+			}
+
+			if tt.wantError != "" {
+				if !strings.Contains(got.Error, tt.wantError) {
+					t.Errorf("result error = %q, want it to contain %q", got.Error, tt.wantError)
+				}
+				return
+			}
+
+			tt.want.ID = task.ID
+			if got != tt.want {
+				t.Errorf("result = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleMessageInvalidTask(t *testing.T) {
+	producer := &fakeProducer{}
+	s, _ := newScraper(t, producer)
+
+	err := s.HandleMessage(context.Background(), []byte(`{"id": 1, "url": "https://www.etsy.com/listing/1"}`))
+	if !envelope.IsInvalid(err) {
+		t.Fatalf("HandleMessage error = %v, want invalid envelope", err)
+	}
+
+	if len(producer.results) != 0 {
+		t.Errorf("published %d results for invalid task", len(producer.results))
+	}
+}
+
+func TestHandleMessagePublishFailure(t *testing.T) {
+	errBroker := errors.New("broker unavailable")
+
+	producer := &fakeProducer{err: errBroker}
+	s, baseURL := newScraper(t, producer)
+
+	_, body := taskMessage(t, models.ProductForProducer{ID: 7, URL: baseURL + "/etsy.html", Marketplace: models.Etsy})
+
+	// * сбой публикации возвращается, чтобы задание ушло на повтор
+	if err := s.HandleMessage(context.Background(), body); !errors.Is(err, errBroker) {
+		t.Fatalf("HandleMessage error = %v, want %v", err, errBroker)
+	}
+}
+
+func TestHandleMessageDuplicateTask(t *testing.T) {
+	producer := &fakeProducer{}
+	s, baseURL := newScraper(t, producer)
+
+	env, body := taskMessage(t, models.ProductForProducer{ID: 7, URL: baseURL + "/etsy.html", Marketplace: models.Etsy})
+
+	// * relay мог опубликовать задание дважды: оба результата ссылаются на одно задание
+	for range 2 {
+		if err := s.HandleMessage(context.Background(), body); err != nil {
+			t.Fatalf("HandleMessage: %v", err)
+		}
+	}
+
+	if len(producer.parents) != 2 || producer.parents[0] != env.ID || producer.parents[1] != env.ID {
+		t.Fatalf("results published for tasks %v, want both for %s", producer.parents, env.ID)
+	}
+}